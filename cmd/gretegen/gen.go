@@ -0,0 +1,587 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ccbhj/grete/types"
+)
+
+const typesPath = "github.com/ccbhj/grete/types"
+
+type (
+	// fileInfo is a parsed file and what it imports
+	fileInfo struct {
+		name       string
+		imports    map[string]string // local name -> import path
+		dotImports map[string]bool   // import paths that are dot-imported
+	}
+
+	// typeDecl is a type declared in the package
+	typeDecl struct {
+		file *fileInfo
+		spec *ast.TypeSpec
+	}
+
+	// packageInfo is the package declaring the types to generate for
+	packageInfo struct {
+		name    string
+		types   map[string]typeDecl
+		methods map[string]map[string]bool // type name -> names of methods declared on it or its pointer
+	}
+
+	// fieldKind tells how a field is wrapped into a GValue
+	fieldKind int
+
+	// field is a field that can be accessed by its name in rules without reflection
+	field struct {
+		name   string // name in rules
+		goPath string // selector of the field in its struct, like `Position.File` for a promoted field
+		kind   fieldKind
+		vt     types.GValueType
+		// type expression of a user-defined GValue, whose GValueType is known after it is registered
+		customType string
+		tagged     bool
+		depth      int // depth of embedding
+	}
+)
+
+const (
+	kindUnsupported fieldKind = iota // accessed by reflection
+	kindInt
+	kindUint
+	kindFloat
+	kindString
+	kindBool
+	kindTime
+	kindTimePtr
+	kindDuration
+	kindGValue    // types in package types that implement GValue
+	kindGValuePtr // pointers in package types that implement GValue
+	kindStruct
+	kindPtr
+)
+
+var basicKinds = map[string]fieldKind{
+	"int": kindInt, "int8": kindInt, "int16": kindInt, "int32": kindInt, "int64": kindInt, "rune": kindInt,
+	"uint": kindUint, "uint8": kindUint, "uint16": kindUint, "uint32": kindUint, "uint64": kindUint, "byte": kindUint,
+	"float32": kindFloat, "float64": kindFloat,
+	"string": kindString,
+	"bool":   kindBool,
+}
+
+// gvalueTypes are types in package types that implement GValue, and the kinds of their underlying types
+var gvalueTypes = map[string]struct {
+	vt         types.GValueType
+	underlying fieldKind
+}{
+	"GVIdentity": {types.GValueTypeIdentity, kindString},
+	"GVInt":      {types.GValueTypeInt, kindInt},
+	"GVUint":     {types.GValueTypeUint, kindUint},
+	"GVFloat":    {types.GValueTypeFloat, kindFloat},
+	"GVString":   {types.GValueTypeString, kindString},
+	"GVBool":     {types.GValueTypeBool, kindBool},
+	"GVDuration": {types.GValueTypeDuration, kindInt},
+	"GVTime":     {types.GValueTypeTime, kindStruct},
+	"GVStruct":   {types.GValueTypeStruct, kindStruct},
+	"GVDecimal":  {types.GValueTypeDecimal, kindStruct},
+}
+
+// gvaluePtrTypes are types in package types whose pointers implement GValue
+var gvaluePtrTypes = map[string]types.GValueType{
+	"GVNil":    types.GValueTypeNil,
+	"GVSet":    types.GValueTypeSet,
+	"GVList":   types.GValueTypeList,
+	"GVMap":    types.GValueTypeMap,
+	"GVRecord": types.GValueTypeRecord,
+}
+
+// loadPackage parse the package named pkgName in dir,
+// the package is the one declaring typeName if pkgName is empty
+func loadPackage(dir, pkgName, typeName string) (*packageInfo, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	pkgs := make(map[string]*packageInfo, 2)
+	for _, path := range paths {
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		pkg, in := pkgs[f.Name.Name]
+		if !in {
+			pkg = &packageInfo{
+				name:    f.Name.Name,
+				types:   make(map[string]typeDecl),
+				methods: make(map[string]map[string]bool),
+			}
+			pkgs[f.Name.Name] = pkg
+		}
+
+		file := &fileInfo{
+			name:       filepath.Base(path),
+			imports:    make(map[string]string, len(f.Imports)),
+			dotImports: make(map[string]bool),
+		}
+		for _, spec := range f.Imports {
+			importPath, _ := strconv.Unquote(spec.Path.Value)
+			switch {
+			case spec.Name == nil:
+				file.imports[importPath[strings.LastIndexByte(importPath, '/')+1:]] = importPath
+			case spec.Name.Name == ".":
+				file.dotImports[importPath] = true
+			default:
+				file.imports[spec.Name.Name] = importPath
+			}
+		}
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv != nil && len(fd.Recv.List) == 1 {
+				recv := embeddedName(fd.Recv.List[0].Type)
+				if pkg.methods[recv] == nil {
+					pkg.methods[recv] = make(map[string]bool)
+				}
+				pkg.methods[recv][fd.Name.Name] = true
+				continue
+			}
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				pkg.types[ts.Name.Name] = typeDecl{file: file, spec: ts}
+			}
+		}
+	}
+
+	if pkgName != "" {
+		if pkg, in := pkgs[pkgName]; in {
+			return pkg, nil
+		}
+		return nil, errors.Errorf("package %s is not found in %s", pkgName, dir)
+	}
+	for _, pkg := range pkgs {
+		if _, in := pkg.types[typeName]; in {
+			return pkg, nil
+		}
+	}
+	return nil, errors.Errorf("type %s is not found in %s", typeName, dir)
+}
+
+// generate the source of accessors for types
+func generate(pkg *packageInfo, typeNames []string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by gretegen -type %s; DO NOT EDIT.\n\n", strings.Join(typeNames, ","))
+	fmt.Fprintf(buf, "package %s\n\n", pkg.name)
+	fmt.Fprintf(buf, "import grete %q\n", typesPath)
+
+	for _, name := range typeNames {
+		decl, in := pkg.types[name]
+		if !in {
+			return nil, errors.Errorf("type %s is not found in package %s", name, pkg.name)
+		}
+		st, ok := decl.spec.Type.(*ast.StructType)
+		if !ok || decl.spec.TypeParams != nil {
+			return nil, errors.Errorf("type %s is not a struct or is generic", name)
+		}
+		writeAccessors(buf, name, pkg.fields(decl.file, st))
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid generated code:\n%s", buf.String())
+	}
+	return src, nil
+}
+
+// fields collect fields of a struct like types.typeFields does,
+// fields promoted from structs declared in other packages are unknown here and left to reflection
+func (pkg *packageInfo) fields(file *fileInfo, st *ast.StructType) []field {
+	candidates := make(map[string][]field, len(st.Fields.List))
+	pkg.walkFields(candidates, file, st, "", 0, false, make(map[string]bool))
+
+	fields := make([]field, 0, len(candidates))
+	for _, fs := range candidates {
+		if f, ok := dominantField(fs); ok {
+			fields = append(fields, f)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	return fields
+}
+
+// walkFields collect fields of st into candidates, prefix is the selector of st in the outermost struct,
+// and viaPtr tells whether an embedded pointer is met along the prefix, fields behind it are got by reflection
+// in case the pointer is nil
+func (pkg *packageInfo) walkFields(candidates map[string][]field, file *fileInfo, st *ast.StructType,
+	prefix string, depth int, viaPtr bool, visited map[string]bool) {
+	for _, f := range st.Fields.List {
+		tag := ""
+		if f.Tag != nil {
+			raw, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(raw).Get(types.TagName)
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		inline := false
+		for _, opt := range strings.Split(opts, ",") {
+			inline = inline || opt == "inline"
+		}
+
+		goNames := make([]string, 0, len(f.Names))
+		for _, n := range f.Names {
+			goNames = append(goNames, n.Name)
+		}
+		embedded := len(f.Names) == 0
+		if embedded {
+			// embedded field is named by its type
+			goNames = append(goNames, embeddedName(f.Type))
+		}
+
+		kind, vt := pkg.kindOf(file, f.Type, false)
+		if inline || (embedded && name == "") {
+			if decl, nested, ptr := pkg.structOf(file, f.Type); nested != nil && (decl == "" || !visited[decl]) {
+				visited[decl] = true
+				declFile := file
+				if d, in := pkg.types[decl]; in {
+					declFile = d.file
+				}
+				pkg.walkFields(candidates, declFile, nested, prefix+goNames[0]+".", depth+1, viaPtr || ptr, visited)
+				delete(visited, decl)
+				if inline {
+					continue
+				}
+			} else if inline && (kind == kindUnsupported || pkg.isStruct(file, f.Type)) {
+				// fields of an inlined struct are promoted, leave them and what we don't know to reflection
+				continue
+			}
+		}
+
+		customType := ""
+		if vt == types.GValueTypeUnknown {
+			customType = pkg.customGValueType(f.Type)
+		}
+		for _, goName := range goNames {
+			if !ast.IsExported(goName) {
+				continue
+			}
+			fd := field{
+				name: name, goPath: prefix + goName, kind: kind, vt: vt,
+				customType: customType, tagged: name != "", depth: depth,
+			}
+			if fd.name == "" {
+				fd.name = goName
+			}
+			if viaPtr {
+				fd.kind = kindUnsupported
+			}
+			candidates[fd.name] = append(candidates[fd.name], fd)
+		}
+	}
+}
+
+// dominantField return the field that wins over the others with the same name like types.dominantField does,
+// ok is false if they are ambiguous
+func dominantField(fs []field) (dominant field, ok bool) {
+	ambiguous := false
+	for i, f := range fs {
+		switch {
+		case i == 0,
+			f.depth < dominant.depth,
+			f.depth == dominant.depth && f.tagged && !dominant.tagged:
+			dominant, ambiguous = f, false
+		case f.depth == dominant.depth && f.tagged == dominant.tagged:
+			ambiguous = true
+		}
+	}
+	return dominant, len(fs) > 0 && !ambiguous
+}
+
+// structOf return the struct type that expr refers to and the name of its declaration,
+// nested is nil if it is not a struct declared in the package, and ptr tells whether expr is a pointer
+func (pkg *packageInfo) structOf(file *fileInfo, expr ast.Expr) (decl string, nested *ast.StructType, ptr bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr, ptr = star.X, true
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		d, in := pkg.types[t.Name]
+		if !in || d.spec.TypeParams != nil {
+			return "", nil, false
+		}
+		st, _ := d.spec.Type.(*ast.StructType)
+		return t.Name, st, ptr
+	case *ast.StructType:
+		return "", t, ptr
+	}
+	return "", nil, false
+}
+
+// customGValueType return the type expression of a user-defined GValue declared in the package
+// or a pointer to it, or an empty string if expr is not one of them
+func (pkg *packageInfo) customGValueType(expr ast.Expr) string {
+	star := ""
+	if t, ok := expr.(*ast.StarExpr); ok {
+		expr, star = t.X, "*"
+	}
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	if decl, in := pkg.types[id.Name]; !in || decl.spec.TypeParams != nil || !pkg.implementsGValue(id.Name) {
+		return ""
+	}
+	return star + id.Name
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedName(t.X)
+	}
+	return ""
+}
+
+func (pkg *packageInfo) isStruct(file *fileInfo, expr ast.Expr) bool {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	kind, _ := pkg.kindOf(file, expr, true)
+	return kind == kindStruct
+}
+
+// kindOf tell the fieldKind and the GValueType of a type expression,
+// underlying is true if expr is the underlying type of a named type declared in the package,
+// where GValues or types in package time are not what they are any more
+func (pkg *packageInfo) kindOf(file *fileInfo, expr ast.Expr, underlying bool) (fieldKind, types.GValueType) {
+	switch t := expr.(type) {
+	case *ast.ParenExpr:
+		return pkg.kindOf(file, t.X, underlying)
+	case *ast.Ident:
+		if decl, in := pkg.types[t.Name]; in {
+			// user-defined GValues are wrapped as they are by reflection
+			if decl.spec.TypeParams != nil || (!underlying && pkg.implementsGValue(t.Name)) {
+				return kindUnsupported, types.GValueTypeUnknown
+			}
+			if _, isStruct := decl.spec.Type.(*ast.StructType); isStruct {
+				return kindStruct, types.GValueTypeStruct
+			}
+			// an alias is the same type as what it refers to
+			return pkg.kindOf(decl.file, decl.spec.Type, underlying || !decl.spec.Assign.IsValid())
+		}
+		if kind, in := basicKinds[t.Name]; in {
+			return kind, kindGValueType(kind)
+		}
+		if file.dotImports[typesPath] {
+			return gvalueKindOf(t.Name, underlying)
+		}
+	case *ast.SelectorExpr:
+		pkgIdent, ok := t.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		switch file.imports[pkgIdent.Name] {
+		case "time":
+			switch {
+			case t.Sel.Name == "Time" && underlying:
+				return kindStruct, types.GValueTypeStruct
+			case t.Sel.Name == "Time":
+				return kindTime, types.GValueTypeTime
+			case t.Sel.Name == "Duration" && underlying:
+				return kindInt, types.GValueTypeInt
+			case t.Sel.Name == "Duration":
+				return kindDuration, types.GValueTypeDuration
+			}
+		case typesPath:
+			return gvalueKindOf(t.Sel.Name, underlying)
+		}
+	case *ast.StarExpr:
+		if underlying {
+			return kindPtr, types.GValueTypeStruct
+		}
+		if kind, _ := pkg.kindOf(file, t.X, false); kind == kindTime {
+			return kindTimePtr, types.GValueTypeTime
+		}
+		if id, ok := t.X.(*ast.Ident); ok && pkg.implementsGValue(id.Name) {
+			return kindUnsupported, types.GValueTypeUnknown
+		}
+		if name := embeddedName(t.X); gvaluePtrTypes[name] != types.GValueTypeUnknown && pkg.isGValue(file, t.X) {
+			return kindGValuePtr, gvaluePtrTypes[name]
+		}
+		return kindPtr, types.GValueTypeStruct
+	case *ast.StructType:
+		return kindStruct, types.GValueTypeStruct
+	case *ast.ArrayType:
+		return kindUnsupported, types.GValueTypeList
+	case *ast.MapType:
+		return kindUnsupported, types.GValueTypeMap
+	}
+	return kindUnsupported, types.GValueTypeUnknown
+}
+
+// gvalueMethods are methods of types.GValue
+var gvalueMethods = []string{"Hash", "Type", "RType", "GetField", "Equal", "ToGoValue"}
+
+// implementsGValue check if type name declared in the package implements GValue
+func (pkg *packageInfo) implementsGValue(name string) bool {
+	for _, m := range gvalueMethods {
+		if !pkg.methods[name][m] {
+			return false
+		}
+	}
+	return true
+}
+
+// isGValue check if expr refers to a type in package types
+func (pkg *packageInfo) isGValue(file *fileInfo, expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		_, local := pkg.types[t.Name]
+		return !local && file.dotImports[typesPath]
+	case *ast.SelectorExpr:
+		pkgIdent, ok := t.X.(*ast.Ident)
+		return ok && file.imports[pkgIdent.Name] == typesPath
+	}
+	return false
+}
+
+func gvalueKindOf(name string, underlying bool) (fieldKind, types.GValueType) {
+	gt, in := gvalueTypes[name]
+	switch {
+	case !in:
+		return kindUnsupported, types.GValueTypeUnknown
+	case underlying:
+		return gt.underlying, kindGValueType(gt.underlying)
+	}
+	return kindGValue, gt.vt
+}
+
+func kindGValueType(kind fieldKind) types.GValueType {
+	switch kind {
+	case kindInt:
+		return types.GValueTypeInt
+	case kindUint:
+		return types.GValueTypeUint
+	case kindFloat:
+		return types.GValueTypeFloat
+	case kindString:
+		return types.GValueTypeString
+	case kindBool:
+		return types.GValueTypeBool
+	case kindStruct, kindPtr:
+		return types.GValueTypeStruct
+	}
+	return types.GValueTypeUnknown
+}
+
+// gvalueTypeNames are the names of GValueType constants in package types
+var gvalueTypeNames = map[types.GValueType]string{
+	types.GValueTypeUnknown:  "GValueTypeUnknown",
+	types.GValueTypeNil:      "GValueTypeNil",
+	types.GValueTypeIdentity: "GValueTypeIdentity",
+	types.GValueTypeInt:      "GValueTypeInt",
+	types.GValueTypeUint:     "GValueTypeUint",
+	types.GValueTypeFloat:    "GValueTypeFloat",
+	types.GValueTypeString:   "GValueTypeString",
+	types.GValueTypeStruct:   "GValueTypeStruct",
+	types.GValueTypeSet:      "GValueTypeSet",
+	types.GValueTypeBool:     "GValueTypeBool",
+	types.GValueTypeTime:     "GValueTypeTime",
+	types.GValueTypeDuration: "GValueTypeDuration",
+	types.GValueTypeList:     "GValueTypeList",
+	types.GValueTypeMap:      "GValueTypeMap",
+	types.GValueTypeRecord:   "GValueTypeRecord",
+	types.GValueTypeDecimal:  "GValueTypeDecimal",
+}
+
+func writeAccessors(buf *bytes.Buffer, typeName string, fields []field) {
+	fmt.Fprintf(buf, "\n// GetField implements types.FieldAccessor for %s\n", typeName)
+	fmt.Fprintf(buf, "func (x *%s) GetField(f string) (grete.GValue, any, error) {\n", typeName)
+	fmt.Fprintf(buf, "if x == nil {\nreturn grete.GetFieldByReflect(x, f)\n}\n")
+	fmt.Fprintf(buf, "switch f {\n")
+	for _, f := range fields {
+		if f.kind == kindUnsupported {
+			continue
+		}
+		v := "x." + f.goPath
+		fmt.Fprintf(buf, "case %q:\n", f.name)
+		switch f.kind {
+		case kindTimePtr, kindGValuePtr, kindPtr:
+			fmt.Fprintf(buf, "if %s == nil {\nreturn &grete.GVNil{}, nil, nil\n}\n", v)
+		}
+		fmt.Fprintf(buf, "return %s, %s, nil\n", wrapValue(f.kind, v), v)
+	}
+	fmt.Fprintf(buf, "}\nreturn grete.GetFieldByReflect(x, f)\n}\n")
+
+	fmt.Fprintf(buf, "\n// HasField implements types.FieldAccessor for %s\n", typeName)
+	fmt.Fprintf(buf, "func (x *%s) HasField(f string) bool {\n", typeName)
+	if len(fields) > 0 {
+		names := make([]string, 0, len(fields))
+		for _, f := range fields {
+			names = append(names, strconv.Quote(f.name))
+		}
+		fmt.Fprintf(buf, "switch f {\ncase %s:\nreturn true\n}\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintf(buf, "return grete.HasFieldByReflect(x, f)\n}\n")
+
+	fmt.Fprintf(buf, "\n// New%sTypeInfo return the TypeInfo of %s with the types of all its fields\n", typeName, typeName)
+	fmt.Fprintf(buf, "func New%sTypeInfo() grete.TypeInfo {\n", typeName)
+	fmt.Fprintf(buf, "return grete.TypeInfo{\nT: grete.GValueTypeStruct,\nFields: map[string]grete.GValueType{\n")
+	for _, f := range fields {
+		if f.customType != "" {
+			fmt.Fprintf(buf, "%q: grete.GValueTypeOf[%s](),\n", f.name, f.customType)
+			continue
+		}
+		fmt.Fprintf(buf, "%q: grete.%s,\n", f.name, gvalueTypeNames[f.vt])
+	}
+	fmt.Fprintf(buf, "},\n}\n}\n")
+}
+
+// wrapValue return the expression that wraps v into GValue
+func wrapValue(kind fieldKind, v string) string {
+	switch kind {
+	case kindInt:
+		return "grete.GVInt(" + v + ")"
+	case kindUint:
+		return "grete.GVUint(" + v + ")"
+	case kindFloat:
+		return "grete.GVFloat(" + v + ")"
+	case kindString:
+		return "grete.GVString(" + v + ")"
+	case kindBool:
+		return "grete.GVBool(" + v + ")"
+	case kindTime:
+		return "grete.GVTime{Time: " + v + "}"
+	case kindTimePtr:
+		return "grete.GVTime{Time: *" + v + "}"
+	case kindDuration:
+		return "grete.GVDuration(" + v + ")"
+	case kindStruct, kindPtr:
+		return "grete.NewGVStruct(" + v + ")"
+	}
+	return v
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const factSrc = `package facts
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/ccbhj/grete/types"
+)
+
+type (
+	Celsius float64
+
+	Position struct {
+		File, Rank int
+	}
+
+	Owner struct {
+		Name string
+	}
+
+	Fact struct {
+		ID       types.GVIdentity
+		Color    string ` + "`grete:\"color\"`" + `
+		Secret   string ` + "`grete:\"-\"`" + `
+		Rank     int
+		Position ` + "`grete:\",inline\"`" + `
+		*Owner
+		On       *Fact
+		Since    *time.Time
+		Set      *types.GVSet
+		Tags     []string
+		Labels   map[string]string
+		Temp     Celsius
+		MaxTemp  *Celsius
+		score    int
+	}
+)
+
+func (Celsius) Type() types.GValueType                         { return types.GValueTypeUnknown }
+func (Celsius) Hash() uint64                                   { return 0 }
+func (Celsius) RType() reflect.Type                            { return nil }
+func (Celsius) GetField(string) (types.GValue, any, error)     { return nil, nil, nil }
+func (Celsius) Equal(types.GValue) bool                        { return false }
+func (Celsius) ToGoValue() any                                 { return nil }
+`
+
+var _ = Describe("Generator", func() {
+	var getField, hasField, typeInfo string
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "fact.go"), []byte(factSrc), 0o644)).Should(Succeed())
+		pkg, err := loadPackage(dir, "", "Fact")
+		Expect(err).ShouldNot(HaveOccurred())
+		src, err := generate(pkg, []string{"Fact"})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		between := func(s, from, to string) string {
+			_, s, _ = strings.Cut(s, from)
+			s, _, _ = strings.Cut(s, to)
+			return s
+		}
+		getField = between(string(src), "func (x *Fact) GetField", "// HasField")
+		hasField = between(string(src), "func (x *Fact) HasField", "// NewFactTypeInfo")
+		typeInfo = between(string(src), "func NewFactTypeInfo", "\n}\n")
+	})
+
+	It("should name fields by their tags", func() {
+		Expect(getField).Should(ContainSubstring(`case "color":
+		return grete.GVString(x.Color), x.Color, nil`))
+		Expect(typeInfo).Should(ContainSubstring(`"color":   grete.GValueTypeString`))
+		for _, s := range []string{getField, hasField, typeInfo} {
+			Expect(s).ShouldNot(ContainSubstring(`"Color"`))
+			Expect(s).ShouldNot(ContainSubstring(`"Secret"`))
+			Expect(s).ShouldNot(ContainSubstring(`"score"`))
+		}
+	})
+
+	It("should promote fields of embedded and inlined structs", func() {
+		Expect(getField).Should(ContainSubstring(`case "File":
+		return grete.GVInt(x.Position.File), x.Position.File, nil`))
+		Expect(typeInfo).Should(ContainSubstring(`"File":    grete.GValueTypeInt`))
+		// a field declared in the struct wins over the promoted one
+		Expect(getField).Should(ContainSubstring(`return grete.GVInt(x.Rank), x.Rank, nil`))
+		Expect(getField).ShouldNot(ContainSubstring(`x.Position.Rank`))
+		// an inlined struct is not a field
+		Expect(typeInfo).ShouldNot(ContainSubstring(`"Position"`))
+
+		// fields behind an embedded pointer are got by reflection in case it is nil
+		Expect(getField).ShouldNot(ContainSubstring(`case "Name":`))
+		Expect(hasField).Should(ContainSubstring(`"Name"`))
+		Expect(typeInfo).Should(ContainSubstring(`"Name":    grete.GValueTypeString`))
+		Expect(getField).Should(ContainSubstring(`case "Owner":
+		if x.Owner == nil {`))
+	})
+
+	It("should access pointers and leave collections to reflection", func() {
+		Expect(getField).Should(ContainSubstring(`case "On":
+		if x.On == nil {
+			return &grete.GVNil{}, nil, nil
+		}
+		return grete.NewGVStruct(x.On), x.On, nil`))
+		Expect(getField).Should(ContainSubstring(`return grete.GVTime{Time: *x.Since}, x.Since, nil`))
+		Expect(getField).Should(ContainSubstring(`return x.Set, x.Set, nil`))
+		Expect(typeInfo).Should(ContainSubstring(`"Set":     grete.GValueTypeSet`))
+
+		Expect(getField).ShouldNot(ContainSubstring(`case "Tags":`))
+		Expect(getField).ShouldNot(ContainSubstring(`case "Labels":`))
+		Expect(hasField).Should(ContainSubstring(`"Labels"`))
+		Expect(hasField).Should(ContainSubstring(`"Tags"`))
+		Expect(typeInfo).Should(ContainSubstring(`"Tags":    grete.GValueTypeList`))
+		Expect(typeInfo).Should(ContainSubstring(`"Labels":  grete.GValueTypeMap`))
+	})
+
+	It("should fall back to reflection", func() {
+		Expect(getField).Should(ContainSubstring(`if x == nil {
+		return grete.GetFieldByReflect(x, f)
+	}`))
+		Expect(getField).Should(HaveSuffix("}\n\treturn grete.GetFieldByReflect(x, f)\n}\n\n"))
+		Expect(hasField).Should(HaveSuffix("}\n\treturn grete.HasFieldByReflect(x, f)\n}\n\n"))
+		Expect(getField).ShouldNot(ContainSubstring(`case "Temp":`))
+		Expect(getField).ShouldNot(ContainSubstring(`case "MaxTemp":`))
+	})
+
+	It("should resolve user-defined GValues when they are registered", func() {
+		Expect(typeInfo).Should(ContainSubstring(`"Temp":    grete.GValueTypeOf[Celsius]()`))
+		Expect(typeInfo).Should(ContainSubstring(`"MaxTemp": grete.GValueTypeOf[*Celsius]()`))
+		Expect(typeInfo).ShouldNot(ContainSubstring(`GValueTypeUnknown`))
+	})
+})
@@ -0,0 +1,58 @@
+// gretegen generates reflection-free field accessors for structs used as facts.
+//
+// Add a directive like this to the file declaring the structs,
+//
+//	//go:generate go run github.com/ccbhj/grete/cmd/gretegen -type Chess,Block
+//
+// and `go generate` writes GetField and HasField methods(see types.FieldAccessor) on pointers of each type,
+// and a New<Type>TypeInfo function that returns the types.TypeInfo of each type.
+// Fields that cannot be accessed without reflection, like slices, fields promoted through embedded pointers
+// or user-defined GValues, and paths like `On.Color` still fall back to reflection.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		typeNames = flag.String("type", "", "comma-separated list of struct type names, required")
+		output    = flag.String("output", "", "output file name, default to <type>_grete.go")
+		dir       = flag.String("dir", ".", "directory of the package declaring the types")
+	)
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	names := strings.Split(*typeNames, ",")
+	pkg, err := loadPackage(*dir, os.Getenv("GOPACKAGE"), names[0])
+	if err != nil {
+		fatal(err)
+	}
+	src, err := generate(pkg, names)
+	if err != nil {
+		fatal(err)
+	}
+
+	out := *output
+	if out == "" {
+		out = strings.ToLower(names[0]) + "_grete.go"
+		if strings.HasSuffix(pkg.types[names[0]].file.name, "_test.go") {
+			out = strings.ToLower(names[0]) + "_grete_test.go"
+		}
+	}
+	if err := os.WriteFile(filepath.Join(*dir, out), src, 0o644); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "gretegen: %s\n", err)
+	os.Exit(1)
+}
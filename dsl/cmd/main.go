@@ -13,10 +13,11 @@ func main() {
 	var (
 		printTree = flag.Bool("pt", false, "print tree")
 		fromFile  = flag.String("file", "", "read input from file")
+		compile   = flag.Bool("c", false, "compile definitions into productions")
 	)
 	flag.Parse()
 
-	input := os.Args[1]
+	input := flag.Arg(0)
 	if fromFile != nil && *fromFile != "" {
 		file, err := os.OpenFile(*fromFile, os.O_RDONLY, os.ModePerm)
 		if err != nil {
@@ -37,6 +38,16 @@ func main() {
 	if printTree != nil && *printTree {
 		pctx.PrintTree()
 	}
+	if compile != nil && *compile {
+		rules, err := pctx.Compile()
+		if err != nil {
+			panic(err)
+		}
+		for i, rule := range rules {
+			fmt.Printf("%d:%+v\n", i, rule)
+		}
+		return
+	}
 	defs, err := pctx.Run()
 	if err != nil {
 		panic(err)
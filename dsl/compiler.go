@@ -0,0 +1,600 @@
+package dsl
+
+import (
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ccbhj/grete/rete"
+	. "github.com/ccbhj/grete/types"
+)
+
+// DefType that can be compiled into a production
+const (
+	DefTypePrdt = "define-prdt"
+	DefTypeRule = "rule"
+)
+
+// sections in the body of a production
+const (
+	sectionWhen  = "when"
+	sectionMatch = "match"
+	sectionThen  = "then"
+)
+
+const opFieldOf = "field-of"
+
+// opIsA declare the registered type of an alias, like `[is-a $x Chess]`
+const opIsA = "is-a"
+
+// operators that test on what is derived from an alias, like `(len (field-of $x "Tags"))`
+const (
+	opLen        = "len"
+	opAnyElement = "any-element"
+)
+
+type (
+	// Rule is a production compiled from a `define-prdt` or `rule` definition,
+	// tests between an alias and constants are compiled into the Guards of the alias in When
+	// no matter they are in section `when` or `match`, use Locate to find where they are in script
+	Rule struct {
+		rete.Production
+		Then []*Expression // actions to take when a match is found
+
+		c         *ParseContext
+		testNodes map[testKey]*node32 // where the Guards and JoinTests are compiled from
+	}
+
+	// testKey is what a rete.TestOpError tells about the Guard or JoinTest it is reported for
+	testKey struct {
+		alias GVIdentity
+		field GVString
+		op    rete.TestOp
+	}
+
+	// testOpDesc describes how an operator in script is mapped into a rete.TestOp
+	testOpDesc struct {
+		op       rete.TestOp
+		reversed bool // operands are passed to the TestOp in reversed order
+		untyped  bool // the type of the tested field cannot be told by the value it is tested with
+	}
+
+	// testOperand is either a selector of an alias or a constant value
+	testOperand struct {
+		selector  *rete.Selector
+		value     GValue
+		modifiers []string // opLen or opAnyElement applied on the selector, innermost first
+	}
+
+	// ruleCompiler holds the states when compiling a definition into a Rule
+	ruleCompiler struct {
+		c         *ParseContext
+		def       *Definition
+		rule      *Rule
+		aliasIdx  map[GVIdentity]int
+		fields    map[GVIdentity]map[string]GValueType
+		selfType  map[GVIdentity]GValueType
+		typeNames map[GVIdentity]string // names of registered types declared by `is-a`
+	}
+)
+
+// testOpTab maps operators in script into rete.TestOp
+var testOpTab = map[string]testOpDesc{
+	"eq":      {op: rete.TestOpEqual},
+	"=":       {op: rete.TestOpEqual},
+	"not-eq":  {op: rete.TestOpNotEqual},
+	"!=":      {op: rete.TestOpNotEqual},
+	"<":       {op: rete.TestOpLess},
+	">":       {op: rete.TestOpGreater},
+	"<=":      {op: rete.TestOpLessEqual},
+	">=":      {op: rete.TestOpGreaterEqual},
+	"eq-fold": {op: rete.TestOpEqualFold},
+	// string matching in script is written as `[op string pattern]`
+	"contains":   {op: rete.TestOpContains, reversed: true, untyped: true},
+	"has-prefix": {op: rete.TestOpHasPrefix, reversed: true},
+	"has-suffix": {op: rete.TestOpHasSuffix, reversed: true},
+	"match":      {op: rete.TestOpMatch, reversed: true},
+	// set membership in script is written as `[op value (v1 v2 ...)]`
+	"in":     {op: rete.TestOpIn, reversed: true},
+	"not-in": {op: rete.TestOpNotIn, reversed: true},
+}
+
+// mirroredTestOp maps a TestOp `op` to the one that `op(x, y) == mirrored(y, x)`
+var mirroredTestOp = map[rete.TestOp]rete.TestOp{
+	rete.TestOpEqual:        rete.TestOpEqual,
+	rete.TestOpNotEqual:     rete.TestOpNotEqual,
+	rete.TestOpLess:         rete.TestOpGreater,
+	rete.TestOpGreater:      rete.TestOpLess,
+	rete.TestOpLessEqual:    rete.TestOpGreaterEqual,
+	rete.TestOpGreaterEqual: rete.TestOpLessEqual,
+	rete.TestOpEqualFold:    rete.TestOpEqualFold,
+}
+
+// Compile run the script and compile all the `define-prdt` and `rule` definitions into Rules,
+// other definitions are ignored
+func (c *ParseContext) Compile() ([]*Rule, error) {
+	defs, err := c.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]*Rule, 0, len(defs))
+	for _, def := range defs {
+		switch def.DefType {
+		case DefTypePrdt, DefTypeRule:
+		default:
+			continue
+		}
+		rule, err := compileRule(c, def)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func compileRule(c *ParseContext, def *Definition) (*Rule, error) {
+	if def.ID == "" {
+		return nil, SyntaxErrorf(c, def.node, "missing identifier for %s", def.DefType)
+	}
+
+	rc := &ruleCompiler{
+		c:   c,
+		def: def,
+		rule: &Rule{
+			Production: rete.Production{
+				ID:    def.ID,
+				When:  make([]rete.AliasDeclaration, 0, 2),
+				Match: make([]rete.JoinTest, 0, 2),
+			},
+			c:         c,
+			testNodes: make(map[testKey]*node32, 4),
+		},
+		aliasIdx:  make(map[GVIdentity]int, 2),
+		fields:    make(map[GVIdentity]map[string]GValueType, 2),
+		selfType:  make(map[GVIdentity]GValueType, 2),
+		typeNames: make(map[GVIdentity]string, 2),
+	}
+
+	var when, match, then []*Expression
+	for _, v := range def.Body {
+		section, ok := v.(*Expression)
+		if !ok {
+			return nil, SyntaxErrorf(c, def.node, "expecting a section of when/match/then, but got %v", v)
+		}
+		exprs, err := rc.sectionBody(section)
+		if err != nil {
+			return nil, err
+		}
+		switch section.Op {
+		case sectionWhen:
+			when = append(when, exprs...)
+		case sectionMatch:
+			match = append(match, exprs...)
+		case sectionThen:
+			then = append(then, exprs...)
+		default:
+			return nil, SyntaxErrorf(c, section.node, "unknown section %q", section.Op)
+		}
+	}
+
+	// tests in `when` go first so that aliases are declared in the order they are guarded
+	for _, exprs := range [][]*Expression{when, match} {
+		for _, expr := range exprs {
+			if err := rc.compileTest(expr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := rc.fillTypeInfo(); err != nil {
+		return nil, err
+	}
+	rc.rule.Then = then
+
+	return rc.rule, nil
+}
+
+// sectionBody return all the expressions in a section,
+// expressions can be either the operands of the section or be grouped in a list
+func (rc *ruleCompiler) sectionBody(section *Expression) ([]*Expression, error) {
+	exprs := make([]*Expression, 0, len(section.Operand))
+	for _, v := range section.Operand {
+		switch v := v.(type) {
+		case *Expression:
+			exprs = append(exprs, v)
+		case []any:
+			for _, e := range v {
+				expr, ok := e.(*Expression)
+				if !ok {
+					return nil, SyntaxErrorf(rc.c, section.node, "expecting expressions in section %q, but got %v",
+						section.Op, e)
+				}
+				exprs = append(exprs, expr)
+			}
+		default:
+			return nil, SyntaxErrorf(rc.c, section.node, "expecting expressions in section %q, but got %v",
+				section.Op, v)
+		}
+	}
+	return exprs, nil
+}
+
+// compileTest compile an test expression into a rete.Guard if only one alias is tested with a constant,
+// or a rete.JoinTest if it tests between aliases
+func (rc *ruleCompiler) compileTest(expr *Expression) error {
+	if expr.Op == opIsA {
+		return rc.compileIsA(expr)
+	}
+
+	desc, in := testOpTab[expr.Op]
+	if !in {
+		// TestOps registered by rete.RegisterTestOp are referenced by their names
+		op, ok := rete.TestOpByName(expr.Op)
+		if !ok {
+			return SyntaxErrorf(rc.c, expr.node, "unsupported operator %q", expr.Op)
+		}
+		desc = testOpDesc{op: op}
+	}
+	if arity := desc.op.Arity(); len(expr.Operand) != arity {
+		return SyntaxErrorf(rc.c, expr.node, "operator %q requires %d operands, but got %d",
+			expr.Op, arity, len(expr.Operand))
+	}
+
+	operands := make([]testOperand, 0, len(expr.Operand))
+	nSelector := 0
+	for _, v := range expr.Operand {
+		operand, err := rc.compileOperand(expr, v)
+		if err != nil {
+			return err
+		}
+		if operand.selector != nil {
+			nSelector++
+		}
+		operands = append(operands, operand)
+	}
+	if desc.reversed {
+		operands[0], operands[1] = operands[1], operands[0]
+	}
+	op := desc.op
+	switch {
+	case nSelector == 0:
+		return SyntaxErrorf(rc.c, expr.node, "expecting at least one alias to test in %q", expr.Op)
+	case len(operands) == 1:
+		// unary test like `[is-nil (field-of $x "On")]` is a guard without value
+		x := operands[0]
+		idx := rc.declareAlias(x.selector.Alias)
+		rc.recordField(*x.selector, GValueTypeUnknown)
+		decl := &rc.rule.When[idx]
+		decl.Guards = append(decl.Guards, rete.Guard{
+			AliasAttr: x.selector.AliasAttr,
+			TestOp:    modifyTestOp(op, x.modifiers, 0),
+		})
+		rc.recordTest(expr, decl.Guards[len(decl.Guards)-1].TestOp, *x.selector)
+	case nSelector > 1:
+		// constants in a join test are passed after the aliases, like the offset of time
+		selectors := make([]rete.Selector, 0, nSelector)
+		var args []GValue
+		for _, operand := range operands {
+			if operand.selector == nil {
+				args = append(args, operand.value)
+				continue
+			}
+			if len(args) > 0 {
+				return SyntaxErrorf(rc.c, expr.node, "constants should be placed after aliases in %q", expr.Op)
+			}
+			rc.declareAlias(operand.selector.Alias)
+			rc.recordField(*operand.selector, GValueTypeUnknown)
+			op = modifyTestOp(op, operand.modifiers, len(selectors))
+			selectors = append(selectors, *operand.selector)
+		}
+		rc.rule.Match = append(rc.rule.Match, rete.JoinTest{
+			Alias:  selectors,
+			Args:   args,
+			TestOp: op,
+		})
+		rc.recordTest(expr, op, selectors...)
+	case len(operands) == 2:
+		x, y := operands[0], operands[1]
+		// constant test always takes the value as its first operand
+		if x.selector != nil {
+			mirrored, in := mirroredTestOp[op]
+			if !in {
+				return SyntaxErrorf(rc.c, expr.node,
+					"operator %q is unsupported with a constant value as the right operand", expr.Op)
+			}
+			op = mirrored
+			x, y = y, x
+		}
+		idx := rc.declareAlias(y.selector.Alias)
+		if desc.untyped || len(y.modifiers) > 0 {
+			rc.recordField(*y.selector, GValueTypeUnknown)
+		} else {
+			rc.recordField(*y.selector, valueTypeOfField(x.value))
+		}
+		op = modifyTestOp(op, y.modifiers, 1)
+		decl := &rc.rule.When[idx]
+		decl.Guards = append(decl.Guards, rete.Guard{
+			AliasAttr: y.selector.AliasAttr,
+			Value:     x.value,
+			TestOp:    op,
+		})
+		rc.recordTest(expr, op, *y.selector)
+	default:
+		return SyntaxErrorf(rc.c, expr.node, "cannot test aliases with constants in %q", expr.Op)
+	}
+
+	return nil
+}
+
+// compileIsA compile `[is-a $alias TypeName]` that declares the type of an alias by a registered name
+func (rc *ruleCompiler) compileIsA(expr *Expression) error {
+	if len(expr.Operand) != 2 {
+		return SyntaxErrorf(rc.c, expr.node, "%q requires 2 operands, but got %d", opIsA, len(expr.Operand))
+	}
+	alias, ok := expr.Operand[0].(Variable)
+	if !ok {
+		return SyntaxErrorf(rc.c, expr.node, "expecting an alias in %q, but got %v", opIsA, expr.Operand[0])
+	}
+	var name string
+	switch v := expr.Operand[1].(type) {
+	case Identifier:
+		name = string(v)
+	case string:
+		name = v
+	default:
+		return SyntaxErrorf(rc.c, expr.node, "expecting a type name in %q, but got %v", opIsA, expr.Operand[1])
+	}
+	if _, in := TypeInfoByName(name); !in {
+		return SyntaxErrorf(rc.c, expr.node, "type %s is not registered", name)
+	}
+	if declared, in := rc.typeNames[GVIdentity(alias)]; in && declared != name {
+		return SyntaxErrorf(rc.c, expr.node, "alias %s is declared as %s and %s", alias, declared, name)
+	}
+
+	rc.declareAlias(GVIdentity(alias))
+	rc.typeNames[GVIdentity(alias)] = name
+	return nil
+}
+
+// compileOperand compile an operand of a test into a selector or a constant
+func (rc *ruleCompiler) compileOperand(expr *Expression, v any) (testOperand, error) {
+	switch v := v.(type) {
+	case Variable:
+		return testOperand{selector: &rete.Selector{Alias: GVIdentity(v), AliasAttr: FieldSelf}}, nil
+	case Identifier:
+		return testOperand{}, SyntaxErrorf(rc.c, expr.node, "unknown identifier %s in %q, use $%s for an alias",
+			v, expr.Op, v)
+	case *Expression:
+		switch v.Op {
+		case opFieldOf:
+			return rc.compileFieldOf(v)
+		case opLen, opAnyElement:
+			if len(v.Operand) != 1 {
+				return testOperand{}, SyntaxErrorf(rc.c, v.node, "%q requires 1 operand, but got %d",
+					v.Op, len(v.Operand))
+			}
+			operand, err := rc.compileOperand(v, v.Operand[0])
+			if err != nil {
+				return testOperand{}, err
+			}
+			if operand.selector == nil {
+				return testOperand{}, SyntaxErrorf(rc.c, v.node, "expecting an alias in %q, but got %v",
+					v.Op, v.Operand[0])
+			}
+			operand.modifiers = append(operand.modifiers, v.Op)
+			return operand, nil
+		}
+		return testOperand{}, SyntaxErrorf(rc.c, v.node, "expecting a %q expression, but got %q",
+			opFieldOf, v.Op)
+	case LiteralList:
+		values := make([]GValue, 0, len(v))
+		for _, e := range v {
+			value, ok := literalToGValue(e)
+			if !ok {
+				return testOperand{}, SyntaxErrorf(rc.c, expr.node, "unsupported value %v of list in %q", e, expr.Op)
+			}
+			values = append(values, value)
+		}
+		return testOperand{value: NewGVSet(values...)}, nil
+	}
+
+	value, ok := literalToGValue(v)
+	if !ok {
+		return testOperand{}, SyntaxErrorf(rc.c, expr.node, "unsupported operand %v in %q", v, expr.Op)
+	}
+	return testOperand{value: value}, nil
+}
+
+// compileFieldOf compile `(field-of $alias "field" keys...)` into a selector,
+// keys are looked up in the field in order, like `(field-of $x "Labels" "env")` for `Labels["env"]`
+func (rc *ruleCompiler) compileFieldOf(expr *Expression) (testOperand, error) {
+	if len(expr.Operand) < 2 {
+		return testOperand{}, SyntaxErrorf(rc.c, expr.node, "%q requires at least 2 operands, but got %d",
+			opFieldOf, len(expr.Operand))
+	}
+	alias, ok := expr.Operand[0].(Variable)
+	if !ok {
+		return testOperand{}, SyntaxErrorf(rc.c, expr.node, "expecting an alias in %q, but got %v",
+			opFieldOf, expr.Operand[0])
+	}
+	field, ok := expr.Operand[1].(string)
+	if !ok || field == "" {
+		return testOperand{}, SyntaxErrorf(rc.c, expr.node, "expecting a field name in %q, but got %v",
+			opFieldOf, expr.Operand[1])
+	}
+	for _, key := range expr.Operand[2:] {
+		switch key := key.(type) {
+		case string:
+			field += "[" + strconv.Quote(key) + "]"
+		case int64:
+			field += "[" + strconv.FormatInt(key, 10) + "]"
+		default:
+			return testOperand{}, SyntaxErrorf(rc.c, expr.node, "expecting a string or an int as key in %q, but got %v",
+				opFieldOf, key)
+		}
+	}
+
+	return testOperand{
+		selector: &rete.Selector{Alias: GVIdentity(alias), AliasAttr: GVString(field)},
+	}, nil
+}
+
+// modifyTestOp derive op to test on what modifiers derived from the arg-th operand
+func modifyTestOp(op rete.TestOp, modifiers []string, arg int) rete.TestOp {
+	// modifiers applied first should be the outermost to convert the operand
+	for i := len(modifiers) - 1; i >= 0; i-- {
+		switch modifiers[i] {
+		case opLen:
+			op = rete.LenOf(op, arg)
+		case opAnyElement:
+			op = rete.AnyElementOf(op, arg)
+		}
+	}
+	return op
+}
+
+// recordTest record that the test op on selectors is compiled from expr, the first one wins if there are many
+func (rc *ruleCompiler) recordTest(expr *Expression, op rete.TestOp, selectors ...rete.Selector) {
+	for _, s := range selectors {
+		key := testKey{alias: s.Alias, field: s.AliasAttr, op: op}
+		if _, in := rc.rule.testNodes[key]; !in {
+			rc.rule.testNodes[key] = expr.node
+		}
+	}
+}
+
+// Locate wrap a *rete.TestOpError returned when adding r.Production into a SyntaxError
+// at the test expression that the Guard or JoinTest is compiled from, other errors are returned as they are
+func (r *Rule) Locate(err error) error {
+	var e *rete.TestOpError
+	if !errors.As(err, &e) {
+		return err
+	}
+	node, in := r.testNodes[testKey{alias: e.Alias, field: e.Field, op: e.TestOp}]
+	if !in || node == nil {
+		return err
+	}
+	return syntaxErrorAt(r.c, node, err)
+}
+
+// declareAlias declare an alias if it is not declared yet, and return the index of its declaration
+func (rc *ruleCompiler) declareAlias(alias GVIdentity) int {
+	if idx, in := rc.aliasIdx[alias]; in {
+		return idx
+	}
+	idx := len(rc.rule.When)
+	rc.rule.When = append(rc.rule.When, rete.AliasDeclaration{Alias: alias})
+	rc.aliasIdx[alias] = idx
+	return idx
+}
+
+// recordField record the type of a field of an alias,
+// vt can be GValueTypeUnknown if we don't know what it is
+func (rc *ruleCompiler) recordField(s rete.Selector, vt GValueType) {
+	if s.AliasAttr == FieldSelf {
+		if vt != GValueTypeUnknown {
+			rc.selfType[s.Alias] = vt
+		}
+		return
+	}
+
+	fields, in := rc.fields[s.Alias]
+	if !in {
+		fields = make(map[string]GValueType, 2)
+		rc.fields[s.Alias] = fields
+	}
+	if t := fields[string(s.AliasAttr)]; t == GValueTypeUnknown {
+		fields[string(s.AliasAttr)] = vt
+	}
+}
+
+// fillTypeInfo fill the TypeInfo of each alias by how they are tested
+func (rc *ruleCompiler) fillTypeInfo() error {
+	if len(rc.rule.When) == 0 {
+		return SyntaxErrorf(rc.c, rc.def.node, "no alias is found in %s %s", rc.def.DefType, rc.def.ID)
+	}
+
+	for i := range rc.rule.When {
+		decl := &rc.rule.When[i]
+		fields := rc.fields[decl.Alias]
+		if name, in := rc.typeNames[decl.Alias]; in {
+			ti, _ := TypeInfoByName(name)
+			if err := rc.checkDeclaredType(decl.Alias, name, ti); err != nil {
+				return err
+			}
+			decl.Type, decl.TypeName = ti, name
+			continue
+		}
+		if t, in := rc.selfType[decl.Alias]; in {
+			if len(fields) > 0 {
+				return SyntaxErrorf(rc.c, rc.def.node, "alias %s is tested as %s, but fields are accessed",
+					decl.Alias, t)
+			}
+			decl.Type = TypeInfo{T: t}
+			continue
+		}
+		decl.Type = TypeInfo{T: GValueTypeStruct, Fields: fields}
+	}
+	return nil
+}
+
+// checkDeclaredType check if an alias is tested as what its declared type is
+func (rc *ruleCompiler) checkDeclaredType(alias GVIdentity, name string, ti TypeInfo) error {
+	if t, in := rc.selfType[alias]; in && t != ti.T {
+		return SyntaxErrorf(rc.c, rc.def.node, "alias %s is tested as %s, but declared as %s", alias, t, name)
+	}
+	for f := range rc.fields[alias] {
+		if ti.T != GValueTypeStruct && ti.T != GValueTypeRecord {
+			return SyntaxErrorf(rc.c, rc.def.node, "alias %s is declared as %s, but fields are accessed", alias, name)
+		}
+		if ti.VT == nil {
+			continue
+		}
+		if _, in := LookupFieldType(ti.VT, f); !in {
+			return SyntaxErrorf(rc.c, rc.def.node, "type %s of alias %s has no field %s", name, alias, f)
+		}
+	}
+	return nil
+}
+
+// valueTypeOfField guess the type of a field by the value it is tested with,
+// a field tested with a set is expected to be of the type of the values in the set
+func valueTypeOfField(v GValue) GValueType {
+	set, ok := v.(*GVSet)
+	if !ok {
+		return v.Type()
+	}
+	t := GValueTypeUnknown
+	for i, e := range set.Elems {
+		if i > 0 && e.Type() != t {
+			return GValueTypeUnknown
+		}
+		t = e.Type()
+	}
+	return t
+}
+
+// literalToGValue convert a literal parsed from script into GValue
+func literalToGValue(v any) (GValue, bool) {
+	switch v := v.(type) {
+	case int64:
+		return GVInt(v), true
+	case uint64:
+		return GVUint(v), true
+	case float64:
+		return GVFloat(v), true
+	case string:
+		return GVString(v), true
+	case bool:
+		return GVBool(v), true
+	case time.Duration:
+		return GVDuration(v), true
+	case *big.Rat:
+		return NewGVDecimalFromRat(v), true
+	}
+	return nil, false
+}
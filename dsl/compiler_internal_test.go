@@ -0,0 +1,545 @@
+package dsl
+
+import (
+	"math/big"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+
+	"github.com/ccbhj/grete/rete"
+	. "github.com/ccbhj/grete/types"
+)
+
+type Block struct {
+	ID     GVIdentity
+	Color  string
+	On     *Block
+	Rank   int
+	Stable bool
+}
+
+func fieldOf(alias, field string) *Expression {
+	return &Expression{Op: opFieldOf, Operand: []any{Variable(alias), field}}
+}
+
+func expr(op string, operands ...any) *Expression {
+	return &Expression{Op: op, Operand: operands}
+}
+
+func prdt(id string, sections ...any) *Definition {
+	return &Definition{DefType: DefTypePrdt, ID: id, Body: sections}
+}
+
+var _ = RegisterType[Block]("Block")
+
+var (
+	testOpOddRank = rete.RegisterTestOp("odd-rank", 2, func(args ...GValue) (bool, error) {
+		return args[1].(GVInt)%2 == args[0].(GVInt), nil
+	})
+	testOpRankBetween = rete.RegisterTestOp("rank-between", 3, func(args ...GValue) (bool, error) {
+		return args[0].(GVInt) < args[1].(GVInt) && args[1].(GVInt) < args[2].(GVInt), nil
+	})
+)
+
+var _ = Describe("Compiler", func() {
+	var c *ParseContext
+
+	BeforeEach(func() {
+		c = &ParseContext{env: make(map[string]any)}
+	})
+
+	It("can compile guards and join tests into a production", func() {
+		rule, err := compileRule(c, prdt("p",
+			expr(sectionWhen,
+				expr("eq", fieldOf("x", "Color"), "red"),
+				expr("eq", fieldOf("t", "Color"), "")),
+			expr(sectionMatch,
+				expr("not-eq", fieldOf("x", "On"), Variable("t")),
+				expr(">", fieldOf("x", "Rank"), int64(1)),
+				expr("<=", fieldOf("x", "Rank"), int64(5))),
+			expr(sectionThen, expr("emit", "found", Identifier("x"))),
+		))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rule.ID).Should(Equal("p"))
+		Expect(rule.When).Should(Equal([]rete.AliasDeclaration{
+			{
+				Alias: "x",
+				Type: TypeInfo{T: GValueTypeStruct, Fields: map[string]GValueType{
+					"Color": GValueTypeString,
+					"On":    GValueTypeUnknown,
+					"Rank":  GValueTypeInt,
+				}},
+				Guards: []rete.Guard{
+					{AliasAttr: "Color", Value: GVString("red"), TestOp: rete.TestOpEqual},
+					{AliasAttr: "Rank", Value: GVInt(1), TestOp: rete.TestOpLess},
+					{AliasAttr: "Rank", Value: GVInt(5), TestOp: rete.TestOpGreaterEqual},
+				},
+			},
+			{
+				Alias: "t",
+				Type: TypeInfo{T: GValueTypeStruct, Fields: map[string]GValueType{
+					"Color": GValueTypeString,
+				}},
+				Guards: []rete.Guard{
+					{AliasAttr: "Color", Value: GVString(""), TestOp: rete.TestOpEqual},
+				},
+			},
+		}))
+		Expect(rule.Match).Should(Equal([]rete.JoinTest{
+			{
+				Alias:  []rete.Selector{{Alias: "x", AliasAttr: "On"}, {Alias: "t", AliasAttr: FieldSelf}},
+				TestOp: rete.TestOpNotEqual,
+			},
+		}))
+		Expect(rule.Then).Should(HaveLen(1))
+	})
+
+	It("produces a production that the beta network can match", func() {
+		var b1, b2, table Block
+		table = Block{ID: "table"}
+		b2 = Block{ID: "B2", Color: "blue", On: &table, Rank: 2}
+		b1 = Block{ID: "B1", Color: "red", On: &b2, Rank: 1}
+
+		rule, err := compileRule(c, prdt("p",
+			expr(sectionWhen,
+				expr("eq", fieldOf("x", "Color"), "red"),
+				expr("eq", fieldOf("y", "Color"), "blue")),
+			expr(sectionMatch,
+				expr("eq", fieldOf("x", "On"), Variable("y")),
+				expr("<", fieldOf("x", "Rank"), fieldOf("y", "Rank"))),
+		))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		bn := rete.NewBetaNetwork(rete.NewAlphaNetwork())
+		pn, err := bn.AddProduction(rule.Production)
+		Expect(err).ShouldNot(HaveOccurred())
+		for _, b := range []*Block{&b1, &b2, &table} {
+			bn.AddFact(rete.Fact{ID: b.ID, Value: NewGVStruct(b)})
+		}
+		Expect(pn.Matches()).Should(ConsistOf(map[GVIdentity]any{
+			"x": &b1,
+			"y": &b2,
+		}))
+	})
+
+	It("can compile set membership with list literals", func() {
+		pc, err := MakeParseContext(`
+(define-prdt p
+  ([when ([in (field-of $x "Color") ("red" "blue")]
+          [not-in (field-of $x "Rank") (1 3)])]))`)
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rules).Should(HaveLen(1))
+
+		decl := rules[0].When[0]
+		Expect(decl.Guards).Should(Equal([]rete.Guard{
+			{AliasAttr: "Color", Value: NewGVSet(GVString("red"), GVString("blue")), TestOp: rete.TestOpIn},
+			{AliasAttr: "Rank", Value: NewGVSet(GVInt(1), GVInt(3)), TestOp: rete.TestOpNotIn},
+		}))
+		Expect(decl.Type.Fields).Should(Equal(map[string]GValueType{
+			"Color": GValueTypeString,
+			"Rank":  GValueTypeInt,
+		}))
+
+		bn := rete.NewBetaNetwork(rete.NewAlphaNetwork())
+		pn, err := bn.AddProduction(rules[0].Production)
+		Expect(err).ShouldNot(HaveOccurred())
+		blocks := []*Block{
+			{ID: "B1", Color: "red", Rank: 1},
+			{ID: "B2", Color: "blue", Rank: 2},
+			{ID: "B3", Color: "green", Rank: 2},
+		}
+		for _, b := range blocks {
+			bn.AddFact(rete.Fact{ID: b.ID, Value: NewGVStruct(b)})
+		}
+		Expect(pn.Matches()).Should(ConsistOf(map[GVIdentity]any{"x": blocks[1]}))
+	})
+
+	It("can compile guards and join tests on bool fields", func() {
+		pc, err := MakeParseContext(`
+(define-prdt p
+  ([when [eq (field-of $x "Stable") #f]]
+   [match ([eq (field-of $y "Stable") (field-of $x "Stable")] [not-eq $x $y])]))`)
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rules[0].When[0].Guards).Should(Equal([]rete.Guard{
+			{AliasAttr: "Stable", Value: GVBool(false), TestOp: rete.TestOpEqual},
+		}))
+		Expect(rules[0].When[0].Type.Fields).Should(HaveKeyWithValue("Stable", GValueTypeBool))
+
+		bn := rete.NewBetaNetwork(rete.NewAlphaNetwork())
+		pn, err := bn.AddProduction(rules[0].Production)
+		Expect(err).ShouldNot(HaveOccurred())
+		blocks := []*Block{
+			{ID: "B1", Stable: true},
+			{ID: "B2", Stable: false},
+		}
+		for _, b := range blocks {
+			bn.AddFact(rete.Fact{ID: b.ID, Value: NewGVStruct(b)})
+		}
+		Expect(pn.Matches()).Should(BeEmpty())
+
+		b3 := &Block{ID: "B3", Stable: false}
+		bn.AddFact(rete.Fact{ID: b3.ID, Value: NewGVStruct(b3)})
+		Expect(pn.Matches()).Should(ConsistOf(
+			map[GVIdentity]any{"x": blocks[1], "y": b3},
+			map[GVIdentity]any{"x": b3, "y": blocks[1]},
+		))
+	})
+
+	It("can compile nil tests", func() {
+		pc, err := MakeParseContext(`
+(define-prdt p
+  ([when ([is-nil (field-of $x "On")] [not-nil (field-of $y "On")] [< (field-of $y "On.Rank") 1])]
+   [match [not-eq $x $y]]))`)
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rules[0].When[0].Guards).Should(Equal([]rete.Guard{
+			{AliasAttr: "On", TestOp: rete.TestOpIsNil},
+		}))
+		Expect(rules[0].When[0].Type.Fields).Should(HaveKeyWithValue("On", GValueTypeUnknown))
+
+		bn := rete.NewBetaNetwork(rete.NewAlphaNetwork())
+		pn, err := bn.AddProduction(rules[0].Production)
+		Expect(err).ShouldNot(HaveOccurred())
+		table := &Block{ID: "table"}
+		b1 := &Block{ID: "B1", On: table, Rank: 1}
+		b2 := &Block{ID: "B2", On: b1}
+		for _, b := range []*Block{table, b1, b2} {
+			bn.AddFact(rete.Fact{ID: b.ID, Value: NewGVStruct(b)})
+		}
+		Expect(pn.Matches()).Should(ConsistOf(map[GVIdentity]any{"x": table, "y": b1}))
+
+		pc, err = MakeParseContext(`(define-prdt p ([when [is-nil (field-of $x "On") 1]]))`)
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = pc.Compile()
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("can compile temporal join tests with an offset", func() {
+		type Event struct {
+			ID   GVIdentity
+			Kind string
+			At   time.Time
+		}
+
+		pc, err := MakeParseContext(`
+(define-prdt p
+  ([when ([eq (field-of $a "Kind") "alert"] [eq (field-of $b "Kind") "ack"])]
+   [match [within-after (field-of $b "At") (field-of $a "At") 5m]]))`)
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rules[0].Match).Should(Equal([]rete.JoinTest{
+			{
+				Alias:  []rete.Selector{{Alias: "b", AliasAttr: "At"}, {Alias: "a", AliasAttr: "At"}},
+				Args:   []GValue{GVDuration(5 * time.Minute)},
+				TestOp: rete.TestOpWithinAfter,
+			},
+		}))
+
+		bn := rete.NewBetaNetwork(rete.NewAlphaNetwork())
+		pn, err := bn.AddProduction(rules[0].Production)
+		Expect(err).ShouldNot(HaveOccurred())
+		now := time.Now()
+		events := []*Event{
+			{ID: "alert", Kind: "alert", At: now},
+			{ID: "late-ack", Kind: "ack", At: now.Add(6 * time.Minute)},
+			{ID: "early-ack", Kind: "ack", At: now.Add(-time.Second)},
+			{ID: "ack", Kind: "ack", At: now.Add(5 * time.Minute)},
+		}
+		for _, e := range events {
+			bn.AddFact(rete.Fact{ID: e.ID, Value: NewGVStruct(e)})
+		}
+		Expect(pn.Matches()).Should(ConsistOf(map[GVIdentity]any{"a": events[0], "b": events[3]}))
+	})
+
+	It("can compile decimal literals which are compared exactly", func() {
+		type Order struct {
+			ID     GVIdentity
+			Total  GVDecimal
+			Amount float64
+			Cents  *big.Int
+		}
+
+		pc, err := MakeParseContext(`
+(define-prdt p
+  ([when ([>= (field-of $o "Total") 100.10M] [eq (field-of $o "Amount") 0.3M] [< (field-of $o "Cents") 10000.5M])]))`)
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rules[0].When[0].Type.Fields).Should(HaveKeyWithValue("Total", GValueTypeDecimal))
+
+		bn := rete.NewBetaNetwork(rete.NewAlphaNetwork())
+		pn, err := bn.AddProduction(rules[0].Production)
+		Expect(err).ShouldNot(HaveOccurred())
+		decimal := func(s string) GVDecimal {
+			d, err := NewGVDecimal(s)
+			Expect(err).ShouldNot(HaveOccurred())
+			return d
+		}
+		tenth, fifth := 0.1, 0.2
+		orders := []*Order{
+			{ID: "O1", Total: decimal("100.10"), Amount: 0.3, Cents: big.NewInt(10000)},
+			{ID: "O2", Total: decimal("100.09"), Amount: 0.3, Cents: big.NewInt(10000)},
+			{ID: "O3", Total: decimal("100.1"), Amount: tenth + fifth, Cents: big.NewInt(10000)},
+			{ID: "O4", Total: decimal("1e3"), Amount: 0.3, Cents: big.NewInt(10001)},
+		}
+		for _, o := range orders {
+			bn.AddFact(rete.Fact{ID: o.ID, Value: NewGVStruct(o)})
+		}
+		Expect(pn.Matches()).Should(ConsistOf(map[GVIdentity]any{"o": orders[0]}))
+	})
+
+	It("can compile string matching operators", func() {
+		rule, err := compileRule(c, prdt("p",
+			expr(sectionWhen,
+				expr("match", fieldOf("x", "Host"), `^web-\d+$`),
+				expr("eq-fold", fieldOf("x", "Env"), "PROD")),
+			expr(sectionMatch,
+				expr("has-prefix", fieldOf("x", "Code"), fieldOf("y", "Code"))),
+		))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rule.When[0].Guards).Should(Equal([]rete.Guard{
+			{AliasAttr: "Host", Value: GVString(`^web-\d+$`), TestOp: rete.TestOpMatch},
+			{AliasAttr: "Env", Value: GVString("PROD"), TestOp: rete.TestOpEqualFold},
+		}))
+		Expect(rule.Match).Should(Equal([]rete.JoinTest{
+			{
+				Alias:  []rete.Selector{{Alias: "y", AliasAttr: "Code"}, {Alias: "x", AliasAttr: "Code"}},
+				TestOp: rete.TestOpHasPrefix,
+			},
+		}))
+
+		_, err = compileRule(c, prdt("p",
+			expr(sectionWhen, expr("contains", "abc", fieldOf("x", "Code")))))
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("can compile tests on collections", func() {
+		type Host struct {
+			ID     GVIdentity
+			Tags   []string
+			Labels map[string]string
+		}
+
+		pc, err := MakeParseContext(`
+(define-prdt p
+  ([when ([contains (field-of $x "Tags") "web"]
+          [eq (field-of $x "Labels" "env") "prod"]
+          [> (len (field-of $y "Tags")) 1])]
+   [match [eq (any-element (field-of $y "Tags")) (field-of $x "Labels" "role")]]))`)
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		rule := rules[0]
+		Expect(rule.When[0].Guards).Should(Equal([]rete.Guard{
+			{AliasAttr: "Tags", Value: GVString("web"), TestOp: rete.TestOpContains},
+			{AliasAttr: `Labels["env"]`, Value: GVString("prod"), TestOp: rete.TestOpEqual},
+		}))
+		Expect(rule.When[0].Type.Fields).Should(Equal(map[string]GValueType{
+			"Tags":           GValueTypeUnknown,
+			`Labels["env"]`:  GValueTypeString,
+			`Labels["role"]`: GValueTypeUnknown,
+		}))
+		Expect(rule.When[1].Guards).Should(Equal([]rete.Guard{
+			{AliasAttr: "Tags", Value: GVInt(1), TestOp: rete.LenOf(rete.TestOpLess, 1)},
+		}))
+		Expect(rule.Match).Should(Equal([]rete.JoinTest{
+			{
+				Alias:  []rete.Selector{{Alias: "y", AliasAttr: "Tags"}, {Alias: "x", AliasAttr: `Labels["role"]`}},
+				TestOp: rete.AnyElementOf(rete.TestOpEqual, 0),
+			},
+		}))
+
+		bn := rete.NewBetaNetwork(rete.NewAlphaNetwork())
+		pn, err := bn.AddProduction(rule.Production)
+		Expect(err).ShouldNot(HaveOccurred())
+		hosts := []*Host{
+			{ID: "H1", Tags: []string{"web"}, Labels: map[string]string{"env": "prod", "role": "lb"}},
+			{ID: "H2", Tags: []string{"web"}, Labels: map[string]string{"env": "dev", "role": "lb"}},
+			{ID: "H3", Tags: []string{"db", "lb"}},
+			{ID: "H4", Tags: []string{"lb"}},
+		}
+		for _, h := range hosts {
+			bn.AddFact(rete.Fact{ID: h.ID, Value: NewGVStruct(h)})
+		}
+		Expect(pn.Matches()).Should(ConsistOf(map[GVIdentity]any{"x": hosts[0], "y": hosts[2]}))
+
+		var serr *SyntaxError
+		_, err = compileRule(c, prdt("p",
+			expr(sectionWhen, expr("eq", expr(opLen, "abc"), fieldOf("x", "Code")))))
+		Expect(err).Should(BeAssignableToTypeOf(serr))
+		_, err = compileRule(c, prdt("p",
+			expr(sectionWhen, expr("eq", expr(opFieldOf, Variable("x"), "Labels", 1.5), "prod"))))
+		Expect(err).Should(BeAssignableToTypeOf(serr))
+	})
+
+	It("can compile aliases of registered types", func() {
+		pc, err := MakeParseContext(`
+(define-prdt p
+  ([when ([is-a $x Block] [is-a $y "Block"] [eq (field-of $x "Color") "red"])]
+   [match [eq (field-of $x "On") $y]]))`)
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		rule := rules[0]
+		Expect(rule.When).Should(HaveLen(2))
+		Expect(rule.When[0].TypeName).Should(Equal("Block"))
+		Expect(rule.When[0].Type).Should(Equal(TypeInfoOf[Block]()))
+		Expect(rule.When[1].Type).Should(Equal(TypeInfoOf[Block]()))
+
+		bn := rete.NewBetaNetwork(rete.NewAlphaNetwork())
+		pn, err := bn.AddProduction(rule.Production)
+		Expect(err).ShouldNot(HaveOccurred())
+		table := &Block{ID: "table"}
+		b1 := &Block{ID: "B1", Color: "red", On: table}
+		for _, b := range []*Block{table, b1} {
+			bn.AddFact(rete.Fact{ID: b.ID, Value: NewGVStruct(b)})
+		}
+		Expect(pn.Matches()).Should(ConsistOf(map[GVIdentity]any{"x": b1, "y": table}))
+
+		var serr *SyntaxError
+		for _, script := range []string{
+			`(define-prdt p [when [is-a $x Chess]])`,
+			`(define-prdt p [when ([is-a $x Block] [eq (field-of $x "Size") 1])])`,
+			`(define-prdt p [when ([is-a $x Block] [eq $x 1])])`,
+			`(define-prdt p [when [is-a "x" Block]])`,
+		} {
+			pc, err := MakeParseContext(script)
+			Expect(err).ShouldNot(HaveOccurred(), script)
+			_, err = pc.Compile()
+			Expect(err).Should(BeAssignableToTypeOf(serr), script)
+		}
+	})
+
+	It("locates guards in section match by where they are written", func() {
+		pc, err := MakeParseContext(`
+(define-prdt p
+  ([when ([is-a $x Block] [is-a $y Block] [eq (field-of $x "Color") "red"])]
+   [match ([eq (field-of $x "On") $y]
+           [> (field-of $y "Rank") "high"])]))`)
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// the guard in match is merged into the declaration of $y
+		rule := rules[0]
+		Expect(rule.When[1].Guards).Should(Equal([]rete.Guard{
+			{AliasAttr: "Rank", Value: GVString("high"), TestOp: rete.TestOpLess},
+		}))
+		_, err = rete.NewBetaNetwork(rete.NewAlphaNetwork()).AddProduction(rule.Production)
+		Expect(err).Should(HaveOccurred())
+
+		var (
+			serr *SyntaxError
+			terr *rete.TestOpError
+		)
+		located := rule.Locate(err)
+		Expect(errors.As(located, &serr)).Should(BeTrue())
+		Expect(serr.beginLine).Should(Equal(5))
+		Expect(errors.As(located, &terr)).Should(BeTrue())
+		Expect(terr.Field).Should(Equal(GVString("Rank")))
+
+		Expect(rule.Locate(errors.New("other"))).Should(MatchError("other"))
+	})
+
+	It("can compile TestOps registered by name", func() {
+		pc, err := MakeParseContext(`
+(define-prdt p
+  ([when [odd-rank 1 (field-of $x "Rank")]]
+   [match [rank-between (field-of $x "Rank") (field-of $y "Rank") (field-of $z "Rank")]]))`)
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rules).Should(HaveLen(1))
+
+		rule := rules[0]
+		Expect(rule.When).Should(HaveLen(3))
+		Expect(rule.When[0].Guards).Should(Equal([]rete.Guard{
+			{AliasAttr: "Rank", Value: GVInt(1), TestOp: testOpOddRank},
+		}))
+		Expect(rule.Match).Should(Equal([]rete.JoinTest{
+			{
+				Alias: []rete.Selector{
+					{Alias: "x", AliasAttr: "Rank"}, {Alias: "y", AliasAttr: "Rank"}, {Alias: "z", AliasAttr: "Rank"},
+				},
+				TestOp: testOpRankBetween,
+			},
+		}))
+
+		var serr *SyntaxError
+		_, err = compileRule(c, prdt("p",
+			expr(sectionMatch, expr("rank-between", fieldOf("x", "Rank"), fieldOf("y", "Rank")))))
+		Expect(err).Should(BeAssignableToTypeOf(serr))
+		_, err = compileRule(c, prdt("p",
+			expr(sectionMatch, expr("rank-between", fieldOf("x", "Rank"), 1, fieldOf("y", "Rank")))))
+		Expect(err).Should(BeAssignableToTypeOf(serr))
+	})
+
+	It("reports SyntaxError for what it cannot map", func() {
+		var serr *SyntaxError
+		_, err := compileRule(c, prdt("p",
+			expr(sectionWhen, expr("like", fieldOf("x", "Color"), "red"))))
+		Expect(err).Should(BeAssignableToTypeOf(serr))
+
+		_, err = compileRule(c, prdt("p",
+			expr(sectionWhen, expr("eq", "red", "blue"))))
+		Expect(err).Should(BeAssignableToTypeOf(serr))
+
+		_, err = compileRule(c, prdt("p",
+			expr("unless", expr("eq", fieldOf("x", "Color"), "red"))))
+		Expect(err).Should(BeAssignableToTypeOf(serr))
+
+		_, err = compileRule(c, prdt("p",
+			expr(sectionWhen, expr("eq", Identifier("x"), "red"))))
+		Expect(err).Should(BeAssignableToTypeOf(serr))
+
+		_, err = compileRule(c, prdt("",
+			expr(sectionWhen, expr("eq", fieldOf("x", "Color"), "red"))))
+		Expect(err).Should(BeAssignableToTypeOf(serr))
+	})
+
+	It("can compile definitions parsed from script", func() {
+		pc, err := MakeParseContext(`(define-prdt p [when [eq $x 1] [= $y 2]])`)
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rules).Should(HaveLen(1))
+		Expect(rules[0].When).Should(HaveLen(2))
+		Expect(rules[0].When[1].Alias).Should(BeEquivalentTo("y"))
+		Expect(rules[0].When[1].Type).Should(Equal(TypeInfo{T: GValueTypeInt}))
+	})
+
+	It("can compile the sample script", func() {
+		script, err := os.ReadFile("doc/test.prd")
+		Expect(err).ShouldNot(HaveOccurred())
+		pc, err := MakeParseContext(string(script))
+		Expect(err).ShouldNot(HaveOccurred())
+		rules, err := pc.Compile()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rules).Should(HaveLen(1))
+
+		rule := rules[0]
+		Expect(rule.ID).Should(Equal("testing_prdt"))
+		Expect(rule.When).Should(HaveLen(2))
+		Expect(rule.When[0].Guards).Should(ConsistOf(
+			rete.Guard{AliasAttr: "Color", Value: GVInt(10), TestOp: rete.TestOpEqual},
+			rete.Guard{AliasAttr: "Rank", Value: GVInt(10), TestOp: rete.TestOpLess},
+		))
+		Expect(rule.Match).Should(ConsistOf(rete.JoinTest{
+			Alias:  []rete.Selector{{Alias: "x", AliasAttr: "On"}, {Alias: "TABLE", AliasAttr: FieldSelf}},
+			TestOp: rete.TestOpNotEqual,
+		}))
+		Expect(rule.Then).Should(HaveLen(1))
+		Expect(rule.Then[0].Op).Should(Equal("emit"))
+	})
+})
@@ -2,7 +2,6 @@ package dsl
 
 import (
 	. "github.com/onsi/ginkgo/v2"
-	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("DSL", func() {
@@ -26,9 +26,15 @@ const (
 	ruleExpression
 	ruleOperator
 	ruleOperand
+	ruleExpressionList
+	ruleLiteralList
 	ruleSpacing
+	ruleVariable
 	ruleIdentifier
+	ruleSymbolOperator
 	ruleLiteral
+	ruleDurationLiteral
+	ruleDecimalLiteral
 	ruleBoolLiteral
 	ruleFloatLiteral
 	ruleExponent
@@ -59,9 +65,15 @@ var rul3s = [...]string{
 	"Expression",
 	"Operator",
 	"Operand",
+	"ExpressionList",
+	"LiteralList",
 	"Spacing",
+	"Variable",
 	"Identifier",
+	"SymbolOperator",
 	"Literal",
+	"DurationLiteral",
+	"DecimalLiteral",
 	"BoolLiteral",
 	"FloatLiteral",
 	"Exponent",
@@ -194,7 +206,7 @@ func (t *tokens32) Tokens() []token32 {
 type PRD struct {
 	Buffer string
 	buffer []rune
-	rules  [30]func() bool
+	rules  [36]func() bool
 	parse  func(rule ...int) error
 	reset  func()
 	Pretty bool
@@ -762,14 +774,80 @@ func (p *PRD) Init(options ...func(*PRD) error) error {
 			position, tokenIndex = position46, tokenIndex46
 			return false
 		},
-		/* 6 Operator <- <(Identifier Spacing)> */
+		/* 6 Operator <- <((Identifier / SymbolOperator) Spacing)> */
 		func() bool {
 			position54, tokenIndex54 := position, tokenIndex
 			{
 				position55 := position
-				if !_rules[ruleIdentifier]() {
-					goto l54
+				{
+					position56, tokenIndex56 := position, tokenIndex
+					if !_rules[ruleIdentifier]() {
+						goto l57
+					}
+					goto l56
+				l57:
+					position, tokenIndex = position56, tokenIndex56
+					{
+						position58 := position
+						{
+							position59, tokenIndex59 := position, tokenIndex
+							if buffer[position] != rune('<') {
+								goto l60
+							}
+							position++
+							if buffer[position] != rune('=') {
+								goto l60
+							}
+							position++
+							goto l59
+						l60:
+							position, tokenIndex = position59, tokenIndex59
+							if buffer[position] != rune('>') {
+								goto l61
+							}
+							position++
+							if buffer[position] != rune('=') {
+								goto l61
+							}
+							position++
+							goto l59
+						l61:
+							position, tokenIndex = position59, tokenIndex59
+							{
+								switch buffer[position] {
+								case '=':
+									if buffer[position] != rune('=') {
+										goto l54
+									}
+									position++
+								case '>':
+									if buffer[position] != rune('>') {
+										goto l54
+									}
+									position++
+								case '<':
+									if buffer[position] != rune('<') {
+										goto l54
+									}
+									position++
+								default:
+									if buffer[position] != rune('!') {
+										goto l54
+									}
+									position++
+									if buffer[position] != rune('=') {
+										goto l54
+									}
+									position++
+								}
+							}
+
+						}
+					l59:
+						add(ruleSymbolOperator, position58)
+					}
 				}
+			l56:
 				if !_rules[ruleSpacing]() {
 					goto l54
 				}
@@ -780,1209 +858,1691 @@ func (p *PRD) Init(options ...func(*PRD) error) error {
 			position, tokenIndex = position54, tokenIndex54
 			return false
 		},
-		/* 7 Operand <- <(((&('\t' | '\n' | '\r' | ' ' | '(' | ';' | '[') Expression) | (&('"' | '#' | '+' | '-' | '.' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') Literal) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z' | '_' | 'a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') Identifier)) Spacing)> */
+		/* 7 Operand <- <((Expression / ExpressionList / ((&('$') Variable) | (&('\t' | '\n' | '\r' | ' ' | '(' | ';' | '[') LiteralList) | (&('"' | '#' | '+' | '-' | '.' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') Literal) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z' | '_' | 'a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') Identifier))) Spacing)> */
 		func() bool {
-			position56, tokenIndex56 := position, tokenIndex
+			position63, tokenIndex63 := position, tokenIndex
 			{
-				position57 := position
+				position64 := position
 				{
-					switch buffer[position] {
-					case '\t', '\n', '\r', ' ', '(', ';', '[':
-						if !_rules[ruleExpression]() {
-							goto l56
-						}
-					case '"', '#', '+', '-', '.', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-						if !_rules[ruleLiteral]() {
-							goto l56
+					position65, tokenIndex65 := position, tokenIndex
+					if !_rules[ruleExpression]() {
+						goto l66
+					}
+					goto l65
+				l66:
+					position, tokenIndex = position65, tokenIndex65
+					{
+						position68 := position
+						{
+							position69, tokenIndex69 := position, tokenIndex
+							if !_rules[ruleLPAR]() {
+								goto l70
+							}
+							if !_rules[ruleExpression]() {
+								goto l70
+							}
+						l71:
+							{
+								position72, tokenIndex72 := position, tokenIndex
+								if !_rules[ruleExpression]() {
+									goto l72
+								}
+								goto l71
+							l72:
+								position, tokenIndex = position72, tokenIndex72
+							}
+							if !_rules[ruleRPAR]() {
+								goto l70
+							}
+							goto l69
+						l70:
+							position, tokenIndex = position69, tokenIndex69
+							if !_rules[ruleLBRK]() {
+								goto l67
+							}
+							if !_rules[ruleExpression]() {
+								goto l67
+							}
+						l73:
+							{
+								position74, tokenIndex74 := position, tokenIndex
+								if !_rules[ruleExpression]() {
+									goto l74
+								}
+								goto l73
+							l74:
+								position, tokenIndex = position74, tokenIndex74
+							}
+							if !_rules[ruleRBRK]() {
+								goto l67
+							}
 						}
-					default:
-						if !_rules[ruleIdentifier]() {
-							goto l56
+					l69:
+						add(ruleExpressionList, position68)
+					}
+					goto l65
+				l67:
+					position, tokenIndex = position65, tokenIndex65
+					{
+						switch buffer[position] {
+						case '$':
+							{
+								position76 := position
+								if buffer[position] != rune('$') {
+									goto l63
+								}
+								position++
+								if !_rules[ruleLetter]() {
+									goto l63
+								}
+							l77:
+								{
+									position78, tokenIndex78 := position, tokenIndex
+									if !_rules[ruleLetterOrDigit]() {
+										goto l78
+									}
+									goto l77
+								l78:
+									position, tokenIndex = position78, tokenIndex78
+								}
+								{
+									position79, tokenIndex79 := position, tokenIndex
+									if !_rules[ruleSpacing]() {
+										goto l79
+									}
+									goto l80
+								l79:
+									position, tokenIndex = position79, tokenIndex79
+								}
+							l80:
+								add(ruleVariable, position76)
+							}
+						case '\t', '\n', '\r', ' ', '(', ';', '[':
+							{
+								position81 := position
+								{
+									position82, tokenIndex82 := position, tokenIndex
+									if !_rules[ruleLPAR]() {
+										goto l83
+									}
+									if !_rules[ruleLiteral]() {
+										goto l83
+									}
+									if !_rules[ruleSpacing]() {
+										goto l83
+									}
+								l84:
+									{
+										position85, tokenIndex85 := position, tokenIndex
+										if !_rules[ruleLiteral]() {
+											goto l85
+										}
+										if !_rules[ruleSpacing]() {
+											goto l85
+										}
+										goto l84
+									l85:
+										position, tokenIndex = position85, tokenIndex85
+									}
+									if !_rules[ruleRPAR]() {
+										goto l83
+									}
+									goto l82
+								l83:
+									position, tokenIndex = position82, tokenIndex82
+									if !_rules[ruleLBRK]() {
+										goto l63
+									}
+									if !_rules[ruleLiteral]() {
+										goto l63
+									}
+									if !_rules[ruleSpacing]() {
+										goto l63
+									}
+								l86:
+									{
+										position87, tokenIndex87 := position, tokenIndex
+										if !_rules[ruleLiteral]() {
+											goto l87
+										}
+										if !_rules[ruleSpacing]() {
+											goto l87
+										}
+										goto l86
+									l87:
+										position, tokenIndex = position87, tokenIndex87
+									}
+									if !_rules[ruleRBRK]() {
+										goto l63
+									}
+								}
+							l82:
+								add(ruleLiteralList, position81)
+							}
+						case '"', '#', '+', '-', '.', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+							if !_rules[ruleLiteral]() {
+								goto l63
+							}
+						default:
+							if !_rules[ruleIdentifier]() {
+								goto l63
+							}
 						}
 					}
-				}
 
+				}
+			l65:
 				if !_rules[ruleSpacing]() {
-					goto l56
+					goto l63
 				}
-				add(ruleOperand, position57)
+				add(ruleOperand, position64)
 			}
 			return true
-		l56:
-			position, tokenIndex = position56, tokenIndex56
+		l63:
+			position, tokenIndex = position63, tokenIndex63
 			return false
 		},
-		/* 8 Spacing <- <(((&('\n') '\n') | (&('\r') '\r') | (&('\t') '\t') | (&(' ') ' '))+ / (';' (!('\r' / '\n') .)* ('\r' / '\n')))*> */
+		/* 8 ExpressionList <- <((LPAR Expression+ RPAR) / (LBRK Expression+ RBRK))> */
+		nil,
+		/* 9 LiteralList <- <((LPAR (Literal Spacing)+ RPAR) / (LBRK (Literal Spacing)+ RBRK))> */
+		nil,
+		/* 10 Spacing <- <(((&('\n') '\n') | (&('\r') '\r') | (&('\t') '\t') | (&(' ') ' '))+ / (';' (!('\r' / '\n') .)* ('\r' / '\n')))*> */
 		func() bool {
 			{
-				position60 := position
-			l61:
+				position91 := position
+			l92:
 				{
-					position62, tokenIndex62 := position, tokenIndex
+					position93, tokenIndex93 := position, tokenIndex
 					{
-						position63, tokenIndex63 := position, tokenIndex
+						position94, tokenIndex94 := position, tokenIndex
 						{
 							switch buffer[position] {
 							case '\n':
 								if buffer[position] != rune('\n') {
-									goto l64
+									goto l95
 								}
 								position++
 							case '\r':
 								if buffer[position] != rune('\r') {
-									goto l64
+									goto l95
 								}
 								position++
 							case '\t':
 								if buffer[position] != rune('\t') {
-									goto l64
+									goto l95
 								}
 								position++
 							default:
 								if buffer[position] != rune(' ') {
-									goto l64
+									goto l95
 								}
 								position++
 							}
 						}
 
-					l65:
+					l96:
 						{
-							position66, tokenIndex66 := position, tokenIndex
+							position97, tokenIndex97 := position, tokenIndex
 							{
 								switch buffer[position] {
 								case '\n':
 									if buffer[position] != rune('\n') {
-										goto l66
+										goto l97
 									}
 									position++
 								case '\r':
 									if buffer[position] != rune('\r') {
-										goto l66
+										goto l97
 									}
 									position++
 								case '\t':
 									if buffer[position] != rune('\t') {
-										goto l66
+										goto l97
 									}
 									position++
 								default:
 									if buffer[position] != rune(' ') {
-										goto l66
+										goto l97
 									}
 									position++
 								}
 							}
 
-							goto l65
-						l66:
-							position, tokenIndex = position66, tokenIndex66
+							goto l96
+						l97:
+							position, tokenIndex = position97, tokenIndex97
 						}
-						goto l63
-					l64:
-						position, tokenIndex = position63, tokenIndex63
+						goto l94
+					l95:
+						position, tokenIndex = position94, tokenIndex94
 						if buffer[position] != rune(';') {
-							goto l62
+							goto l93
 						}
 						position++
-					l69:
+					l100:
 						{
-							position70, tokenIndex70 := position, tokenIndex
+							position101, tokenIndex101 := position, tokenIndex
 							{
-								position71, tokenIndex71 := position, tokenIndex
+								position102, tokenIndex102 := position, tokenIndex
 								{
-									position72, tokenIndex72 := position, tokenIndex
+									position103, tokenIndex103 := position, tokenIndex
 									if buffer[position] != rune('\r') {
-										goto l73
+										goto l104
 									}
 									position++
-									goto l72
-								l73:
-									position, tokenIndex = position72, tokenIndex72
+									goto l103
+								l104:
+									position, tokenIndex = position103, tokenIndex103
 									if buffer[position] != rune('\n') {
-										goto l71
+										goto l102
 									}
 									position++
 								}
-							l72:
-								goto l70
-							l71:
-								position, tokenIndex = position71, tokenIndex71
+							l103:
+								goto l101
+							l102:
+								position, tokenIndex = position102, tokenIndex102
 							}
 							if !matchDot() {
-								goto l70
+								goto l101
 							}
-							goto l69
-						l70:
-							position, tokenIndex = position70, tokenIndex70
+							goto l100
+						l101:
+							position, tokenIndex = position101, tokenIndex101
 						}
 						{
-							position74, tokenIndex74 := position, tokenIndex
+							position105, tokenIndex105 := position, tokenIndex
 							if buffer[position] != rune('\r') {
-								goto l75
+								goto l106
 							}
 							position++
-							goto l74
-						l75:
-							position, tokenIndex = position74, tokenIndex74
+							goto l105
+						l106:
+							position, tokenIndex = position105, tokenIndex105
 							if buffer[position] != rune('\n') {
-								goto l62
+								goto l93
 							}
 							position++
 						}
-					l74:
+					l105:
 					}
-				l63:
-					goto l61
-				l62:
-					position, tokenIndex = position62, tokenIndex62
+				l94:
+					goto l92
+				l93:
+					position, tokenIndex = position93, tokenIndex93
 				}
-				add(ruleSpacing, position60)
+				add(ruleSpacing, position91)
 			}
 			return true
 		},
-		/* 9 Identifier <- <(!Keyword Letter LetterOrDigit* Spacing?)> */
+		/* 11 Variable <- <('$' Letter LetterOrDigit* Spacing?)> */
+		nil,
+		/* 12 Identifier <- <(!Keyword Letter LetterOrDigit* ('-' LetterOrDigit+)* Spacing?)> */
 		func() bool {
-			position76, tokenIndex76 := position, tokenIndex
+			position108, tokenIndex108 := position, tokenIndex
 			{
-				position77 := position
+				position109 := position
 				{
-					position78, tokenIndex78 := position, tokenIndex
+					position110, tokenIndex110 := position, tokenIndex
 					{
-						position79 := position
+						position111 := position
 						{
-							position80, tokenIndex80 := position, tokenIndex
+							position112, tokenIndex112 := position, tokenIndex
 							if buffer[position] != rune('d') {
-								goto l81
+								goto l113
 							}
 							position++
 							if buffer[position] != rune('e') {
-								goto l81
+								goto l113
 							}
 							position++
 							if buffer[position] != rune('f') {
-								goto l81
+								goto l113
 							}
 							position++
 							if buffer[position] != rune('i') {
-								goto l81
+								goto l113
 							}
 							position++
 							if buffer[position] != rune('n') {
-								goto l81
+								goto l113
 							}
 							position++
 							if buffer[position] != rune('e') {
-								goto l81
+								goto l113
 							}
 							position++
 							if buffer[position] != rune('-') {
-								goto l81
+								goto l113
 							}
 							position++
 							if buffer[position] != rune('p') {
-								goto l81
+								goto l113
 							}
 							position++
 							if buffer[position] != rune('r') {
-								goto l81
+								goto l113
 							}
 							position++
 							if buffer[position] != rune('d') {
-								goto l81
+								goto l113
 							}
 							position++
 							if buffer[position] != rune('t') {
-								goto l81
+								goto l113
 							}
 							position++
-							goto l80
-						l81:
-							position, tokenIndex = position80, tokenIndex80
+							goto l112
+						l113:
+							position, tokenIndex = position112, tokenIndex112
 							if buffer[position] != rune('d') {
-								goto l82
+								goto l114
 							}
 							position++
 							if buffer[position] != rune('e') {
-								goto l82
+								goto l114
 							}
 							position++
 							if buffer[position] != rune('f') {
-								goto l82
+								goto l114
 							}
 							position++
 							if buffer[position] != rune('i') {
-								goto l82
+								goto l114
 							}
 							position++
 							if buffer[position] != rune('n') {
-								goto l82
+								goto l114
 							}
 							position++
 							if buffer[position] != rune('e') {
-								goto l82
+								goto l114
 							}
 							position++
 							if buffer[position] != rune('-') {
-								goto l82
+								goto l114
 							}
 							position++
 							if buffer[position] != rune('l') {
-								goto l82
+								goto l114
 							}
 							position++
 							if buffer[position] != rune('h') {
-								goto l82
+								goto l114
 							}
 							position++
 							if buffer[position] != rune('s') {
-								goto l82
+								goto l114
 							}
 							position++
-							goto l80
-						l82:
-							position, tokenIndex = position80, tokenIndex80
+							goto l112
+						l114:
+							position, tokenIndex = position112, tokenIndex112
 							if buffer[position] != rune('d') {
-								goto l83
+								goto l115
 							}
 							position++
 							if buffer[position] != rune('e') {
-								goto l83
+								goto l115
 							}
 							position++
 							if buffer[position] != rune('f') {
-								goto l83
+								goto l115
 							}
 							position++
 							if buffer[position] != rune('i') {
-								goto l83
+								goto l115
 							}
 							position++
 							if buffer[position] != rune('n') {
-								goto l83
+								goto l115
 							}
 							position++
 							if buffer[position] != rune('e') {
-								goto l83
+								goto l115
 							}
 							position++
 							if buffer[position] != rune('-') {
-								goto l83
+								goto l115
 							}
 							position++
 							if buffer[position] != rune('r') {
-								goto l83
+								goto l115
 							}
 							position++
 							if buffer[position] != rune('h') {
-								goto l83
+								goto l115
 							}
 							position++
 							if buffer[position] != rune('s') {
-								goto l83
+								goto l115
 							}
 							position++
-							goto l80
-						l83:
-							position, tokenIndex = position80, tokenIndex80
+							goto l112
+						l115:
+							position, tokenIndex = position112, tokenIndex112
 							if buffer[position] != rune('r') {
-								goto l84
+								goto l116
 							}
 							position++
 							if buffer[position] != rune('u') {
-								goto l84
+								goto l116
 							}
 							position++
 							if buffer[position] != rune('l') {
-								goto l84
+								goto l116
 							}
 							position++
 							if buffer[position] != rune('e') {
-								goto l84
+								goto l116
 							}
 							position++
-							goto l80
-						l84:
-							position, tokenIndex = position80, tokenIndex80
+							goto l112
+						l116:
+							position, tokenIndex = position112, tokenIndex112
 							if buffer[position] != rune('d') {
-								goto l78
+								goto l110
 							}
 							position++
 							if buffer[position] != rune('e') {
-								goto l78
+								goto l110
 							}
 							position++
 							if buffer[position] != rune('f') {
-								goto l78
+								goto l110
 							}
 							position++
 							if buffer[position] != rune('i') {
-								goto l78
+								goto l110
 							}
 							position++
 							if buffer[position] != rune('n') {
-								goto l78
+								goto l110
 							}
 							position++
 							if buffer[position] != rune('e') {
-								goto l78
+								goto l110
 							}
 							position++
 						}
-					l80:
+					l112:
 						{
-							position85, tokenIndex85 := position, tokenIndex
-							if !_rules[ruleLetterOrDigit]() {
-								goto l85
+							position117, tokenIndex117 := position, tokenIndex
+							{
+								position118, tokenIndex118 := position, tokenIndex
+								if !_rules[ruleLetterOrDigit]() {
+									goto l119
+								}
+								goto l118
+							l119:
+								position, tokenIndex = position118, tokenIndex118
+								if buffer[position] != rune('-') {
+									goto l117
+								}
+								position++
 							}
-							goto l78
-						l85:
-							position, tokenIndex = position85, tokenIndex85
+						l118:
+							goto l110
+						l117:
+							position, tokenIndex = position117, tokenIndex117
 						}
-						add(ruleKeyword, position79)
+						add(ruleKeyword, position111)
 					}
-					goto l76
-				l78:
-					position, tokenIndex = position78, tokenIndex78
+					goto l108
+				l110:
+					position, tokenIndex = position110, tokenIndex110
+				}
+				if !_rules[ruleLetter]() {
+					goto l108
 				}
+			l120:
 				{
-					position86 := position
-					{
-						switch buffer[position] {
-						case '_':
-							if buffer[position] != rune('_') {
-								goto l76
-							}
-							position++
-						case 'A', 'B', 'C', 'D', 'E', 'F', 'G', 'H', 'I', 'J', 'K', 'L', 'M', 'N', 'O', 'P', 'Q', 'R', 'S', 'T', 'U', 'V', 'W', 'X', 'Y', 'Z':
-							if c := buffer[position]; c < rune('A') || c > rune('Z') {
-								goto l76
-							}
-							position++
-						default:
-							if c := buffer[position]; c < rune('a') || c > rune('z') {
-								goto l76
-							}
-							position++
-						}
+					position121, tokenIndex121 := position, tokenIndex
+					if !_rules[ruleLetterOrDigit]() {
+						goto l121
 					}
-
-					add(ruleLetter, position86)
+					goto l120
+				l121:
+					position, tokenIndex = position121, tokenIndex121
 				}
-			l88:
+			l122:
 				{
-					position89, tokenIndex89 := position, tokenIndex
+					position123, tokenIndex123 := position, tokenIndex
+					if buffer[position] != rune('-') {
+						goto l123
+					}
+					position++
 					if !_rules[ruleLetterOrDigit]() {
-						goto l89
+						goto l123
+					}
+				l124:
+					{
+						position125, tokenIndex125 := position, tokenIndex
+						if !_rules[ruleLetterOrDigit]() {
+							goto l125
+						}
+						goto l124
+					l125:
+						position, tokenIndex = position125, tokenIndex125
 					}
-					goto l88
-				l89:
-					position, tokenIndex = position89, tokenIndex89
+					goto l122
+				l123:
+					position, tokenIndex = position123, tokenIndex123
 				}
 				{
-					position90, tokenIndex90 := position, tokenIndex
+					position126, tokenIndex126 := position, tokenIndex
 					if !_rules[ruleSpacing]() {
-						goto l90
+						goto l126
 					}
-					goto l91
-				l90:
-					position, tokenIndex = position90, tokenIndex90
+					goto l127
+				l126:
+					position, tokenIndex = position126, tokenIndex126
 				}
-			l91:
-				add(ruleIdentifier, position77)
+			l127:
+				add(ruleIdentifier, position109)
 			}
 			return true
-		l76:
-			position, tokenIndex = position76, tokenIndex76
+		l108:
+			position, tokenIndex = position108, tokenIndex108
 			return false
 		},
-		/* 10 Literal <- <(FloatLiteral / ((&('#') BoolLiteral) | (&('"') StringLiteral) | (&('-' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') IntegerLiteral)))> */
+		/* 13 SymbolOperator <- <(('<' '=') / ('>' '=') / ((&('=') '=') | (&('>') '>') | (&('<') '<') | (&('!') ('!' '='))))> */
+		nil,
+		/* 14 Literal <- <(DecimalLiteral / DurationLiteral / FloatLiteral / ((&('#') BoolLiteral) | (&('"') StringLiteral) | (&('-' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') IntegerLiteral)))> */
 		func() bool {
-			position92, tokenIndex92 := position, tokenIndex
+			position129, tokenIndex129 := position, tokenIndex
 			{
-				position93 := position
+				position130 := position
 				{
-					position94, tokenIndex94 := position, tokenIndex
+					position131, tokenIndex131 := position, tokenIndex
 					{
-						position96 := position
+						position133 := position
 						{
-							position97, tokenIndex97 := position, tokenIndex
+							position134, tokenIndex134 := position, tokenIndex
 							{
-								position99, tokenIndex99 := position, tokenIndex
+								position136, tokenIndex136 := position, tokenIndex
 								if buffer[position] != rune('+') {
-									goto l100
+									goto l137
 								}
 								position++
-								goto l99
-							l100:
-								position, tokenIndex = position99, tokenIndex99
+								goto l136
+							l137:
+								position, tokenIndex = position136, tokenIndex136
 								if buffer[position] != rune('-') {
-									goto l97
+									goto l134
 								}
 								position++
 							}
-						l99:
-							goto l98
-						l97:
-							position, tokenIndex = position97, tokenIndex97
+						l136:
+							goto l135
+						l134:
+							position, tokenIndex = position134, tokenIndex134
 						}
-					l98:
+					l135:
 						{
-							position101, tokenIndex101 := position, tokenIndex
+							position138, tokenIndex138 := position, tokenIndex
 							if !_rules[ruleDigits]() {
-								goto l102
+								goto l139
 							}
+							{
+								position140, tokenIndex140 := position, tokenIndex
+								if buffer[position] != rune('.') {
+									goto l140
+								}
+								position++
+								{
+									position142, tokenIndex142 := position, tokenIndex
+									if !_rules[ruleDigits]() {
+										goto l142
+									}
+									goto l143
+								l142:
+									position, tokenIndex = position142, tokenIndex142
+								}
+							l143:
+								goto l141
+							l140:
+								position, tokenIndex = position140, tokenIndex140
+							}
+						l141:
+							{
+								position144, tokenIndex144 := position, tokenIndex
+								if !_rules[ruleExponent]() {
+									goto l144
+								}
+								goto l145
+							l144:
+								position, tokenIndex = position144, tokenIndex144
+							}
+						l145:
+							goto l138
+						l139:
+							position, tokenIndex = position138, tokenIndex138
 							if buffer[position] != rune('.') {
-								goto l102
+								goto l132
 							}
 							position++
+							if !_rules[ruleDigits]() {
+								goto l132
+							}
 							{
-								position103, tokenIndex103 := position, tokenIndex
+								position146, tokenIndex146 := position, tokenIndex
+								if !_rules[ruleExponent]() {
+									goto l146
+								}
+								goto l147
+							l146:
+								position, tokenIndex = position146, tokenIndex146
+							}
+						l147:
+						}
+					l138:
+						if buffer[position] != rune('M') {
+							goto l132
+						}
+						position++
+						{
+							position148, tokenIndex148 := position, tokenIndex
+							if !_rules[ruleLetterOrDigit]() {
+								goto l148
+							}
+							goto l132
+						l148:
+							position, tokenIndex = position148, tokenIndex148
+						}
+						add(ruleDecimalLiteral, position133)
+					}
+					goto l131
+				l132:
+					position, tokenIndex = position131, tokenIndex131
+					{
+						position150 := position
+						{
+							position151, tokenIndex151 := position, tokenIndex
+							if buffer[position] != rune('-') {
+								goto l151
+							}
+							position++
+							goto l152
+						l151:
+							position, tokenIndex = position151, tokenIndex151
+						}
+					l152:
+						if !_rules[ruleDigits]() {
+							goto l149
+						}
+						{
+							position155, tokenIndex155 := position, tokenIndex
+							if buffer[position] != rune('.') {
+								goto l155
+							}
+							position++
+							if !_rules[ruleDigits]() {
+								goto l155
+							}
+							goto l156
+						l155:
+							position, tokenIndex = position155, tokenIndex155
+						}
+					l156:
+						{
+							position157, tokenIndex157 := position, tokenIndex
+							if buffer[position] != rune('m') {
+								goto l158
+							}
+							position++
+							if buffer[position] != rune('s') {
+								goto l158
+							}
+							position++
+							goto l157
+						l158:
+							position, tokenIndex = position157, tokenIndex157
+							{
+								switch buffer[position] {
+								case 'h':
+									if buffer[position] != rune('h') {
+										goto l149
+									}
+									position++
+								case 'm':
+									if buffer[position] != rune('m') {
+										goto l149
+									}
+									position++
+								case 's':
+									if buffer[position] != rune('s') {
+										goto l149
+									}
+									position++
+								case 'u':
+									if buffer[position] != rune('u') {
+										goto l149
+									}
+									position++
+									if buffer[position] != rune('s') {
+										goto l149
+									}
+									position++
+								default:
+									if buffer[position] != rune('n') {
+										goto l149
+									}
+									position++
+									if buffer[position] != rune('s') {
+										goto l149
+									}
+									position++
+								}
+							}
+
+						}
+					l157:
+					l153:
+						{
+							position154, tokenIndex154 := position, tokenIndex
+							if !_rules[ruleDigits]() {
+								goto l154
+							}
+							{
+								position160, tokenIndex160 := position, tokenIndex
+								if buffer[position] != rune('.') {
+									goto l160
+								}
+								position++
 								if !_rules[ruleDigits]() {
-									goto l103
+									goto l160
 								}
-								goto l104
-							l103:
-								position, tokenIndex = position103, tokenIndex103
+								goto l161
+							l160:
+								position, tokenIndex = position160, tokenIndex160
 							}
-						l104:
+						l161:
 							{
-								position105, tokenIndex105 := position, tokenIndex
-								if !_rules[ruleExponent]() {
-									goto l105
+								position162, tokenIndex162 := position, tokenIndex
+								if buffer[position] != rune('m') {
+									goto l163
 								}
-								goto l106
-							l105:
-								position, tokenIndex = position105, tokenIndex105
+								position++
+								if buffer[position] != rune('s') {
+									goto l163
+								}
+								position++
+								goto l162
+							l163:
+								position, tokenIndex = position162, tokenIndex162
+								{
+									switch buffer[position] {
+									case 'h':
+										if buffer[position] != rune('h') {
+											goto l154
+										}
+										position++
+									case 'm':
+										if buffer[position] != rune('m') {
+											goto l154
+										}
+										position++
+									case 's':
+										if buffer[position] != rune('s') {
+											goto l154
+										}
+										position++
+									case 'u':
+										if buffer[position] != rune('u') {
+											goto l154
+										}
+										position++
+										if buffer[position] != rune('s') {
+											goto l154
+										}
+										position++
+									default:
+										if buffer[position] != rune('n') {
+											goto l154
+										}
+										position++
+										if buffer[position] != rune('s') {
+											goto l154
+										}
+										position++
+									}
+								}
+
 							}
-						l106:
-							goto l101
-						l102:
-							position, tokenIndex = position101, tokenIndex101
+						l162:
+							goto l153
+						l154:
+							position, tokenIndex = position154, tokenIndex154
+						}
+						{
+							position165, tokenIndex165 := position, tokenIndex
+							if !_rules[ruleLetterOrDigit]() {
+								goto l165
+							}
+							goto l149
+						l165:
+							position, tokenIndex = position165, tokenIndex165
+						}
+						add(ruleDurationLiteral, position150)
+					}
+					goto l131
+				l149:
+					position, tokenIndex = position131, tokenIndex131
+					{
+						position167 := position
+						{
+							position168, tokenIndex168 := position, tokenIndex
+							{
+								position170, tokenIndex170 := position, tokenIndex
+								if buffer[position] != rune('+') {
+									goto l171
+								}
+								position++
+								goto l170
+							l171:
+								position, tokenIndex = position170, tokenIndex170
+								if buffer[position] != rune('-') {
+									goto l168
+								}
+								position++
+							}
+						l170:
+							goto l169
+						l168:
+							position, tokenIndex = position168, tokenIndex168
+						}
+					l169:
+						{
+							position172, tokenIndex172 := position, tokenIndex
 							if !_rules[ruleDigits]() {
-								goto l107
+								goto l173
+							}
+							if buffer[position] != rune('.') {
+								goto l173
+							}
+							position++
+							{
+								position174, tokenIndex174 := position, tokenIndex
+								if !_rules[ruleDigits]() {
+									goto l174
+								}
+								goto l175
+							l174:
+								position, tokenIndex = position174, tokenIndex174
+							}
+						l175:
+							{
+								position176, tokenIndex176 := position, tokenIndex
+								if !_rules[ruleExponent]() {
+									goto l176
+								}
+								goto l177
+							l176:
+								position, tokenIndex = position176, tokenIndex176
+							}
+						l177:
+							goto l172
+						l173:
+							position, tokenIndex = position172, tokenIndex172
+							if !_rules[ruleDigits]() {
+								goto l178
 							}
 							if !_rules[ruleExponent]() {
-								goto l107
+								goto l178
 							}
-							goto l101
-						l107:
-							position, tokenIndex = position101, tokenIndex101
+							goto l172
+						l178:
+							position, tokenIndex = position172, tokenIndex172
 							if buffer[position] != rune('.') {
-								goto l95
+								goto l166
 							}
 							position++
 							if !_rules[ruleDigits]() {
-								goto l95
+								goto l166
 							}
 							{
-								position108, tokenIndex108 := position, tokenIndex
+								position179, tokenIndex179 := position, tokenIndex
 								if !_rules[ruleExponent]() {
-									goto l108
+									goto l179
 								}
-								goto l109
-							l108:
-								position, tokenIndex = position108, tokenIndex108
+								goto l180
+							l179:
+								position, tokenIndex = position179, tokenIndex179
 							}
-						l109:
+						l180:
 						}
-					l101:
-						add(ruleFloatLiteral, position96)
+					l172:
+						add(ruleFloatLiteral, position167)
 					}
-					goto l94
-				l95:
-					position, tokenIndex = position94, tokenIndex94
+					goto l131
+				l166:
+					position, tokenIndex = position131, tokenIndex131
 					{
 						switch buffer[position] {
 						case '#':
 							{
-								position111 := position
+								position182 := position
 								{
-									position112, tokenIndex112 := position, tokenIndex
+									position183, tokenIndex183 := position, tokenIndex
 									if buffer[position] != rune('#') {
-										goto l113
+										goto l184
 									}
 									position++
 									if buffer[position] != rune('f') {
-										goto l113
+										goto l184
 									}
 									position++
-									goto l112
-								l113:
-									position, tokenIndex = position112, tokenIndex112
+									goto l183
+								l184:
+									position, tokenIndex = position183, tokenIndex183
 									if buffer[position] != rune('#') {
-										goto l92
+										goto l129
 									}
 									position++
 									if buffer[position] != rune('t') {
-										goto l92
+										goto l129
 									}
 									position++
 								}
-							l112:
+							l183:
 								{
-									position114, tokenIndex114 := position, tokenIndex
+									position185, tokenIndex185 := position, tokenIndex
 									if !_rules[ruleLetterOrDigit]() {
-										goto l114
+										goto l185
 									}
-									goto l92
-								l114:
-									position, tokenIndex = position114, tokenIndex114
+									goto l129
+								l185:
+									position, tokenIndex = position185, tokenIndex185
 								}
-								add(ruleBoolLiteral, position111)
+								add(ruleBoolLiteral, position182)
 							}
 						case '"':
 							{
-								position115 := position
+								position186 := position
 								if buffer[position] != rune('"') {
-									goto l92
+									goto l129
 								}
 								position++
-							l116:
+							l187:
 								{
-									position117, tokenIndex117 := position, tokenIndex
+									position188, tokenIndex188 := position, tokenIndex
 									{
-										position118 := position
+										position189 := position
 										{
-											position119, tokenIndex119 := position, tokenIndex
+											position190, tokenIndex190 := position, tokenIndex
 											{
-												position121 := position
+												position192 := position
 												if buffer[position] != rune('\\') {
-													goto l120
+													goto l191
 												}
 												position++
 												{
 													switch buffer[position] {
 													case '\\':
 														if buffer[position] != rune('\\') {
-															goto l120
+															goto l191
 														}
 														position++
 													case '\'':
 														if buffer[position] != rune('\'') {
-															goto l120
+															goto l191
 														}
 														position++
 													case '"':
 														if buffer[position] != rune('"') {
-															goto l120
+															goto l191
 														}
 														position++
 													case 'r':
 														if buffer[position] != rune('r') {
-															goto l120
+															goto l191
 														}
 														position++
 													case 'f':
 														if buffer[position] != rune('f') {
-															goto l120
+															goto l191
 														}
 														position++
 													case 'n':
 														if buffer[position] != rune('n') {
-															goto l120
+															goto l191
 														}
 														position++
 													case 't':
 														if buffer[position] != rune('t') {
-															goto l120
+															goto l191
 														}
 														position++
 													default:
 														if buffer[position] != rune('b') {
-															goto l120
+															goto l191
 														}
 														position++
 													}
 												}
 
-												add(ruleEscape, position121)
+												add(ruleEscape, position192)
 											}
-											goto l119
-										l120:
-											position, tokenIndex = position119, tokenIndex119
+											goto l190
+										l191:
+											position, tokenIndex = position190, tokenIndex190
 											{
-												position123, tokenIndex123 := position, tokenIndex
+												position194, tokenIndex194 := position, tokenIndex
 												{
 													switch buffer[position] {
 													case '\\':
 														if buffer[position] != rune('\\') {
-															goto l123
+															goto l194
 														}
 														position++
 													case '\n':
 														if buffer[position] != rune('\n') {
-															goto l123
+															goto l194
 														}
 														position++
 													default:
 														if buffer[position] != rune('"') {
-															goto l123
+															goto l194
 														}
 														position++
 													}
 												}
 
-												goto l117
-											l123:
-												position, tokenIndex = position123, tokenIndex123
+												goto l188
+											l194:
+												position, tokenIndex = position194, tokenIndex194
 											}
 											if !matchDot() {
-												goto l117
+												goto l188
 											}
 										}
-									l119:
-										add(ruleStringChar, position118)
+									l190:
+										add(ruleStringChar, position189)
 									}
-									goto l116
-								l117:
-									position, tokenIndex = position117, tokenIndex117
+									goto l187
+								l188:
+									position, tokenIndex = position188, tokenIndex188
 								}
 								if buffer[position] != rune('"') {
-									goto l92
+									goto l129
 								}
 								position++
-								add(ruleStringLiteral, position115)
+								add(ruleStringLiteral, position186)
 							}
 						default:
 							{
-								position125 := position
+								position196 := position
 								{
-									position126, tokenIndex126 := position, tokenIndex
+									position197, tokenIndex197 := position, tokenIndex
 									if buffer[position] != rune('-') {
-										goto l126
+										goto l197
 									}
 									position++
-									goto l127
-								l126:
-									position, tokenIndex = position126, tokenIndex126
+									goto l198
+								l197:
+									position, tokenIndex = position197, tokenIndex197
 								}
-							l127:
+							l198:
 								{
-									position128 := position
+									position199 := position
 									{
-										position129, tokenIndex129 := position, tokenIndex
+										position200, tokenIndex200 := position, tokenIndex
 										if buffer[position] != rune('0') {
-											goto l130
+											goto l201
 										}
 										position++
-										goto l129
-									l130:
-										position, tokenIndex = position129, tokenIndex129
+										goto l200
+									l201:
+										position, tokenIndex = position200, tokenIndex200
 										if c := buffer[position]; c < rune('1') || c > rune('9') {
-											goto l92
+											goto l129
 										}
 										position++
-									l131:
+									l202:
 										{
-											position132, tokenIndex132 := position, tokenIndex
-										l133:
+											position203, tokenIndex203 := position, tokenIndex
+										l204:
 											{
-												position134, tokenIndex134 := position, tokenIndex
+												position205, tokenIndex205 := position, tokenIndex
 												if buffer[position] != rune('_') {
-													goto l134
+													goto l205
 												}
 												position++
-												goto l133
-											l134:
-												position, tokenIndex = position134, tokenIndex134
+												goto l204
+											l205:
+												position, tokenIndex = position205, tokenIndex205
 											}
 											if c := buffer[position]; c < rune('0') || c > rune('9') {
-												goto l132
+												goto l203
 											}
 											position++
-											goto l131
-										l132:
-											position, tokenIndex = position132, tokenIndex132
+											goto l202
+										l203:
+											position, tokenIndex = position203, tokenIndex203
 										}
 									}
-								l129:
-									add(ruleDecimalNumeral, position128)
+								l200:
+									add(ruleDecimalNumeral, position199)
 								}
 								{
-									position135, tokenIndex135 := position, tokenIndex
+									position206, tokenIndex206 := position, tokenIndex
 									{
 										switch buffer[position] {
 										case 'F':
 											if buffer[position] != rune('F') {
-												goto l135
+												goto l206
 											}
 											position++
 										case 'f':
 											if buffer[position] != rune('f') {
-												goto l135
+												goto l206
 											}
 											position++
 										case 'U':
 											if buffer[position] != rune('U') {
-												goto l135
+												goto l206
 											}
 											position++
 										default:
 											if buffer[position] != rune('u') {
-												goto l135
+												goto l206
 											}
 											position++
 										}
 									}
 
-									goto l136
-								l135:
-									position, tokenIndex = position135, tokenIndex135
+									goto l207
+								l206:
+									position, tokenIndex = position206, tokenIndex206
 								}
-							l136:
-								add(ruleIntegerLiteral, position125)
+							l207:
+								add(ruleIntegerLiteral, position196)
 							}
 						}
 					}
 
 				}
-			l94:
-				add(ruleLiteral, position93)
+			l131:
+				add(ruleLiteral, position130)
 			}
 			return true
-		l92:
-			position, tokenIndex = position92, tokenIndex92
+		l129:
+			position, tokenIndex = position129, tokenIndex129
 			return false
 		},
-		/* 11 BoolLiteral <- <((('#' 'f') / ('#' 't')) !LetterOrDigit)> */
+		/* 15 DurationLiteral <- <('-'? (Digits ('.' Digits)? (('m' 's') / ((&('h') 'h') | (&('m') 'm') | (&('s') 's') | (&('u') ('u' 's')) | (&('n') ('n' 's')))))+ !LetterOrDigit)> */
 		nil,
-		/* 12 FloatLiteral <- <(('+' / '-')? ((Digits '.' Digits? Exponent?) / (Digits Exponent) / ('.' Digits Exponent?)))> */
+		/* 16 DecimalLiteral <- <(('+' / '-')? ((Digits ('.' Digits?)? Exponent?) / ('.' Digits Exponent?)) 'M' !LetterOrDigit)> */
 		nil,
-		/* 13 Exponent <- <(('e' / 'E') ('+' / '-')? Digits)> */
+		/* 17 BoolLiteral <- <((('#' 'f') / ('#' 't')) !LetterOrDigit)> */
+		nil,
+		/* 18 FloatLiteral <- <(('+' / '-')? ((Digits '.' Digits? Exponent?) / (Digits Exponent) / ('.' Digits Exponent?)))> */
+		nil,
+		/* 19 Exponent <- <(('e' / 'E') ('+' / '-')? Digits)> */
 		func() bool {
-			position140, tokenIndex140 := position, tokenIndex
+			position213, tokenIndex213 := position, tokenIndex
 			{
-				position141 := position
+				position214 := position
 				{
-					position142, tokenIndex142 := position, tokenIndex
+					position215, tokenIndex215 := position, tokenIndex
 					if buffer[position] != rune('e') {
-						goto l143
+						goto l216
 					}
 					position++
-					goto l142
-				l143:
-					position, tokenIndex = position142, tokenIndex142
+					goto l215
+				l216:
+					position, tokenIndex = position215, tokenIndex215
 					if buffer[position] != rune('E') {
-						goto l140
+						goto l213
 					}
 					position++
 				}
-			l142:
+			l215:
 				{
-					position144, tokenIndex144 := position, tokenIndex
+					position217, tokenIndex217 := position, tokenIndex
 					{
-						position146, tokenIndex146 := position, tokenIndex
+						position219, tokenIndex219 := position, tokenIndex
 						if buffer[position] != rune('+') {
-							goto l147
+							goto l220
 						}
 						position++
-						goto l146
-					l147:
-						position, tokenIndex = position146, tokenIndex146
+						goto l219
+					l220:
+						position, tokenIndex = position219, tokenIndex219
 						if buffer[position] != rune('-') {
-							goto l144
+							goto l217
 						}
 						position++
 					}
-				l146:
-					goto l145
-				l144:
-					position, tokenIndex = position144, tokenIndex144
+				l219:
+					goto l218
+				l217:
+					position, tokenIndex = position217, tokenIndex217
 				}
-			l145:
+			l218:
 				if !_rules[ruleDigits]() {
-					goto l140
+					goto l213
 				}
-				add(ruleExponent, position141)
+				add(ruleExponent, position214)
 			}
 			return true
-		l140:
-			position, tokenIndex = position140, tokenIndex140
+		l213:
+			position, tokenIndex = position213, tokenIndex213
 			return false
 		},
-		/* 14 IntegerLiteral <- <('-'? DecimalNumeral ((&('F') 'F') | (&('f') 'f') | (&('U') 'U') | (&('u') 'u'))?)> */
+		/* 20 IntegerLiteral <- <('-'? DecimalNumeral ((&('F') 'F') | (&('f') 'f') | (&('U') 'U') | (&('u') 'u'))?)> */
 		nil,
-		/* 15 DecimalNumeral <- <('0' / ([1-9] ('_'* [0-9])*))> */
+		/* 21 DecimalNumeral <- <('0' / ([1-9] ('_'* [0-9])*))> */
 		nil,
-		/* 16 StringLiteral <- <('"' StringChar* '"')> */
+		/* 22 StringLiteral <- <('"' StringChar* '"')> */
 		nil,
-		/* 17 StringChar <- <(Escape / (!((&('\\') '\\') | (&('\n') '\n') | (&('"') '"')) .))> */
+		/* 23 StringChar <- <(Escape / (!((&('\\') '\\') | (&('\n') '\n') | (&('"') '"')) .))> */
 		nil,
-		/* 18 LetterOrDigit <- <((&('_') '_') | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]) | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]))> */
+		/* 24 LetterOrDigit <- <((&('_') '_') | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]) | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]))> */
 		func() bool {
-			position152, tokenIndex152 := position, tokenIndex
+			position225, tokenIndex225 := position, tokenIndex
 			{
-				position153 := position
+				position226 := position
 				{
 					switch buffer[position] {
 					case '_':
 						if buffer[position] != rune('_') {
-							goto l152
+							goto l225
 						}
 						position++
 					case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 						if c := buffer[position]; c < rune('0') || c > rune('9') {
-							goto l152
+							goto l225
 						}
 						position++
 					case 'A', 'B', 'C', 'D', 'E', 'F', 'G', 'H', 'I', 'J', 'K', 'L', 'M', 'N', 'O', 'P', 'Q', 'R', 'S', 'T', 'U', 'V', 'W', 'X', 'Y', 'Z':
 						if c := buffer[position]; c < rune('A') || c > rune('Z') {
-							goto l152
+							goto l225
 						}
 						position++
 					default:
 						if c := buffer[position]; c < rune('a') || c > rune('z') {
-							goto l152
+							goto l225
 						}
 						position++
 					}
 				}
 
-				add(ruleLetterOrDigit, position153)
+				add(ruleLetterOrDigit, position226)
 			}
 			return true
-		l152:
-			position, tokenIndex = position152, tokenIndex152
+		l225:
+			position, tokenIndex = position225, tokenIndex225
 			return false
 		},
-		/* 19 Letter <- <((&('_') '_') | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]) | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]))> */
-		nil,
-		/* 20 Digits <- <([0-9] ('_'* [0-9])*)> */
+		/* 25 Letter <- <((&('_') '_') | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]) | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]))> */
+		func() bool {
+			position228, tokenIndex228 := position, tokenIndex
+			{
+				position229 := position
+				{
+					switch buffer[position] {
+					case '_':
+						if buffer[position] != rune('_') {
+							goto l228
+						}
+						position++
+					case 'A', 'B', 'C', 'D', 'E', 'F', 'G', 'H', 'I', 'J', 'K', 'L', 'M', 'N', 'O', 'P', 'Q', 'R', 'S', 'T', 'U', 'V', 'W', 'X', 'Y', 'Z':
+						if c := buffer[position]; c < rune('A') || c > rune('Z') {
+							goto l228
+						}
+						position++
+					default:
+						if c := buffer[position]; c < rune('a') || c > rune('z') {
+							goto l228
+						}
+						position++
+					}
+				}
+
+				add(ruleLetter, position229)
+			}
+			return true
+		l228:
+			position, tokenIndex = position228, tokenIndex228
+			return false
+		},
+		/* 26 Digits <- <([0-9] ('_'* [0-9])*)> */
 		func() bool {
-			position156, tokenIndex156 := position, tokenIndex
+			position231, tokenIndex231 := position, tokenIndex
 			{
-				position157 := position
+				position232 := position
 				if c := buffer[position]; c < rune('0') || c > rune('9') {
-					goto l156
+					goto l231
 				}
 				position++
-			l158:
+			l233:
 				{
-					position159, tokenIndex159 := position, tokenIndex
-				l160:
+					position234, tokenIndex234 := position, tokenIndex
+				l235:
 					{
-						position161, tokenIndex161 := position, tokenIndex
+						position236, tokenIndex236 := position, tokenIndex
 						if buffer[position] != rune('_') {
-							goto l161
+							goto l236
 						}
 						position++
-						goto l160
-					l161:
-						position, tokenIndex = position161, tokenIndex161
+						goto l235
+					l236:
+						position, tokenIndex = position236, tokenIndex236
 					}
 					if c := buffer[position]; c < rune('0') || c > rune('9') {
-						goto l159
+						goto l234
 					}
 					position++
-					goto l158
-				l159:
-					position, tokenIndex = position159, tokenIndex159
+					goto l233
+				l234:
+					position, tokenIndex = position234, tokenIndex234
 				}
-				add(ruleDigits, position157)
+				add(ruleDigits, position232)
 			}
 			return true
-		l156:
-			position, tokenIndex = position156, tokenIndex156
+		l231:
+			position, tokenIndex = position231, tokenIndex231
 			return false
 		},
-		/* 21 Escape <- <('\\' ((&('\\') '\\') | (&('\'') '\'') | (&('"') '"') | (&('r') 'r') | (&('f') 'f') | (&('n') 'n') | (&('t') 't') | (&('b') 'b')))> */
+		/* 27 Escape <- <('\\' ((&('\\') '\\') | (&('\'') '\'') | (&('"') '"') | (&('r') 'r') | (&('f') 'f') | (&('n') 'n') | (&('t') 't') | (&('b') 'b')))> */
 		nil,
-		/* 22 Keyword <- <((('d' 'e' 'f' 'i' 'n' 'e' '-' 'p' 'r' 'd' 't') / ('d' 'e' 'f' 'i' 'n' 'e' '-' 'l' 'h' 's') / ('d' 'e' 'f' 'i' 'n' 'e' '-' 'r' 'h' 's') / ('r' 'u' 'l' 'e') / ('d' 'e' 'f' 'i' 'n' 'e')) !LetterOrDigit)> */
+		/* 28 Keyword <- <((('d' 'e' 'f' 'i' 'n' 'e' '-' 'p' 'r' 'd' 't') / ('d' 'e' 'f' 'i' 'n' 'e' '-' 'l' 'h' 's') / ('d' 'e' 'f' 'i' 'n' 'e' '-' 'r' 'h' 's') / ('r' 'u' 'l' 'e') / ('d' 'e' 'f' 'i' 'n' 'e')) !(LetterOrDigit / '-'))> */
 		nil,
-		/* 23 DefType <- <((('d' 'e' 'f' 'i' 'n' 'e' '-' 'p' 'r' 'd' 't') / ('d' 'e' 'f' 'i' 'n' 'e' '-' 'l' 'h' 's') / ('d' 'e' 'f' 'i' 'n' 'e' '-' 'r' 'h' 's') / ('r' 'u' 'l' 'e') / ('d' 'e' 'f' 'i' 'n' 'e')) !LetterOrDigit Spacing)> */
+		/* 29 DefType <- <((('d' 'e' 'f' 'i' 'n' 'e' '-' 'p' 'r' 'd' 't') / ('d' 'e' 'f' 'i' 'n' 'e' '-' 'l' 'h' 's') / ('d' 'e' 'f' 'i' 'n' 'e' '-' 'r' 'h' 's') / ('r' 'u' 'l' 'e') / ('d' 'e' 'f' 'i' 'n' 'e')) !LetterOrDigit Spacing)> */
 		func() bool {
-			position164, tokenIndex164 := position, tokenIndex
+			position239, tokenIndex239 := position, tokenIndex
 			{
-				position165 := position
+				position240 := position
 				{
-					position166, tokenIndex166 := position, tokenIndex
+					position241, tokenIndex241 := position, tokenIndex
 					if buffer[position] != rune('d') {
-						goto l167
+						goto l242
 					}
 					position++
 					if buffer[position] != rune('e') {
-						goto l167
+						goto l242
 					}
 					position++
 					if buffer[position] != rune('f') {
-						goto l167
+						goto l242
 					}
 					position++
 					if buffer[position] != rune('i') {
-						goto l167
+						goto l242
 					}
 					position++
 					if buffer[position] != rune('n') {
-						goto l167
+						goto l242
 					}
 					position++
 					if buffer[position] != rune('e') {
-						goto l167
+						goto l242
 					}
 					position++
 					if buffer[position] != rune('-') {
-						goto l167
+						goto l242
 					}
 					position++
 					if buffer[position] != rune('p') {
-						goto l167
+						goto l242
 					}
 					position++
 					if buffer[position] != rune('r') {
-						goto l167
+						goto l242
 					}
 					position++
 					if buffer[position] != rune('d') {
-						goto l167
+						goto l242
 					}
 					position++
 					if buffer[position] != rune('t') {
-						goto l167
+						goto l242
 					}
 					position++
-					goto l166
-				l167:
-					position, tokenIndex = position166, tokenIndex166
+					goto l241
+				l242:
+					position, tokenIndex = position241, tokenIndex241
 					if buffer[position] != rune('d') {
-						goto l168
+						goto l243
 					}
 					position++
 					if buffer[position] != rune('e') {
-						goto l168
+						goto l243
 					}
 					position++
 					if buffer[position] != rune('f') {
-						goto l168
+						goto l243
 					}
 					position++
 					if buffer[position] != rune('i') {
-						goto l168
+						goto l243
 					}
 					position++
 					if buffer[position] != rune('n') {
-						goto l168
+						goto l243
 					}
 					position++
 					if buffer[position] != rune('e') {
-						goto l168
+						goto l243
 					}
 					position++
 					if buffer[position] != rune('-') {
-						goto l168
+						goto l243
 					}
 					position++
 					if buffer[position] != rune('l') {
-						goto l168
+						goto l243
 					}
 					position++
 					if buffer[position] != rune('h') {
-						goto l168
+						goto l243
 					}
 					position++
 					if buffer[position] != rune('s') {
-						goto l168
+						goto l243
 					}
 					position++
-					goto l166
-				l168:
-					position, tokenIndex = position166, tokenIndex166
+					goto l241
+				l243:
+					position, tokenIndex = position241, tokenIndex241
 					if buffer[position] != rune('d') {
-						goto l169
+						goto l244
 					}
 					position++
 					if buffer[position] != rune('e') {
-						goto l169
+						goto l244
 					}
 					position++
 					if buffer[position] != rune('f') {
-						goto l169
+						goto l244
 					}
 					position++
 					if buffer[position] != rune('i') {
-						goto l169
+						goto l244
 					}
 					position++
 					if buffer[position] != rune('n') {
-						goto l169
+						goto l244
 					}
 					position++
 					if buffer[position] != rune('e') {
-						goto l169
+						goto l244
 					}
 					position++
 					if buffer[position] != rune('-') {
-						goto l169
+						goto l244
 					}
 					position++
 					if buffer[position] != rune('r') {
-						goto l169
+						goto l244
 					}
 					position++
 					if buffer[position] != rune('h') {
-						goto l169
+						goto l244
 					}
 					position++
 					if buffer[position] != rune('s') {
-						goto l169
+						goto l244
 					}
 					position++
-					goto l166
-				l169:
-					position, tokenIndex = position166, tokenIndex166
+					goto l241
+				l244:
+					position, tokenIndex = position241, tokenIndex241
 					if buffer[position] != rune('r') {
-						goto l170
+						goto l245
 					}
 					position++
 					if buffer[position] != rune('u') {
-						goto l170
+						goto l245
 					}
 					position++
 					if buffer[position] != rune('l') {
-						goto l170
+						goto l245
 					}
 					position++
 					if buffer[position] != rune('e') {
-						goto l170
+						goto l245
 					}
 					position++
-					goto l166
-				l170:
-					position, tokenIndex = position166, tokenIndex166
+					goto l241
+				l245:
+					position, tokenIndex = position241, tokenIndex241
 					if buffer[position] != rune('d') {
-						goto l164
+						goto l239
 					}
 					position++
 					if buffer[position] != rune('e') {
-						goto l164
+						goto l239
 					}
 					position++
 					if buffer[position] != rune('f') {
-						goto l164
+						goto l239
 					}
 					position++
 					if buffer[position] != rune('i') {
-						goto l164
+						goto l239
 					}
 					position++
 					if buffer[position] != rune('n') {
-						goto l164
+						goto l239
 					}
 					position++
 					if buffer[position] != rune('e') {
-						goto l164
+						goto l239
 					}
 					position++
 				}
-			l166:
+			l241:
 				{
-					position171, tokenIndex171 := position, tokenIndex
+					position246, tokenIndex246 := position, tokenIndex
 					if !_rules[ruleLetterOrDigit]() {
-						goto l171
+						goto l246
 					}
-					goto l164
-				l171:
-					position, tokenIndex = position171, tokenIndex171
+					goto l239
+				l246:
+					position, tokenIndex = position246, tokenIndex246
 				}
 				if !_rules[ruleSpacing]() {
-					goto l164
+					goto l239
 				}
-				add(ruleDefType, position165)
+				add(ruleDefType, position240)
 			}
 			return true
-		l164:
-			position, tokenIndex = position164, tokenIndex164
+		l239:
+			position, tokenIndex = position239, tokenIndex239
 			return false
 		},
-		/* 24 LPAR <- <(Spacing '(' Spacing)> */
+		/* 30 LPAR <- <(Spacing '(' Spacing)> */
 		func() bool {
-			position172, tokenIndex172 := position, tokenIndex
+			position247, tokenIndex247 := position, tokenIndex
 			{
-				position173 := position
+				position248 := position
 				if !_rules[ruleSpacing]() {
-					goto l172
+					goto l247
 				}
 				if buffer[position] != rune('(') {
-					goto l172
+					goto l247
 				}
 				position++
 				if !_rules[ruleSpacing]() {
-					goto l172
+					goto l247
 				}
-				add(ruleLPAR, position173)
+				add(ruleLPAR, position248)
 			}
 			return true
-		l172:
-			position, tokenIndex = position172, tokenIndex172
+		l247:
+			position, tokenIndex = position247, tokenIndex247
 			return false
 		},
-		/* 25 RPAR <- <(Spacing ')' Spacing)> */
+		/* 31 RPAR <- <(Spacing ')' Spacing)> */
 		func() bool {
-			position174, tokenIndex174 := position, tokenIndex
+			position249, tokenIndex249 := position, tokenIndex
 			{
-				position175 := position
+				position250 := position
 				if !_rules[ruleSpacing]() {
-					goto l174
+					goto l249
 				}
 				if buffer[position] != rune(')') {
-					goto l174
+					goto l249
 				}
 				position++
 				if !_rules[ruleSpacing]() {
-					goto l174
+					goto l249
 				}
-				add(ruleRPAR, position175)
+				add(ruleRPAR, position250)
 			}
 			return true
-		l174:
-			position, tokenIndex = position174, tokenIndex174
+		l249:
+			position, tokenIndex = position249, tokenIndex249
 			return false
 		},
-		/* 26 LBRK <- <(Spacing '[' Spacing)> */
+		/* 32 LBRK <- <(Spacing '[' Spacing)> */
 		func() bool {
-			position176, tokenIndex176 := position, tokenIndex
+			position251, tokenIndex251 := position, tokenIndex
 			{
-				position177 := position
+				position252 := position
 				if !_rules[ruleSpacing]() {
-					goto l176
+					goto l251
 				}
 				if buffer[position] != rune('[') {
-					goto l176
+					goto l251
 				}
 				position++
 				if !_rules[ruleSpacing]() {
-					goto l176
+					goto l251
 				}
-				add(ruleLBRK, position177)
+				add(ruleLBRK, position252)
 			}
 			return true
-		l176:
-			position, tokenIndex = position176, tokenIndex176
+		l251:
+			position, tokenIndex = position251, tokenIndex251
 			return false
 		},
-		/* 27 RBRK <- <(Spacing ']' Spacing)> */
+		/* 33 RBRK <- <(Spacing ']' Spacing)> */
 		func() bool {
-			position178, tokenIndex178 := position, tokenIndex
+			position253, tokenIndex253 := position, tokenIndex
 			{
-				position179 := position
+				position254 := position
 				if !_rules[ruleSpacing]() {
-					goto l178
+					goto l253
 				}
 				if buffer[position] != rune(']') {
-					goto l178
+					goto l253
 				}
 				position++
 				if !_rules[ruleSpacing]() {
-					goto l178
+					goto l253
 				}
-				add(ruleRBRK, position179)
+				add(ruleRBRK, position254)
 			}
 			return true
-		l178:
-			position, tokenIndex = position178, tokenIndex178
+		l253:
+			position, tokenIndex = position253, tokenIndex253
 			return false
 		},
-		/* 28 EOT <- <!.> */
+		/* 34 EOT <- <!.> */
 		nil,
 	}
 	p.rules = _rules
@@ -2,8 +2,10 @@ package dsl
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -16,16 +18,29 @@ type ParseContext struct {
 type (
 	OptionList map[string]any
 
+	// Identifier is a bare name in the script, which is different from a string literal
+	Identifier string
+
+	// Variable is a name prefixed by '$' in the script, like $x, the '$' is not included
+	Variable string
+
+	// LiteralList is a list of literals in the script, like ("red" "blue")
+	LiteralList []any
+
 	Definition struct {
 		DefType string
 		ID      string
 		Options OptionList
 		Body    []any
+
+		node *node32 // where the definition is found in the script
 	}
 
 	Expression struct {
 		Op      string
 		Operand []any
+
+		node *node32 // where the expression is found in the script
 	}
 )
 
@@ -33,20 +48,27 @@ var parserTab map[pegRule]func(*ParseContext, *node32) (any, error)
 
 func init() {
 	parserTab = map[pegRule]func(*ParseContext, *node32) (any, error){
-		ruleDefinitions:    parseDefinitions,
-		ruleDefinition:     parseDefinition,
-		ruleDefinitionBody: parseDefinitionBody,
-		ruleOptionList:     parseOptionList,
-		ruleExpression:     parseExpression,
-		ruleIdentifier:     parseIdentifier,
-		ruleOperator:       parseChild,
-		ruleLiteral:        parseChild,
-		ruleBoolLiteral:    parseBoolLiteral,
-		ruleFloatLiteral:   parseFloatLiteral,
-		ruleIntegerLiteral: parseIntegerLiteral,
-		ruleStringLiteral:  parseStringLiteral,
-		ruleKeyword:        parseNodeText,
-		ruleDefType:        parseNodeText,
+		ruleDefinitions:     parseDefinitions,
+		ruleDefinition:      parseDefinition,
+		ruleDefinitionBody:  parseDefinitionBody,
+		ruleOptionList:      parseOptionList,
+		ruleExpression:      parseExpression,
+		ruleIdentifier:      parseIdentifier,
+		ruleOperator:        parseChild,
+		ruleOperand:         parseChild,
+		ruleExpressionList:  parseExpressionList,
+		ruleLiteralList:     parseLiteralList,
+		ruleVariable:        parseVariable,
+		ruleSymbolOperator:  parseNodeText,
+		ruleLiteral:         parseChild,
+		ruleDurationLiteral: parseDurationLiteral,
+		ruleDecimalLiteral:  parseDecimalLiteral,
+		ruleBoolLiteral:     parseBoolLiteral,
+		ruleFloatLiteral:    parseFloatLiteral,
+		ruleIntegerLiteral:  parseIntegerLiteral,
+		ruleStringLiteral:   parseStringLiteral,
+		ruleKeyword:         parseNodeText,
+		ruleDefType:         parseNodeText,
 	}
 }
 
@@ -91,8 +113,7 @@ func (c *ParseContext) parseNode(node *node32) (any, error) {
 func parseDefinitions(c *ParseContext, node *node32) (any, error) {
 	defs := make([]*Definition, 0, 2)
 	i := 0
-	node = node.up
-	for cur := node; cur != nil; cur = node.next {
+	for cur := node.up; cur != nil; cur = cur.next {
 		v, err := c.parseNode(cur)
 		if err != nil {
 			return nil, err
@@ -134,10 +155,12 @@ func parseDefinition(c *ParseContext, node *node32) (any, error) {
 				return nil, errors.Errorf("fail to parse %s, expecting a string but got %v", rule, v)
 			}
 		case ruleIdentifier:
-			id, ok = v.(string)
+			var ident Identifier
+			ident, ok = v.(Identifier)
 			if !ok {
 				return nil, errors.Errorf("fail to parse %s, expecting a string but got %v", rule, v)
 			}
+			id = string(ident)
 			if _, in := c.env[id]; in {
 				return nil, SyntaxErrorf(c, cur, "re-define identifier %q is not allowed", id)
 			}
@@ -162,8 +185,10 @@ func parseDefinition(c *ParseContext, node *node32) (any, error) {
 		DefType: defType,
 		Options: options,
 		Body:    defBody,
+		node:    node,
 	}
-	// TODO: convert def into specify value
+	// definitions are kept as they are parsed, `define-prdt` and `rule` ones are converted into Rules by Compile,
+	// and env only tells re-definitions for now
 	c.env[def.ID] = def
 
 	return def, nil
@@ -202,10 +227,19 @@ func parseExpression(c *ParseContext, node *node32) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	operator = v.(string)
+	switch op := v.(type) {
+	case Identifier:
+		operator = string(op)
+	case string:
+		operator = op
+	}
 
 	// parse operands
-	for cur = cur.up; cur != nil; cur = cur.next {
+	for cur = cur.next; cur != nil; cur = cur.next {
+		switch cur.pegRule {
+		case ruleRPAR, ruleRBRK:
+			continue
+		}
 		v, err := c.parseNode(cur)
 		if err != nil {
 			return nil, err
@@ -216,9 +250,44 @@ func parseExpression(c *ParseContext, node *node32) (any, error) {
 	return &Expression{
 		Op:      operator,
 		Operand: operands,
+		node:    node,
 	}, nil
 }
 
+func parseExpressionList(c *ParseContext, node *node32) (any, error) {
+	exprs := make([]any, 0, 2)
+	for cur := node.up; cur != nil; cur = cur.next {
+		switch cur.pegRule {
+		case ruleLPAR, ruleRPAR, ruleLBRK, ruleRBRK:
+			continue
+		}
+		v, err := c.parseNode(cur)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, v)
+	}
+
+	return exprs, nil
+}
+
+func parseLiteralList(c *ParseContext, node *node32) (any, error) {
+	list := make(LiteralList, 0, 2)
+	for cur := node.up; cur != nil; cur = cur.next {
+		switch cur.pegRule {
+		case ruleLPAR, ruleRPAR, ruleLBRK, ruleRBRK, ruleSpacing:
+			continue
+		}
+		v, err := c.parseNode(cur)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+
+	return list, nil
+}
+
 func parseOptionList(c *ParseContext, node *node32) (any, error) {
 	opts := make(OptionList, 2)
 	for node = node.up; node != nil; node = node.next {
@@ -242,7 +311,7 @@ func parseOption(c *ParseContext, node *node32) (string, any, error) {
 	if err != nil {
 		return "", nil, errors.Errorf("fail to parse option id(%q)", c.nodeText(node))
 	}
-	id = idVal.(string)
+	id = string(idVal.(Identifier))
 
 	val, err = c.parseNode(node.next)
 	if err != nil {
@@ -253,11 +322,23 @@ func parseOption(c *ParseContext, node *node32) (string, any, error) {
 }
 
 func parseIdentifier(c *ParseContext, node *node32) (any, error) {
-	id, err := c.readChars(node.up)
+	id, err := c.readName(node)
+	if err != nil {
+		return nil, err
+	}
+	return Identifier(id), nil
+}
+
+func parseVariable(c *ParseContext, node *node32) (any, error) {
+	name, err := c.readName(node)
 	if err != nil {
 		return nil, err
 	}
-	return id, nil
+	return Variable(name[1:]), nil
+}
+
+func parseDurationLiteral(c *ParseContext, node *node32) (any, error) {
+	return time.ParseDuration(c.nodeText(node))
 }
 
 func parseStringLiteral(c *ParseContext, node *node32) (any, error) {
@@ -272,6 +353,16 @@ func parseFloatLiteral(c *ParseContext, node *node32) (any, error) {
 	return strconv.ParseFloat(c.nodeText(node), 64)
 }
 
+// parseDecimalLiteral parse a decimal like 19.99M into *big.Rat
+func parseDecimalLiteral(c *ParseContext, node *node32) (any, error) {
+	text := c.nodeText(node)
+	r, ok := new(big.Rat).SetString(strings.ReplaceAll(text[:len(text)-1], "_", ""))
+	if !ok {
+		return nil, errors.Errorf("invalid decimal %s", text)
+	}
+	return r, nil
+}
+
 func parseBoolLiteral(c *ParseContext, node *node32) (any, error) {
 	s := c.nodeText(node)
 	if s == "#t" {
@@ -297,19 +388,20 @@ func (c *ParseContext) nodeText(n *node32) string {
 	return strings.TrimSpace(string(c.p.buffer[n.begin:n.end]))
 }
 
-func (c *ParseContext) readChars(node *node32) (string, error) {
-	buf := &strings.Builder{}
-	for node.next != nil {
-		switch node.pegRule {
+// readName read the name of an Identifier or a Variable without the spacing after it
+func (c *ParseContext) readName(node *node32) (string, error) {
+	end := node.begin
+	for cur := node.up; cur != nil; cur = cur.next {
+		switch cur.pegRule {
 		case ruleLetterOrDigit, ruleLetter:
-			buf.WriteString(c.nodeText(node))
+			end = cur.end
+		case ruleSpacing:
 		default:
-			return "", SyntaxErrorf(c, node, "expecting a digit or char")
+			return "", SyntaxErrorf(c, cur, "expecting a digit or char")
 		}
-		node = node.next
 	}
 
-	return buf.String(), nil
+	return string(c.p.buffer[node.begin:end]), nil
 }
 
 // parse whatever its child is
@@ -346,6 +438,15 @@ func (s *SyntaxError) Cause() error {
 }
 
 func SyntaxErrorf(c *ParseContext, node *node32, f string, args ...any) error {
+	return syntaxErrorAt(c, node, errors.Errorf(f, args...))
+}
+
+// syntaxErrorAt wrap cause into a SyntaxError at the position of node
+func syntaxErrorAt(c *ParseContext, node *node32, cause error) error {
+	if node == nil {
+		// position is unknown
+		return &SyntaxError{cause: cause}
+	}
 	pos := translatePositions(c.p.buffer, []int{int(node.begin), int(node.end)})
 	beg, end := pos[int(node.begin)], pos[int(node.end)]
 
@@ -354,6 +455,6 @@ func SyntaxErrorf(c *ParseContext, node *node32, f string, args ...any) error {
 		endLine:   end.line,
 		beginSym:  beg.symbol,
 		endSym:    end.symbol,
-		cause:     errors.Errorf(f, args...),
+		cause:     cause,
 	}
 }
@@ -0,0 +1,103 @@
+package dsl
+
+import (
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parser", func() {
+	parse := func(script string) []*Definition {
+		pc, err := MakeParseContext(script)
+		Expect(err).ShouldNot(HaveOccurred())
+		defs, err := pc.Run()
+		Expect(err).ShouldNot(HaveOccurred())
+		return defs
+	}
+
+	It("tells variables apart from identifiers", func() {
+		defs := parse(`(define foo [bar $x x "x"])`)
+		Expect(defs).Should(HaveLen(1))
+		Expect(defs[0].ID).Should(Equal("foo"))
+		Expect(defs[0].Body).Should(HaveLen(1))
+		expr := defs[0].Body[0].(*Expression)
+		Expect(expr.Op).Should(Equal("bar"))
+		Expect(expr.Operand).Should(Equal([]any{Variable("x"), Identifier("x"), "x"}))
+	})
+
+	It("accepts kebab-case identifiers and symbol operators", func() {
+		defs := parse(`(define foo ([not-eq (field-of $a "B") $c] [>= 1 2] [!= is-set #t]))`)
+		ops := make([]string, 0, 3)
+		for _, v := range defs[0].Body {
+			ops = append(ops, v.(*Expression).Op)
+		}
+		Expect(ops).Should(Equal([]string{"not-eq", ">=", "!="}))
+
+		inner := defs[0].Body[0].(*Expression).Operand[0].(*Expression)
+		Expect(inner.Op).Should(Equal("field-of"))
+		Expect(inner.Operand).Should(Equal([]any{Variable("a"), "B"}))
+		Expect(defs[0].Body[2].(*Expression).Operand).Should(Equal([]any{Identifier("is-set"), true}))
+	})
+
+	It("parses a list of expressions as an operand", func() {
+		defs := parse(`(define foo (when ([eq $x 1] [eq $y 2])))`)
+		expr := defs[0].Body[0].(*Expression)
+		Expect(expr.Operand).Should(HaveLen(1))
+		Expect(expr.Operand[0]).Should(HaveLen(2))
+	})
+
+	It("parses a list of literals as an operand", func() {
+		defs := parse(`(define foo ([in $x ("red" "blue" 1 2.5)] [not-in $y [#t]]))`)
+		Expect(defs[0].Body[0].(*Expression).Operand).Should(Equal([]any{
+			Variable("x"), LiteralList{"red", "blue", int64(1), 2.5},
+		}))
+		Expect(defs[0].Body[1].(*Expression).Operand).Should(Equal([]any{Variable("y"), LiteralList{true}}))
+	})
+
+	It("parses duration literals", func() {
+		defs := parse(`(define foo [f 5m 1h30m -1.5s 10 1.5 10u])`)
+		Expect(defs[0].Body[0].(*Expression).Operand).Should(Equal([]any{
+			5 * time.Minute, time.Hour + 30*time.Minute, -1500 * time.Millisecond, int64(10), 1.5, uint64(10),
+		}))
+	})
+
+	It("parses decimal literals", func() {
+		defs := parse(`(define foo [f 19.99M -1M 1_000.5M .5M 1e-2M 1.5 2m])`)
+		Expect(defs[0].Body[0].(*Expression).Operand).Should(Equal([]any{
+			big.NewRat(1999, 100), big.NewRat(-1, 1), big.NewRat(2001, 2), big.NewRat(1, 2), big.NewRat(1, 100),
+			1.5, 2 * time.Minute,
+		}))
+	})
+
+	It("can parse more than one definition", func() {
+		defs := parse(`(define foo 1) (define bar 2)`)
+		Expect(defs).Should(HaveLen(2))
+		Expect(defs[1].ID).Should(Equal("bar"))
+	})
+
+	It("keeps definitions as they are parsed", func() {
+		pc, err := MakeParseContext(`(define-prdt p ([when [eq (field-of $x "Rank") 1]])) (define foo 1)`)
+		Expect(err).ShouldNot(HaveOccurred())
+		defs, err := pc.Run()
+		Expect(err).ShouldNot(HaveOccurred())
+		// only `define-prdt` and `rule` definitions are converted, by Compile
+		Expect(pc.env).Should(Equal(map[string]any{"p": defs[0], "foo": defs[1]}))
+		Expect(defs[0].DefType).Should(Equal(DefTypePrdt))
+		Expect(defs[1].Body).Should(Equal([]any{int64(1)}))
+
+		pc, err = MakeParseContext(`(define foo 1) (define foo 2)`)
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = pc.Run()
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("does not take keywords as identifiers", func() {
+		_, err := MakeParseContext(`(define define-prdt 1)`)
+		Expect(err).Should(HaveOccurred())
+
+		defs := parse(`(define define-x 1)`)
+		Expect(defs[0].ID).Should(Equal("define-x"))
+	})
+})
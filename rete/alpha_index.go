@@ -0,0 +1,333 @@
+package rete
+
+import (
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/ccbhj/grete/log"
+	. "github.com/ccbhj/grete/types"
+)
+
+// fieldIndex index the ConstantTestNodes of the same parent testing the same field by their values,
+// so that a WME is only tested by the nodes which may pass instead of all of them(see 2.2.3(page 32) in the paper):
+//   - equality nodes are indexed by the keys of their values, so one lookup finds the ones equal to the field
+//   - ordering nodes are sorted by their values, so a binary search splits them into the passed and the failed
+type fieldIndex struct {
+	field      string
+	eq         map[uint64][]*ConstantTestNode // keyed by equalityKey of the values
+	thresholds map[GValueType]*thresholds     // keyed by the types of rangeKey of the values
+	n          int
+}
+
+// thresholds are the ordering nodes sorted by rangeKey of their values
+type thresholds struct {
+	below []rangeEntry // nodes of TestOpLess and TestOpLessEqual, which pass when their values are below the field
+	above []rangeEntry // nodes of TestOpGreater and TestOpGreaterEqual, which pass when their values are above the field
+}
+
+type rangeEntry struct {
+	key  GValue
+	node *ConstantTestNode
+}
+
+func newFieldIndex(field string) *fieldIndex {
+	return &fieldIndex{
+		field:      field,
+		eq:         make(map[uint64][]*ConstantTestNode),
+		thresholds: make(map[GValueType]*thresholds),
+	}
+}
+
+func (idx *fieldIndex) add(c *ConstantTestNode) {
+	idx.n++
+	if c.TestOp == TestOpEqual {
+		k, _ := equalityKey(c.V)
+		idx.eq[k] = append(idx.eq[k], c)
+		return
+	}
+
+	k, _ := rangeKey(c.V)
+	ts, in := idx.thresholds[k.Type()]
+	if !in {
+		ts = &thresholds{}
+		idx.thresholds[k.Type()] = ts
+	}
+	entries := ts.entriesOf(c.TestOp)
+	i := sort.Search(len(*entries), func(i int) bool { return compareKey((*entries)[i].key, k) > 0 })
+	*entries = slices.Insert(*entries, i, rangeEntry{key: k, node: c})
+}
+
+func (idx *fieldIndex) remove(c *ConstantTestNode) {
+	if c.TestOp == TestOpEqual {
+		k, _ := equalityKey(c.V)
+		nodes := idx.eq[k]
+		if i := slices.Index(nodes, c); i >= 0 {
+			idx.n--
+			if len(nodes) == 1 {
+				delete(idx.eq, k)
+			} else {
+				idx.eq[k] = slices.Delete(nodes, i, i+1)
+			}
+		}
+		return
+	}
+
+	k, _ := rangeKey(c.V)
+	ts, in := idx.thresholds[k.Type()]
+	if !in {
+		return
+	}
+	entries := ts.entriesOf(c.TestOp)
+	if i := slices.IndexFunc(*entries, func(e rangeEntry) bool { return e.node == c }); i >= 0 {
+		idx.n--
+		*entries = slices.Delete(*entries, i, i+1)
+	}
+	if len(ts.below) == 0 && len(ts.above) == 0 {
+		delete(idx.thresholds, k.Type())
+	}
+}
+
+// forEachMatch call fn with each node passed by v
+func (idx *fieldIndex) forEachMatch(v GValue, fn func(*ConstantTestNode)) {
+	// none of the indexed nodes pass with nil
+	if isNil(v) {
+		return
+	}
+	test := func(c *ConstantTestNode) {
+		ok, err := c.test(v)
+		if err != nil {
+			log.L("fail to perform test on field %s: %s", idx.field, err)
+			return
+		}
+		if ok {
+			fn(c)
+		}
+	}
+
+	if k, ok := equalityKey(v); ok {
+		for _, c := range idx.eq[k] {
+			test(c)
+		}
+	} else {
+		// v cannot be keyed, like a user-defined GValue, so test it with all the nodes
+		for _, nodes := range idx.eq {
+			for _, c := range nodes {
+				test(c)
+			}
+		}
+	}
+
+	k, ok := rangeKey(v)
+	for t, ts := range idx.thresholds {
+		if !ok || t != k.Type() {
+			for _, e := range ts.below {
+				test(e.node)
+			}
+			for _, e := range ts.above {
+				test(e.node)
+			}
+			continue
+		}
+		ts.forEachMatch(k, test, fn)
+	}
+}
+
+// forEachMatch split the nodes by the key of a field, nodes with keys equal to k have to be tested,
+// and the rest either pass or fail without being tested
+func (ts *thresholds) forEachMatch(k GValue, test, pass func(*ConstantTestNode)) {
+	split := func(entries []rangeEntry) (lo, hi int) {
+		lo = sort.Search(len(entries), func(i int) bool { return compareKey(entries[i].key, k) >= 0 })
+		hi = lo + sort.Search(len(entries)-lo, func(i int) bool { return compareKey(entries[lo+i].key, k) > 0 })
+		return lo, hi
+	}
+
+	lo, hi := split(ts.below)
+	for i, e := range ts.below[:hi] {
+		if i < lo {
+			pass(e.node)
+		} else {
+			test(e.node)
+		}
+	}
+	lo, hi = split(ts.above)
+	for i, e := range ts.above[lo:] {
+		if lo+i < hi {
+			test(e.node)
+		} else {
+			pass(e.node)
+		}
+	}
+}
+
+func (ts *thresholds) entriesOf(op TestOp) *[]rangeEntry {
+	if op == TestOpLess || op == TestOpLessEqual {
+		return &ts.below
+	}
+	return &ts.above
+}
+
+// equalityKey return the key of v in fieldIndex, values equal by TestEqual always have the same key,
+// so numbers of all types are keyed by their float values since they are equal only if their float values are equal,
+// ok is false if v cannot be keyed
+func equalityKey(v GValue) (k uint64, ok bool) {
+	switch v := v.(type) {
+	case GVInt, GVUint, GVFloat:
+		f, _ := conv2Float(v)
+		return hashValues(0, f), true
+	case GVDecimal:
+		f, _ := v.Rat().Float64()
+		return hashValues(0, GVFloat(f)), true
+	case GVString, GVBool, GVTime, GVDuration:
+		return hashValues(0, v), true
+	}
+	return 0, false
+}
+
+// rangeKey return the key of v to be sorted in thresholds, numbers of all types are converted into floats,
+// which keeps their order since rounding never reverses the order of two numbers,
+// but numbers with the same key have to be compared by their TestOps,
+// ok is false if v cannot be keyed
+func rangeKey(v GValue) (k GValue, ok bool) {
+	switch v := v.(type) {
+	case GVInt, GVUint:
+		f, _ := conv2Float(v)
+		return f, true
+	case GVFloat:
+		// NaN is unordered and Inf cannot be compared with decimals
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return nil, false
+		}
+		return v, true
+	case GVDecimal:
+		f, _ := v.Rat().Float64()
+		return GVFloat(f), true
+	case GVString, GVTime, GVDuration:
+		return v, true
+	}
+	return nil, false
+}
+
+// compareKey compare two keys of the same type returned by rangeKey
+func compareKey(x, y GValue) int {
+	c, _ := compareValue(x, y)
+	return c
+}
+
+// indexable tell whether c can be indexed by fieldIndex,
+// nodes with negative nodes are not since they have to be activated when their tests fail
+func indexable(c *ConstantTestNode) bool {
+	if c.negativeNode != nil {
+		return false
+	}
+	switch c.TestOp {
+	case TestOpEqual:
+		if f, ok := c.V.(GVFloat); ok && math.IsNaN(float64(f)) {
+			return false
+		}
+		_, ok := equalityKey(c.V)
+		return ok
+	case TestOpLess, TestOpLessEqual, TestOpGreater, TestOpGreaterEqual:
+		_, ok := rangeKey(c.V)
+		return ok
+	}
+	return false
+}
+
+// typeIndex index the TypeTestNodes under the root by the types they accept,
+// so that a WME is only tested by the nodes which may accept it instead of all of them
+type typeIndex struct {
+	byRType map[reflect.Type][]*TypeTestNode // nodes of structs with TypeInfo.VT, keyed by TypeInfo.VT
+	byType  map[GValueType][]*TypeTestNode   // nodes keyed by TypeInfo.T
+	others  []*TypeTestNode                  // nodes of types which cannot be dispatched by the types of WMEs
+	n       int
+}
+
+func newTypeIndex() *typeIndex {
+	return &typeIndex{
+		byRType: make(map[reflect.Type][]*TypeTestNode),
+		byType:  make(map[GValueType][]*TypeTestNode),
+	}
+}
+
+func (idx *typeIndex) add(t *TypeTestNode) {
+	idx.n++
+	tf := t.TypeInfo
+	switch {
+	case tf.T == GValueTypeStruct && tf.VT != nil:
+		idx.byRType[tf.VT] = append(idx.byRType[tf.VT], t)
+	case dispatchableType(tf.T):
+		idx.byType[tf.T] = append(idx.byType[tf.T], t)
+	default:
+		idx.others = append(idx.others, t)
+	}
+}
+
+func (idx *typeIndex) remove(t *TypeTestNode) {
+	remove := func(nodes []*TypeTestNode) []*TypeTestNode {
+		if i := slices.Index(nodes, t); i >= 0 {
+			idx.n--
+			return slices.Delete(nodes, i, i+1)
+		}
+		return nodes
+	}
+	tf := t.TypeInfo
+	switch {
+	case tf.T == GValueTypeStruct && tf.VT != nil:
+		if idx.byRType[tf.VT] = remove(idx.byRType[tf.VT]); len(idx.byRType[tf.VT]) == 0 {
+			delete(idx.byRType, tf.VT)
+		}
+	case dispatchableType(tf.T):
+		if idx.byType[tf.T] = remove(idx.byType[tf.T]); len(idx.byType[tf.T]) == 0 {
+			delete(idx.byType, tf.T)
+		}
+	default:
+		idx.others = remove(idx.others)
+	}
+}
+
+// forEachMatch call fn with each node accepting w
+func (idx *typeIndex) forEachMatch(w *WME, fn func(*TypeTestNode)) {
+	test := func(nodes []*TypeTestNode) {
+		for _, t := range nodes {
+			ok, err := t.PerformTest(w)
+			if err != nil {
+				log.L("fail to perform test on wme(%+v): %s", w, err)
+				continue
+			}
+			if ok {
+				fn(t)
+			}
+		}
+	}
+
+	switch vt := w.Value.Type(); vt {
+	case GValueTypeStruct:
+		rt := reflect.TypeOf(w.Value.(*GVStruct).V)
+		if rt != nil && rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		test(idx.byRType[rt])
+		test(idx.byType[GValueTypeStruct])
+	case GValueTypeRecord:
+		// a record can be accepted by the nodes of structs without TypeInfo.VT
+		test(idx.byType[GValueTypeStruct])
+		test(idx.byType[GValueTypeRecord])
+	default:
+		test(idx.byType[vt])
+	}
+	test(idx.others)
+}
+
+// dispatchableType tell whether TypeTestNodes of t accept only the WMEs of type t,
+// nodes of structs accept records too, which are handled by typeIndex
+func dispatchableType(t GValueType) bool {
+	switch t {
+	case GValueTypeInt, GValueTypeUint, GValueTypeFloat, GValueTypeDecimal, GValueTypeString,
+		GValueTypeSet, GValueTypeBool, GValueTypeTime, GValueTypeDuration, GValueTypeList, GValueTypeMap,
+		GValueTypeStruct, GValueTypeRecord:
+		return true
+	}
+	return t.IsCustom()
+}
@@ -1,10 +1,12 @@
 package rete
 
 import (
+	"math/big"
 	"reflect"
+	"slices"
 
 	"github.com/pkg/errors"
-
+	"github.com/samber/lo"
 	"github.com/zyedidia/generic/list"
 
 	"github.com/ccbhj/grete/log"
@@ -17,6 +19,7 @@ type (
 		ID    GVIdentity
 		Value GValue
 
+		key       uint64 // key in AlphaNetwork.workingMems, which is the hash of the fact when it is added or updated
 		tokens    set[*Token]
 		alphaMems set[*AlphaMem]
 	}
@@ -51,14 +54,16 @@ func (w *WME) HasAttr(attr string) bool {
 	case FieldSelf, FieldID:
 		return true
 	}
-	val := w.Value
-	// ony TVStruct has fields other than FieldSelf
-	if val.Type() != GValueTypeStruct {
+	// ony TVStruct and GVRecord have fields other than FieldSelf
+	val, ok := w.Value.(FieldAccessor)
+	if !ok {
 		return false
 	}
-	return val.(*GVStruct).HasField(attr)
+	return val.HasField(attr)
 }
 
+// GetAttrValue return the value of attr, a GVNil is returned if the value is nil or attr is not found,
+// like a nil pointer along the path or a key missing in a record
 func (w *WME) GetAttrValue(attr string) (GValue, error) {
 	v, err := w.getAttrValue(attr, false)
 	if err != nil {
@@ -68,6 +73,7 @@ func (w *WME) GetAttrValue(attr string) (GValue, error) {
 	return v.(GValue), nil
 }
 
+// GetAttrValueRaw return the underlying value of attr, nil is returned if attr is not found
 func (w *WME) GetAttrValueRaw(attr string) (any, error) {
 	return w.getAttrValue(attr, true)
 }
@@ -79,19 +85,22 @@ func (w *WME) getAttrValue(attr string, raw bool) (any, error) {
 	case FieldID:
 		return w.ID, nil
 	}
-	val := w.Value
-	// ony TVStruct has fields other than FieldSelf
-	if val.Type() != GValueTypeStruct {
-		return nil, errors.WithMessagef(ErrFieldNotFound, "for type is %s", val.Type().String())
+	// ony TVStruct and GVRecord have fields other than FieldSelf
+	val, ok := w.Value.(FieldAccessor)
+	if !ok {
+		return nil, errors.WithMessagef(ErrFieldNotFound, "for type is %s", w.Value.Type().String())
 	}
 
 	var ret any
-	v, rv, err := val.(*GVStruct).GetField(attr)
+	v, rv, err := val.GetField(attr)
 	if err != nil {
-		if errors.Is(err, ErrFieldNotFound) {
-			return nil, nil
+		if !errors.Is(err, ErrFieldNotFound) {
+			return nil, err
 		}
-		return nil, err
+		v, rv = &GVNil{}, nil
+	}
+	if v == nil {
+		v = &GVNil{}
 	}
 
 	if raw {
@@ -229,8 +238,7 @@ func (m *AlphaMem) Activate(w *WME) int {
 // _destory clean remove all the WME from alpha mem along with all the ConstantTestNode that is no long in use
 func (m *AlphaMem) _destory() {
 	m.items.ForEach(func(item *WME) {
-		h := item.FactOfWME().Hash()
-		m.an.removeWME(h, item)
+		m.an.removeWME(item)
 	})
 	m.items.Clear()
 
@@ -240,8 +248,8 @@ func (m *AlphaMem) _destory() {
 		current = m.inputAlphaNode
 	)
 	current.SetOutputMem(nil)
-	// remove current node from its parent when current node has no child (leaf node)
-	for current != nil && current.Hash() != 0 && current.NChildren() == 0 {
+	// remove current node from its parent when current node has no child (leaf node) and outputs to no alpha mem
+	for current != nil && current.Hash() != 0 && current.NChildren() == 0 && current.OutputMem() == nil {
 		parent = current.Parent()
 		if parent != nil {
 			parent.RemoveChild(current)
@@ -260,7 +268,8 @@ func (m *AlphaMem) _destory() {
 type AlphaNetwork struct {
 	root          AlphaNode
 	cond2AlphaMem map[uint64]*AlphaMem
-	workingMems   map[uint64]*WME
+	workingMems   map[uint64][]*WME        // WMEs bucketed by the hashes of their facts
+	factIDs       map[GVIdentity]set[*WME] // WMEs in workingMems indexed by their IDs
 	typeNodes     map[uint64]*TypeTestNode
 }
 
@@ -269,7 +278,8 @@ func NewAlphaNetwork() *AlphaNetwork {
 	alphaNet := &AlphaNetwork{
 		root:          root,
 		cond2AlphaMem: make(map[uint64]*AlphaMem),
-		workingMems:   make(map[uint64]*WME),
+		workingMems:   make(map[uint64][]*WME),
+		factIDs:       make(map[GVIdentity]set[*WME]),
 		typeNodes:     make(map[uint64]*TypeTestNode),
 	}
 	return alphaNet
@@ -281,11 +291,63 @@ func (n *AlphaNetwork) AlphaRoot() AlphaNode {
 
 func (n *AlphaNetwork) activateAlphaNode(node AlphaNode, w *WME) int {
 	ret := 0
-	testOk, err := node.PerformTest(w)
+	n.matchAlphaNode(node, w, func(mem *AlphaMem) {
+		mem.Activate(w)
+		ret++
+	})
+	return ret
+}
+
+// matchAlphaNode call fn with each AlphaMem below node that w passes all the tests to
+func (n *AlphaNetwork) matchAlphaNode(node AlphaNode, w *WME, fn func(*AlphaMem)) {
+	m := &alphaMatch{an: n, w: w, fn: fn}
+	m.matchAlphaNode(node, true)
+}
+
+// alphaMatch match a WME against the alpha network,
+// subtrees whose results are unchanged by the modification of the WME are skipped if mask is not nil
+type alphaMatch struct {
+	an     *AlphaNetwork
+	w      *WME
+	mask   fieldMask      // fields changed since w was matched last time
+	pruned set[AlphaNode] // roots of the subtrees skipped
+	fn     func(*AlphaMem)
+}
+
+// skip tell whether the subtree of node can be skipped, that is neither the tests above node nor the ones in the subtree
+// read the changed fields, so w stays in the same AlphaMems of the subtree,
+// affected tells whether any test above node reads the changed fields
+func (m *alphaMatch) skip(node AlphaNode, affected bool) bool {
+	if m.mask == nil || affected || subtreeAffected(node, m.mask) {
+		return false
+	}
+	m.pruned.Add(node)
+	return true
+}
+
+// isPruned tell whether am is in a skipped subtree
+func (m *alphaMatch) isPruned(am *AlphaMem) bool {
+	if m.pruned.Len() == 0 {
+		return false
+	}
+	for node := am.inputAlphaNode; node != nil; node = node.Parent() {
+		if m.pruned.Contains(node) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *alphaMatch) matchAlphaNode(node AlphaNode, affected bool) {
+	if m.skip(node, affected) {
+		return
+	}
+	testOk, err := node.PerformTest(m.w)
 	if err != nil {
-		log.L("fail to perform test on wme(%+v): %s", w, err)
-		return 0
+		log.L("fail to perform test on wme(%+v): %s", m.w, err)
+		return
 	}
+	affected = affected || testAffected(node, m.w, m.mask)
 
 	// check if node support negative activation
 	var negativeChild *NegativeTestNode
@@ -296,55 +358,95 @@ func (n *AlphaNetwork) activateAlphaNode(node AlphaNode, w *WME) int {
 
 	if !testOk {
 		if negativeChild != nil {
-			ret += n.activateAlphaNode(negativeChild, w)
+			m.matchAlphaNode(negativeChild, affected)
 		}
-		return ret
+		return
 	}
 
+	m.matchSuccessors(node, negativeChild, affected)
+}
+
+// matchSuccessors match the output mem and the children of node after w passed its test
+func (m *alphaMatch) matchSuccessors(node AlphaNode, negativeChild *NegativeTestNode, affected bool) {
 	if mem := node.OutputMem(); mem != nil {
-		mem.Activate(w)
-		ret++
+		m.fn(mem)
+	}
+
+	forEachChild := func(fn func(AlphaNode)) {
+		node.ForEachChild(func(child AlphaNode) (stop bool) { fn(child); return false })
+	}
+	if indexed, ok := node.(indexedAlphaNode); ok {
+		forEachChild = indexed.forEachUnindexedChild
+		// only the indexed children passed by w are matched
+		indexed.forEachIndexedMatch(m.w, func(child AlphaNode) {
+			if !m.skip(child, affected) {
+				m.matchSuccessors(child, nil, affected || testAffected(child, m.w, m.mask))
+			}
+		})
 	}
 
-	node.ForEachChild(func(child AlphaNode) (stop bool) {
+	forEachChild(func(child AlphaNode) {
 		// avoid negative activation
 		if child == negativeChild {
-			return false
+			return
 		}
-		log.BugOn(n.root.OutputMem() == nil || child.OutputMem() != n.root.OutputMem(),
+		log.BugOn(m.an.root.OutputMem() == nil || child.OutputMem() != m.an.root.OutputMem(),
 			"dummy output mem is found!!!")
-		ret += n.activateAlphaNode(child, w)
-		return false
+		m.matchAlphaNode(child, affected)
 	})
-	return ret
 }
 
 func (n *AlphaNetwork) AddFact(f Fact) int {
 	h := f.Hash()
-	w, in := n.workingMems[h]
-	if in {
+	if w := n.findWME(h, f); w != nil {
 		return n.activateAlphaNode(n.root, w)
 	}
-	w = f.WMEFromFact()
-	return n.addWME(h, w)
+	return n.addWME(h, f.WMEFromFact())
+}
+
+// findWME return the WME of the fact equal to f in working memory, h is the hash of f
+func (n *AlphaNetwork) findWME(h uint64, f Fact) *WME {
+	for _, w := range n.workingMems[h] {
+		if f.Equal(w.FactOfWME()) {
+			return w
+		}
+	}
+	return nil
+}
+
+// forEachWME iterate all the WMEs in working memory
+func (n *AlphaNetwork) forEachWME(fn func(*WME)) {
+	for _, ws := range n.workingMems {
+		for _, w := range ws {
+			fn(w)
+		}
+	}
 }
 
 func (n *AlphaNetwork) addWME(sum uint64, w *WME) int {
-	n.workingMems[sum] = w
+	n.putWME(sum, w)
 	return n.activateAlphaNode(n.root, w)
 }
 
-func (n *AlphaNetwork) RemoveFact(f Fact) {
-	h := f.Hash()
-	w, in := n.workingMems[h]
+func (n *AlphaNetwork) putWME(sum uint64, w *WME) {
+	w.key = sum
+	n.workingMems[sum] = append(n.workingMems[sum], w)
+	ids, in := n.factIDs[w.ID]
 	if !in {
-		return
+		ids = newSet[*WME]()
+		n.factIDs[w.ID] = ids
+	}
+	ids.Add(w)
+}
+
+func (n *AlphaNetwork) RemoveFact(f Fact) {
+	if w := n.findWME(f.Hash(), f); w != nil {
+		n.removeWME(w)
 	}
-	n.removeWME(h, w)
 }
 
-func (n *AlphaNetwork) removeWME(sum uint64, w *WME) {
-	delete(n.workingMems, sum)
+func (n *AlphaNetwork) removeWME(w *WME) {
+	n.deleteWME(w)
 	// clear all the alpha memories
 	w.alphaMems.ForEach(func(am *AlphaMem) {
 		am.removeWME(w)
@@ -353,6 +455,102 @@ func (n *AlphaNetwork) removeWME(sum uint64, w *WME) {
 	w._destory()
 }
 
+func (n *AlphaNetwork) deleteWME(w *WME) {
+	if ws := slices.DeleteFunc(n.workingMems[w.key], func(x *WME) bool { return x == w }); len(ws) > 0 {
+		n.workingMems[w.key] = ws
+	} else {
+		delete(n.workingMems, w.key)
+	}
+	if ids := n.factIDs[w.ID]; ids != nil {
+		ids.Del(w)
+		if ids.Len() == 0 {
+			delete(n.factIDs, w.ID)
+		}
+	}
+}
+
+// UpdateFact change the value of the fact with ID id into value, and re-evaluate it incrementally:
+// it leaves the AlphaMems it no longer passes along with the tokens from them, enters the ones it passes now,
+// and the join tests of the rest tokens are re-run, so that tokens whose join results are unchanged are kept.
+// If the updated fact equals to another one, they are merged into one.
+// An error is returned if there is no fact or more than one facts with ID id.
+func (n *AlphaNetwork) UpdateFact(id GVIdentity, value GValue) error {
+	w, err := n.lookupWME(id)
+	if err != nil {
+		return err
+	}
+	n.updateWME(w, value, nil)
+	return nil
+}
+
+// ModifyFact re-evaluate the fact with ID id after its value is modified in place,
+// like the fields of a struct pointer are changed, see UpdateFact.
+// If the changed fields are given, only the guards and join tests reading them are re-run,
+// and all the fields are considered changed if none is given.
+func (n *AlphaNetwork) ModifyFact(id GVIdentity, fields ...string) error {
+	w, err := n.lookupWME(id)
+	if err != nil {
+		return err
+	}
+	n.updateWME(w, w.Value, newFieldMask(fields...))
+	return nil
+}
+
+func (n *AlphaNetwork) lookupWME(id GVIdentity) (*WME, error) {
+	ids := n.factIDs[id]
+	switch ids.Len() {
+	case 0:
+		return nil, errors.WithMessagef(ErrFactNotFound, "id=%s", id)
+	case 1:
+		for w := range ids {
+			return w, nil
+		}
+	}
+	return nil, errors.Errorf("%d facts with id %s are found", ids.Len(), id)
+}
+
+func (n *AlphaNetwork) updateWME(w *WME, value GValue, mask fieldMask) {
+	n.deleteWME(w)
+	w.Value = value
+	h := w.FactOfWME().Hash()
+	if n.findWME(h, w.FactOfWME()) != nil {
+		// merged into an existing fact
+		n.removeWME(w)
+		return
+	}
+	n.putWME(h, w)
+
+	mems := newSet[*AlphaMem]()
+	m := &alphaMatch{an: n, w: w, mask: mask, pruned: newSet[AlphaNode](), fn: func(am *AlphaMem) { mems.Add(am) }}
+	m.matchAlphaNode(n.root, mask == nil)
+
+	// leave the alpha mems that w no longer passes, w stays in the ones below the skipped subtrees
+	w.alphaMems.ForEach(func(am *AlphaMem) {
+		if !mems.Contains(am) && !m.isPruned(am) {
+			am.removeWME(w)
+			w.alphaMems.Del(am)
+		}
+	})
+	tokens := lo.Keys(w.tokens)
+	for _, tk := range tokens {
+		if tk.wme != nil && !w.alphaMems.Contains(tk.alphaMem()) {
+			tk.destory()
+		}
+	}
+	// re-run the join tests of the tokens kept
+	for _, tk := range tokens {
+		if tk.wme != nil {
+			tk.reevaluate(mask)
+		}
+	}
+	// enter the alpha mems that w passes now
+	mems.ForEach(func(am *AlphaMem) {
+		if !am.hasWME(w) {
+			am.Activate(w)
+		}
+	})
+}
+
 func (n *AlphaNetwork) buildOrShareNegativeTestNode(c Guard, nn negatableAlphaNode) AlphaNode {
 	negativeNode := nn.GetNegativeNode()
 	if negativeNode != nil {
@@ -365,11 +563,18 @@ func (n *AlphaNetwork) buildOrShareNegativeTestNode(c Guard, nn negatableAlphaNo
 	return newNode
 }
 
-func (n *AlphaNetwork) MakeAlphaMem(aliasType TypeInfo, guards []Guard) *AlphaMem {
+// MakeAlphaMem build or share an AlphaMem storing WMEs of aliasType that pass all the guards,
+// a *TestOpError is returned if any guard cannot be performed on the field of aliasType
+func (n *AlphaNetwork) MakeAlphaMem(aliasType TypeInfo, guards []Guard) (*AlphaMem, error) {
 	var err error
+	for _, g := range guards {
+		if err = checkGuard(aliasType, g); err != nil {
+			return nil, err
+		}
+	}
 	h := n.hashGuards(aliasType, guards)
 	if am, in := n.cond2AlphaMem[h]; in {
-		return am
+		return am, nil
 	}
 
 	var (
@@ -388,6 +593,8 @@ func (n *AlphaNetwork) MakeAlphaMem(aliasType TypeInfo, guards []Guard) *AlphaMe
 					n.root.RemoveChild(tn)
 				}
 			}()
+			// tn is detached from the root after its AlphaMems are destroyed
+			tn.SetParent(currentNode)
 			currentNode.AddChild(tn)
 			currentNode = tn
 		}
@@ -403,11 +610,11 @@ func (n *AlphaNetwork) MakeAlphaMem(aliasType TypeInfo, guards []Guard) *AlphaMe
 		currentNode = tn
 	}
 	for _, g := range guards {
-		if g.Value.Type() == GValueTypeIdentity {
-			panic("alias as value is not allowed in guard")
-		}
 		base := newAlphaNode(currentNode)
-		newNode := NewConstantTestNode(base, g)
+		var newNode AlphaNode
+		if newNode, err = NewConstantTestNode(base, g); err != nil {
+			return nil, err
+		}
 		if cached := currentNode.GetChild(newNode.Hash()); cached != nil {
 			currentNode = cached
 		} else {
@@ -424,21 +631,21 @@ func (n *AlphaNetwork) MakeAlphaMem(aliasType TypeInfo, guards []Guard) *AlphaMe
 		if g.Negative {
 			nnode, ok := currentNode.(negatableAlphaNode)
 			if !ok {
-				// TODO: handle error
-				panic("node not supported negation")
+				err = &TestOpError{Field: g.AliasAttr, TestOp: g.TestOp, cause: errors.Errorf("%T cannot be negated", currentNode)}
+				return nil, err
 			}
 			currentNode = n.buildOrShareNegativeTestNode(g, nnode)
 		}
 	}
 
 	if am := currentNode.OutputMem(); am != nil {
-		return am
+		return am, nil
 	}
 
 	am := newAlphaMem(aliasType, guards, currentNode, n)
 	currentNode.SetOutputMem(am)
 	n.cond2AlphaMem[h] = am
-	return am
+	return am, nil
 }
 
 // initialize am with any current working memory
@@ -452,16 +659,12 @@ func (n *AlphaNetwork) InitAlphaMem(am *AlphaMem) {
 		node = node.Parent()
 	}
 	if node != nil {
-		for _, w := range n.workingMems {
-			n.activateAlphaNode(node, w)
-		}
+		n.forEachWME(func(w *WME) { n.activateAlphaNode(node, w) })
 	}
 }
 
 func (n *AlphaNetwork) InitDummyAlphaMem(am *AlphaMem, c Guard) {
-	for _, w := range n.workingMems {
-		am.Activate(w)
-	}
+	n.forEachWME(func(w *WME) { am.Activate(w) })
 }
 
 func (n *AlphaNetwork) dummyAlphaNode() *AlphaMem {
@@ -492,8 +695,12 @@ func (n *AlphaNetwork) hashGuards(typeInfo TypeInfo, guards []Guard) uint64 {
 // Alpha test nodes
 type (
 	alphaNode struct {
-		parent    AlphaNode            // parent, nil for the root node
-		children  map[uint64]AlphaNode // children node to be activated when PerformTest() returns true
+		parent    AlphaNode              // parent, nil for the root node
+		children  map[uint64]AlphaNode   // children node to be activated when PerformTest() returns true
+		unindexed map[uint64]AlphaNode   // children which are not in indexes
+		indexes   map[string]*fieldIndex // ConstantTestNodes in children indexed by their fields
+		types     *typeIndex             // TypeTestNodes in children indexed by their types
+		reads     set[string]            // fields read by the tests of the descendants
 		outputMem *AlphaMem
 	}
 
@@ -517,12 +724,26 @@ type (
 		GetNegativeNode() *NegativeTestNode
 		SetNegativeNode(n *NegativeTestNode)
 	}
+
+	// indexedAlphaNode is implemented by all the nodes embedding *alphaNode
+	indexedAlphaNode interface {
+		AlphaNode
+		forEachUnindexedChild(fn func(AlphaNode))
+		forEachIndexedMatch(w *WME, fn func(AlphaNode))
+		reindexChild(child AlphaNode)
+		testFields() []string
+		descendantReads() set[string]
+		addReads(fields []string)
+		recomputeReads()
+	}
 )
 
 func newAlphaNode(parent AlphaNode) *alphaNode {
 	return &alphaNode{
-		parent:   parent,
-		children: make(map[uint64]AlphaNode),
+		parent:    parent,
+		children:  make(map[uint64]AlphaNode),
+		unindexed: make(map[uint64]AlphaNode),
+		reads:     newSet[string](),
 	}
 }
 
@@ -551,6 +772,11 @@ func (n *alphaNode) AddChild(child AlphaNode) {
 		return
 	}
 	n.children[h] = child
+	n.unindexed[h] = child
+	n.indexChild(child)
+	if c, ok := child.(indexedAlphaNode); ok {
+		n.addReads(append(c.testFields(), lo.Keys(c.descendantReads())...))
+	}
 }
 
 func (n *alphaNode) ForEachChild(fn func(AlphaNode) (stop bool)) {
@@ -563,7 +789,167 @@ func (n *alphaNode) ForEachChild(fn func(AlphaNode) (stop bool)) {
 
 func (n *alphaNode) RemoveChild(child AlphaNode) {
 	h := child.Hash()
+	if cached, in := n.children[h]; in {
+		n.unindexChild(cached)
+	}
 	delete(n.children, h)
+	delete(n.unindexed, h)
+	n.recomputeReads()
+}
+
+// indexChild add child into the index of its field, or the index of types if it is a TypeTestNode
+func (n *alphaNode) indexChild(child AlphaNode) {
+	switch c := child.(type) {
+	case *ConstantTestNode:
+		if c.indexed || !indexable(c) {
+			return
+		}
+		if n.indexes == nil {
+			n.indexes = make(map[string]*fieldIndex)
+		}
+		idx, in := n.indexes[c.Field]
+		if !in {
+			idx = newFieldIndex(c.Field)
+			n.indexes[c.Field] = idx
+		}
+		idx.add(c)
+		c.indexed = true
+	case *TypeTestNode:
+		if c.indexed {
+			return
+		}
+		if n.types == nil {
+			n.types = newTypeIndex()
+		}
+		n.types.add(c)
+		c.indexed = true
+	default:
+		return
+	}
+	delete(n.unindexed, child.Hash())
+}
+
+func (n *alphaNode) unindexChild(child AlphaNode) {
+	switch c := child.(type) {
+	case *ConstantTestNode:
+		if !c.indexed {
+			return
+		}
+		idx := n.indexes[c.Field]
+		idx.remove(c)
+		if idx.n == 0 {
+			delete(n.indexes, c.Field)
+		}
+		c.indexed = false
+	case *TypeTestNode:
+		if !c.indexed {
+			return
+		}
+		n.types.remove(c)
+		c.indexed = false
+	default:
+		return
+	}
+	n.unindexed[child.Hash()] = child
+}
+
+// reindexChild update the index of child after it is changed, like its negative node is set
+func (n *alphaNode) reindexChild(child AlphaNode) {
+	if n.children[child.Hash()] != child {
+		return
+	}
+	n.unindexChild(child)
+	n.indexChild(child)
+}
+
+// forEachUnindexedChild iterate the children which are not indexed by their fields
+func (n *alphaNode) forEachUnindexedChild(fn func(AlphaNode)) {
+	for _, child := range n.unindexed {
+		fn(child)
+	}
+}
+
+// forEachIndexedMatch call fn with each indexed child passed by w, reading each indexed field of w only once
+func (n *alphaNode) forEachIndexedMatch(w *WME, fn func(AlphaNode)) {
+	if n.types != nil {
+		n.types.forEachMatch(w, func(t *TypeTestNode) { fn(t) })
+	}
+	for _, idx := range n.indexes {
+		v, err := w.GetAttrValue(idx.field)
+		if err != nil {
+			log.L("fail to perform test on wme(%+v): %s", w, err)
+			continue
+		}
+		idx.forEachMatch(v, func(c *ConstantTestNode) { fn(c) })
+	}
+}
+
+// testFields return the fields read by the test of the node
+func (n *alphaNode) testFields() []string { return nil }
+
+// descendantReads return the fields read by the tests of the descendants
+func (n *alphaNode) descendantReads() set[string] { return n.reads }
+
+// addReads add the fields read by a descendant into the reads of n and its ancestors
+func (n *alphaNode) addReads(fields []string) {
+	added := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if !n.reads.Contains(f) {
+			n.reads.Add(f)
+			added = append(added, f)
+		}
+	}
+	if p, ok := n.parent.(indexedAlphaNode); ok && len(added) > 0 {
+		p.addReads(added)
+	}
+}
+
+// recomputeReads rebuild the reads of n and its ancestors after a descendant is removed
+func (n *alphaNode) recomputeReads() {
+	reads := newSet[string]()
+	for _, child := range n.children {
+		if c, ok := child.(indexedAlphaNode); ok {
+			for _, f := range c.testFields() {
+				reads.Add(f)
+			}
+			c.descendantReads().ForEach(reads.Add)
+		}
+	}
+	// reads never grows by removing a descendant
+	if reads.Len() == n.reads.Len() {
+		return
+	}
+	n.reads = reads
+	if p, ok := n.parent.(indexedAlphaNode); ok {
+		p.recomputeReads()
+	}
+}
+
+// subtreeAffected tell whether the test of node or any of its descendants reads the fields changed in mask
+func subtreeAffected(node AlphaNode, mask fieldMask) bool {
+	n, ok := node.(indexedAlphaNode)
+	if !ok || mask == nil || mask.affectsAny(n.testFields()...) {
+		return true
+	}
+	for f := range n.descendantReads() {
+		if mask.affects(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// testAffected tell whether the result of the test of node on w may be changed by the changes in mask
+func testAffected(node AlphaNode, w *WME, mask fieldMask) bool {
+	n, ok := node.(indexedAlphaNode)
+	if !ok {
+		return true
+	}
+	if _, ok := node.(*TypeTestNode); ok && w.Value.Type() != GValueTypeRecord {
+		// only the fields of records are checked, the type of a value is never changed by modifying its fields
+		return false
+	}
+	return mask.affectsAny(n.testFields()...)
 }
 
 func (n *alphaNode) NChildren() int {
@@ -585,6 +971,9 @@ func (n *alphaNode) PerformTest(*WME) (bool, error) { return true, nil }
 type TypeTestNode struct {
 	*alphaNode `hash:"ignore"`
 	TypeInfo   TypeInfo `hash:"ignore"` // TypeInfo specified in a Cond
+
+	verdicts map[reflect.Type]bool // results of checkStructType cached by the struct types
+	indexed  bool                  // whether it is indexed by the typeIndex of its parent
 }
 
 var _ AlphaNode = (*TypeTestNode)(nil)
@@ -598,14 +987,25 @@ func NewTypeTestNode(alphaNode *alphaNode, tf TypeInfo) AlphaNode {
 
 func (t *TypeTestNode) PerformTest(w *WME) (bool, error) {
 	switch t.TypeInfo.T {
-	case GValueTypeInt, GValueTypeUint,
-		GValueTypeFloat, GValueTypeString:
+	case GValueTypeInt, GValueTypeUint, GValueTypeFloat, GValueTypeDecimal,
+		GValueTypeString, GValueTypeSet, GValueTypeBool,
+		GValueTypeTime, GValueTypeDuration, GValueTypeList, GValueTypeMap:
 		return t.TypeInfo.T == w.Value.Type(), nil
-	case GValueTypeStruct:
-		return w.Value.Type() == GValueTypeStruct && t.checkStructType(w), nil
+	case GValueTypeStruct, GValueTypeRecord:
+		switch w.Value.Type() {
+		case GValueTypeStruct:
+			return t.TypeInfo.T == GValueTypeStruct && t.checkStructType(w), nil
+		case GValueTypeRecord:
+			// a record has no go type to check strictly
+			return t.TypeInfo.VT == nil && t.checkRecordType(w), nil
+		}
+		return false, nil
 	case GValueTypeUnknown:
 		return false, errors.New("invalid TypeInfo, T cannot be GValueTypeUnknown")
 	}
+	if t.TypeInfo.T.IsCustom() {
+		return t.TypeInfo.T == w.Value.Type(), nil
+	}
 	return true, nil
 }
 
@@ -621,21 +1021,60 @@ func (t *TypeTestNode) checkStructType(w *WME) bool {
 		return tf.VT == vt
 	}
 
-	// check whether the struct contains fields in tf
+	// fields of a struct type are checked only once
+	ok, in := t.verdicts[vt]
+	if !in {
+		ok = checkStructFields(tf, vt)
+		if t.verdicts == nil {
+			t.verdicts = make(map[reflect.Type]bool)
+		}
+		t.verdicts[vt] = ok
+	}
+	return ok
+}
+
+// checkStructFields check whether the struct type vt contains fields in tf
+func checkStructFields(tf TypeInfo, vt reflect.Type) bool {
 	for f, t := range tf.Fields {
-		sf, in := vt.FieldByName(f)
+		sft, in := LookupFieldType(vt, f)
 		if !in {
 			return false
 		}
-		if t == GValueTypeUnknown || t == GValueTypeStruct {
+		if t == GValueTypeUnknown || t == GValueTypeStruct || sft.Kind() == reflect.Interface {
 			// skip field type checking
 			continue
 		}
-		sft := sf.Type
+		ft := sft
 		if sft.Kind() == reflect.Ptr {
 			sft = sft.Elem()
 		}
-		if rt := t.RType(); rt != nil && !sft.ConvertibleTo(rt) {
+		switch t {
+		case GValueTypeInt, GValueTypeUint, GValueTypeFloat, GValueTypeDecimal:
+			// numbers of different types are comparable
+			if !isNumberType(sft) {
+				return false
+			}
+			continue
+		case GValueTypeList:
+			if k := sft.Kind(); k != reflect.Slice && k != reflect.Array && sft != t.RType().Elem() {
+				return false
+			}
+			continue
+		case GValueTypeMap:
+			if sft.Kind() != reflect.Map && sft != t.RType().Elem() {
+				return false
+			}
+			continue
+		}
+		// user-defined GValues are not converted from any other types
+		if t.IsCustom() {
+			if sft != t.RType() && ft != t.RType() {
+				return false
+			}
+			continue
+		}
+		// pointers like *GVSet are GValues themselves
+		if rt := t.RType(); rt != nil && !sft.ConvertibleTo(rt) && !ft.ConvertibleTo(rt) {
 			return false
 		}
 	}
@@ -643,6 +1082,60 @@ func (t *TypeTestNode) checkStructType(w *WME) bool {
 	return true
 }
 
+// checkRecordType check whether the record contains fields in tf by their keys,
+// and the values of them are of the field types
+func (t *TypeTestNode) checkRecordType(w *WME) bool {
+	rec := w.Value.(*GVRecord)
+	for f, ft := range t.TypeInfo.Fields {
+		if !rec.HasField(f) {
+			return false
+		}
+		if ft == GValueTypeUnknown {
+			continue
+		}
+		v, _, err := rec.GetField(f)
+		if err != nil || !recordValueOfType(v.Type(), ft) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordValueOfType check if a value of type vt in a record can be the value of a field of type ft,
+// numbers are of any numeric type since JSON does not tell them apart,
+// and a null is a nil pointer to any struct
+func recordValueOfType(vt, ft GValueType) bool {
+	switch ft {
+	case GValueTypeInt, GValueTypeUint, GValueTypeFloat, GValueTypeDecimal:
+		return vt == GValueTypeInt || vt == GValueTypeUint || vt == GValueTypeFloat || vt == GValueTypeDecimal
+	case GValueTypeString, GValueTypeIdentity:
+		return vt == GValueTypeString || vt == GValueTypeIdentity
+	case GValueTypeStruct:
+		return vt == GValueTypeStruct || vt == GValueTypeRecord || vt == GValueTypeMap || vt == GValueTypeNil
+	}
+	return vt == ft
+}
+
+var bigNumberTypes = []reflect.Type{
+	reflect.TypeOf(GVDecimal{}), reflect.TypeOf(big.Int{}), reflect.TypeOf(big.Rat{}), reflect.TypeOf(big.Float{}),
+}
+
+// isNumberType check if values of type t are wrapped into numbers
+func isNumberType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return slices.Contains(bigNumberTypes, t)
+}
+
+// testFields return the fields checked in records
+func (t *TypeTestNode) testFields() []string {
+	return lo.Keys(t.TypeInfo.Fields)
+}
+
 func (t *TypeTestNode) Hash() uint64 {
 	return t.TypeInfo.Hash()
 }
@@ -655,22 +1148,32 @@ type ConstantTestNode struct {
 	Field        string            // which field in WME we gonna test
 	V            GValue            // the value to be compared
 	TestOp       TestOp            // test operation
+
+	test    func(GValue) (bool, error) // TestOp with V bound as its first argument
+	indexed bool                       // whether it is indexed by the equalityIndex of its parent
 }
 
 var _ AlphaNode = (*ConstantTestNode)(nil)
 var _ negatableAlphaNode = (*ConstantTestNode)(nil)
 
-func NewConstantTestNode(alphaNode *alphaNode, g Guard) AlphaNode {
+// NewConstantTestNode build a ConstantTestNode testing g, a *TestOpError is returned if g.Value cannot be bound to g.TestOp,
+// like an invalid pattern of TestOpMatch
+func NewConstantTestNode(alphaNode *alphaNode, g Guard) (AlphaNode, error) {
+	test, err := bindTestValue(g.TestOp, g.Value)
+	if err != nil {
+		return nil, &TestOpError{Field: g.AliasAttr, TestOp: g.TestOp, cause: err}
+	}
 	return &ConstantTestNode{
 		alphaNode: alphaNode,
 		Field:     string(g.AliasAttr),
 		V:         g.Value,
 		TestOp:    g.TestOp,
-	}
+		test:      test,
+	}, nil
 }
 
 func (n *ConstantTestNode) Hash() uint64 {
-	return hashAny(n)
+	return hashValues(hashAny(n), n.V)
 }
 
 func (n *ConstantTestNode) PerformTest(w *WME) (bool, error) {
@@ -678,8 +1181,11 @@ func (n *ConstantTestNode) PerformTest(w *WME) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	fn := n.TestOp.ToFunc()
-	return fn(n.V, val2test)
+	return n.test(val2test)
+}
+
+func (n *ConstantTestNode) testFields() []string {
+	return []string{n.Field}
 }
 
 func (t *ConstantTestNode) Adjust(c Guard) {}
@@ -689,6 +1195,12 @@ func (t *ConstantTestNode) GetNegativeNode() *NegativeTestNode {
 }
 
 func (t *ConstantTestNode) SetNegativeNode(n *NegativeTestNode) {
+	// t has to be activated when its test fails if it has a negative node, so it cannot be indexed
+	defer func() {
+		if p, ok := t.parent.(indexedAlphaNode); ok {
+			p.reindexChild(t)
+		}
+	}()
 	if n == nil {
 		t.alphaNode.RemoveChild(t.negativeNode)
 		t.negativeNode = nil
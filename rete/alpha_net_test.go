@@ -1,10 +1,13 @@
 package rete
 
 import (
+	"math/big"
 	"reflect"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 	"github.com/samber/lo"
 
 	. "github.com/ccbhj/grete/types"
@@ -18,6 +21,17 @@ type Chess struct {
 	Rank   int
 }
 
+type Host struct {
+	ID     GVIdentity
+	Tags   []string
+	Labels map[string]string
+}
+
+// testOpMultipleOf test if args[1] is a multiple of args[0]
+var testOpMultipleOf = RegisterTestOp("multiple-of", 2, func(args ...GValue) (bool, error) {
+	return args[1].(GVInt)%args[0].(GVInt) == 0, nil
+})
+
 func getTestFacts() []*Chess {
 	var b1, b2, b3, table Chess
 	b1 = Chess{
@@ -75,6 +89,14 @@ var _ = Describe("AlphaNet", func() {
 			Expect(Fact{ID: "X", Value: NewGVStruct(&struct{ Foo string }{"FOO"})}.Hash()).
 				ShouldNot(BeEquivalentTo(firstArg(Fact{ID: "X", Value: NewGVStruct(&struct{ Bar string }{"FOO"})}.Hash())))
 		})
+
+		It("compare by id and value", func() {
+			x := Fact{ID: "X", Value: NewGVStruct(struct{ Foo string }{"FOO"})}
+			Expect(x.Equal(Fact{ID: "X", Value: NewGVStruct(struct{ Foo string }{"FOO"})})).Should(BeTrue())
+			Expect(x.Equal(Fact{ID: "Y", Value: NewGVStruct(struct{ Foo string }{"FOO"})})).Should(BeFalse())
+			// same hash but not equal
+			Expect(x.Equal(Fact{ID: "X", Value: NewGVStruct(struct{ Bar string }{"FOO"})})).Should(BeFalse())
+		})
 	})
 
 	Describe("performing type checking", func() {
@@ -83,6 +105,8 @@ var _ = Describe("AlphaNet", func() {
 
 			Expect(NewTypeTestNode(nil, TypeInfo{T: GValueTypeString}).PerformTest(&WME{ID: "X", Value: GVInt(1)})).Should(BeFalse())
 
+			Expect(NewTypeTestNode(nil, TypeInfo{T: GValueTypeBool}).PerformTest(&WME{ID: "X", Value: GVBool(true)})).Should(BeTrue())
+			Expect(NewTypeTestNode(nil, TypeInfo{T: GValueTypeBool}).PerformTest(&WME{ID: "X", Value: GVInt(1)})).Should(BeFalse())
 		})
 
 		Context("check the value type of TVStruct", func() {
@@ -123,6 +147,316 @@ var _ = Describe("AlphaNet", func() {
 				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct{ On string }{"Y"})})).Should(BeFalse())
 			})
 
+			It("could check bool fields", func() {
+				type Switch struct{ On bool }
+				n := NewTypeTestNode(nil, TypeInfo{
+					T:      GValueTypeStruct,
+					Fields: map[string]GValueType{"On": GValueTypeBool},
+				})
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(Switch{On: true})})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct{ On string }{"Y"})})).Should(BeFalse())
+
+				v, raw, err := NewGVStruct(&Switch{On: true}).GetField("On")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(GVBool(true)))
+				Expect(raw).Should(Equal(true))
+			})
+
+			It("could check time and duration fields", func() {
+				type Job struct {
+					Start    time.Time
+					Deadline *time.Time
+					Timeout  time.Duration
+				}
+				n := NewTypeTestNode(nil, TypeInfo{
+					T: GValueTypeStruct,
+					Fields: map[string]GValueType{
+						"Start":    GValueTypeTime,
+						"Deadline": GValueTypeTime,
+						"Timeout":  GValueTypeDuration,
+					},
+				})
+				now := time.Now()
+				job := NewGVStruct(&Job{Start: now, Deadline: &now, Timeout: time.Minute})
+				Expect(n.PerformTest(&WME{ID: "X", Value: job})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct{ Start string }{"now"})})).Should(BeFalse())
+
+				v, _, err := job.GetField("Start")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(NewGVTime(now)))
+				v, _, err = job.GetField("Deadline")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(NewGVTime(now)))
+				v, _, err = job.GetField("Timeout")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(GVDuration(time.Minute)))
+			})
+
+			It("could check list and map fields", func() {
+				n := NewTypeTestNode(nil, TypeInfo{
+					T: GValueTypeStruct,
+					Fields: map[string]GValueType{
+						"Tags":          GValueTypeList,
+						"Labels":        GValueTypeMap,
+						`Labels["env"]`: GValueTypeString,
+					},
+				})
+				host := NewGVStruct(&Host{Tags: []string{"web"}, Labels: map[string]string{"env": "prod"}})
+				Expect(n.PerformTest(&WME{ID: "X", Value: host})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct {
+					Tags   string
+					Labels map[string]string
+				}{})})).Should(BeFalse())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct {
+					Tags   []string
+					Labels map[string]bool
+				}{})})).Should(BeFalse())
+
+				v, _, err := host.GetField("Tags")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(NewGVList(GVString("web"))))
+				v, _, err = host.GetField(`Labels["env"]`)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(GVString("prod")))
+				v, _, err = host.GetField(`Labels["zone"]`)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(&GVNil{}))
+				v, _, err = host.GetField("Tags[1]")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(&GVNil{}))
+				_, _, err = host.GetField("Tags[\"web\"]")
+				Expect(err).Should(HaveOccurred())
+			})
+
+			It("could check nested fields", func() {
+				n := NewTypeTestNode(nil, TypeInfo{
+					T: GValueTypeStruct,
+					Fields: map[string]GValueType{
+						"On.Color":  GValueTypeString,
+						"On.On.ID":  GValueTypeIdentity,
+						"On.LeftOf": GValueTypeStruct,
+					},
+				})
+				chesses := getTestFacts()
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(chesses[0])})).Should(BeTrue())
+				// the path is checked by type, so a nil pointer along it doesn't matter
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(chesses[3])})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct{ On string }{})})).Should(BeFalse())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct {
+					On *struct{ Color bool }
+				}{})})).Should(BeFalse())
+
+				v, _, err := NewGVStruct(chesses[0]).GetField("On.On.ID")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(GVIdentity("table")))
+				v, _, err = NewGVStruct(chesses[1]).GetField("On.On.ID")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(&GVNil{}))
+				_, _, err = NewGVStruct(chesses[0]).GetField("Color.Rank")
+				Expect(err).Should(MatchError(ErrFieldNotFound))
+				_, _, err = NewGVStruct(chesses[0]).GetField("On..Color")
+				Expect(err).Should(HaveOccurred())
+			})
+
+			It("could check fields named by tags", func() {
+				type Meta struct {
+					Env string `grete:"env"`
+				}
+				type Base struct {
+					Owner string
+				}
+				type Service struct {
+					ID     GVIdentity
+					Name   string `grete:"name"`
+					Secret string `grete:"-"`
+					Meta   Meta   `grete:",inline"`
+					Base
+				}
+				svc := NewGVStruct(&Service{ID: "S1", Name: "api", Secret: "x", Meta: Meta{Env: "prod"}, Base: Base{Owner: "ops"}})
+				for _, f := range []string{"ID", "name", "env", "Owner", "Base", "Base.Owner"} {
+					Expect(svc.HasField(f)).Should(BeTrue(), f)
+				}
+				for _, f := range []string{"Name", "Secret", "Meta", "Env", "Meta.env"} {
+					Expect(svc.HasField(f)).Should(BeFalse(), f)
+				}
+				v, _, err := svc.GetField("env")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(GVString("prod")))
+				v, _, err = svc.GetField("Owner")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(GVString("ops")))
+				_, _, err = svc.GetField("Secret")
+				Expect(err).Should(MatchError(ErrFieldNotFound))
+
+				n := NewTypeTestNode(nil, TypeInfo{
+					T: GValueTypeStruct,
+					Fields: map[string]GValueType{
+						"name":  GValueTypeString,
+						"env":   GValueTypeString,
+						"Owner": GValueTypeString,
+					},
+				})
+				Expect(n.PerformTest(&WME{ID: "S1", Value: svc})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct {
+					Name, Env, Owner string
+				}{})})).Should(BeFalse())
+
+				am, err := an.MakeAlphaMem(TypeInfo{T: GValueTypeStruct, Fields: map[string]GValueType{"env": GValueTypeString}},
+					[]Guard{{AliasAttr: "env", Value: GVString("prod"), TestOp: TestOpEqual}})
+				Expect(err).ShouldNot(HaveOccurred())
+				an.AddFact(Fact{ID: "S1", Value: svc})
+				an.AddFact(Fact{ID: "S2", Value: NewGVStruct(&Service{ID: "S2", Meta: Meta{Env: "dev"}})})
+				Expect(am.NItems()).Should(Equal(1))
+			})
+
+			It("could resolve ambiguous fields like encoding/json", func() {
+				type A struct{ X, Y int }
+				type B struct {
+					X int
+					Z int `grete:"Y"`
+				}
+				type C struct {
+					A
+					B
+					Y string
+				}
+				c := NewGVStruct(C{A: A{X: 1, Y: 2}, B: B{X: 3, Z: 4}, Y: "c"})
+				Expect(c.HasField("X")).Should(BeFalse())
+				v, _, err := c.GetField("Y")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(GVString("c")))
+
+				type D struct {
+					A
+					B
+				}
+				v, _, err = NewGVStruct(D{A: A{X: 1, Y: 2}, B: B{X: 3, Z: 4}}).GetField("Y")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(GVInt(4)))
+
+				type E struct {
+					*A
+				}
+				v, _, err = NewGVStruct(E{}).GetField("X")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(&GVNil{}))
+			})
+
+			It("could access fields through interfaces", func() {
+				type Box struct {
+					Content any
+					Labels  map[string]any
+				}
+				chesses := getTestFacts()
+				box := NewGVStruct(&Box{Content: chesses[0], Labels: map[string]any{"on": chesses[1]}})
+				Expect(box.HasField("Content.Color")).Should(BeTrue())
+				Expect(box.HasField(`Labels["on"].Rank`)).Should(BeTrue())
+				v, _, err := box.GetField("Content.On.Color")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(GVString("blue")))
+				v, _, err = box.GetField(`Labels["on"].Rank`)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(GVInt(2)))
+
+				v, _, err = NewGVStruct(&Box{}).GetField("Content.Color")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(&GVNil{}))
+				_, _, err = NewGVStruct(&Box{Content: "chess"}).GetField("Content.Color")
+				Expect(err).Should(MatchError(ErrFieldNotFound))
+				_, _, err = NewGVStruct(&Box{Content: chesses[0]}).GetField("Content.Size")
+				Expect(err).Should(MatchError(ErrFieldNotFound))
+			})
+
+			It("could derive TypeInfo from Go types", func() {
+				tf := TypeInfoOf[Chess]()
+				Expect(tf).Should(Equal(TypeInfo{
+					T: GValueTypeStruct,
+					Fields: map[string]GValueType{
+						"ID":     GValueTypeIdentity,
+						"Color":  GValueTypeString,
+						"On":     GValueTypeStruct,
+						"LeftOf": GValueTypeStruct,
+						"Rank":   GValueTypeInt,
+					},
+					VT: reflect.TypeOf(Chess{}),
+				}))
+				Expect(TypeInfoOf[*Chess]()).Should(Equal(tf))
+				Expect(TypeInfoOf[int]()).Should(Equal(TypeInfo{T: GValueTypeInt}))
+				Expect(TypeInfoOf[time.Time]()).Should(Equal(TypeInfo{T: GValueTypeTime}))
+
+				type Meta struct {
+					Env     string `grete:"env"`
+					Created time.Time
+				}
+				type Base struct{ Owner string }
+				type Service struct {
+					Name   string `grete:"name"`
+					Secret string `grete:"-"`
+					Meta   *Meta
+					Labels map[string]string
+					Base
+				}
+				Expect(TypeInfoOf[Service]().Fields).Should(Equal(map[string]GValueType{
+					"name":         GValueTypeString,
+					"Meta":         GValueTypeStruct,
+					"Meta.env":     GValueTypeString,
+					"Meta.Created": GValueTypeTime,
+					"Labels":       GValueTypeMap,
+					"Base":         GValueTypeStruct,
+					"Base.Owner":   GValueTypeString,
+					"Owner":        GValueTypeString,
+				}))
+
+				n := NewTypeTestNode(nil, tf)
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(getTestFacts()[0])})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(&Piece{})})).Should(BeFalse())
+				Expect(tf.Hash()).ShouldNot(Equal(TypeInfo{T: GValueTypeStruct, Fields: tf.Fields, VT: reflect.TypeOf(Piece{})}.Hash()))
+			})
+
+			It("could check big number fields", func() {
+				type Account struct {
+					Balance big.Int
+					Limit   *big.Rat
+					Rate    big.Float
+				}
+				acc := NewGVStruct(&Account{Balance: *big.NewInt(100), Limit: big.NewRat(1, 4), Rate: *big.NewFloat(0.5)})
+				v, _, err := acc.GetField("Balance")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v.(GVDecimal).String()).Should(Equal("100"))
+				v, _, err = acc.GetField("Limit")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v.(GVDecimal).String()).Should(Equal("0.25"))
+				v, _, err = acc.GetField("Rate")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v.(GVDecimal).String()).Should(Equal("0.5"))
+				v, _, err = NewGVStruct(&Account{}).GetField("Limit")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(v).Should(Equal(&GVNil{}))
+
+				Expect(TypeInfoOf[Account]().Fields).Should(Equal(map[string]GValueType{
+					"Balance": GValueTypeDecimal,
+					"Limit":   GValueTypeDecimal,
+					"Rate":    GValueTypeDecimal,
+				}))
+				n := NewTypeTestNode(nil, TypeInfo{T: GValueTypeStruct, Fields: map[string]GValueType{
+					"Balance": GValueTypeInt,
+					"Limit":   GValueTypeDecimal,
+				}})
+				Expect(n.PerformTest(&WME{ID: "X", Value: acc})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct{ Balance, Limit float64 }{})})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct{ Balance, Limit string }{})})).Should(BeFalse())
+			})
+
+			It("could check decimal values", func() {
+				one, err := NewGVDecimal("1")
+				Expect(err).ShouldNot(HaveOccurred())
+				n := NewTypeTestNode(nil, TypeInfo{T: GValueTypeDecimal})
+				Expect(n.PerformTest(&WME{ID: "X", Value: one})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: GVInt(1)})).Should(BeFalse())
+				Expect(n.PerformTest(&WME{ID: "X", Value: GVString("1")})).Should(BeFalse())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(&Chess{ID: "B1"})})).Should(BeFalse())
+			})
+
 			It("could check by the reflection type", func() {
 				n := NewTypeTestNode(nil, TypeInfo{
 					T:  GValueTypeStruct,
@@ -131,6 +465,110 @@ var _ = Describe("AlphaNet", func() {
 
 				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct{ Color GVString }{"red"})})).Should(BeFalse())
 				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(Chess{})})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVRecord(map[string]any{"Color": "red"})})).Should(BeFalse())
+			})
+		})
+
+		Context("check the value type of GVRecord", func() {
+			fieldOf := func(rec *GVRecord, f string) GValue {
+				v, _, err := rec.GetField(f)
+				Expect(err).ShouldNot(HaveOccurred())
+				return v
+			}
+			tf := TypeInfo{
+				T: GValueTypeStruct,
+				Fields: map[string]GValueType{
+					"Color":  GValueTypeString,
+					"Rank":   GValueTypeInt,
+					"On":     GValueTypeStruct,
+					"Meta.x": GValueTypeFloat,
+				},
+			}
+
+			It("could test missing keys as nil", func() {
+				w := &WME{ID: "X", Value: NewGVRecord(map[string]any{"Color": "red", "On": nil})}
+				for _, f := range []string{"On", "Rank", "On.Rank"} {
+					v, err := w.GetAttrValue(f)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(v).Should(Equal(&GVNil{}))
+					raw, err := w.GetAttrValueRaw(f)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(raw).Should(BeNil())
+				}
+
+				am, err := an.MakeAlphaMem(TypeInfo{T: GValueTypeStruct}, []Guard{{AliasAttr: "Rank", TestOp: TestOpIsNil}})
+				Expect(err).ShouldNot(HaveOccurred())
+				an.AddFact(Fact{ID: "X", Value: w.Value})
+				an.AddFact(Fact{ID: "Y", Value: NewGVRecord(map[string]any{"Rank": 1})})
+				Expect(am.NItems()).Should(Equal(1))
+			})
+
+			It("could check records by keys and value types", func() {
+				n := NewTypeTestNode(nil, tf)
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVRecord(map[string]any{
+					"Color": "red", "Rank": 1, "On": nil, "Meta": map[string]any{"x": 1.5},
+				})})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVRecord(map[string]any{
+					"Color": "red", "Rank": 1.0, "On": getTestFacts()[1], "Meta": map[string]any{"x": 1},
+				})})).Should(BeTrue())
+
+				// missing keys
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVRecord(map[string]any{
+					"Color": "red", "Rank": 1, "On": nil,
+				})})).Should(BeFalse())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVRecord(map[string]any{
+					"Color": "red", "Rank": 1, "On": nil, "Meta": map[string]any{},
+				})})).Should(BeFalse())
+				// mismatched value types
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVRecord(map[string]any{
+					"Color": "red", "Rank": "1", "On": nil, "Meta": map[string]any{"x": 1.5},
+				})})).Should(BeFalse())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVRecord(map[string]any{
+					"Color": "red", "Rank": 1, "On": "B2", "Meta": map[string]any{"x": 1.5},
+				})})).Should(BeFalse())
+
+				// records are not any other type
+				Expect(NewTypeTestNode(nil, TypeInfo{T: GValueTypeMap}).PerformTest(&WME{ID: "X",
+					Value: NewGVRecord(map[string]any{})})).Should(BeFalse())
+			})
+
+			It("could only check records with TypeInfo of records", func() {
+				n := NewTypeTestNode(nil, TypeInfo{T: GValueTypeRecord, Fields: map[string]GValueType{"Color": GValueTypeString}})
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVRecord(map[string]any{"Color": "red"})})).Should(BeTrue())
+				Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(getTestFacts()[0])})).Should(BeFalse())
+			})
+
+			It("could decode records from JSON", func() {
+				rec, err := NewGVRecordFromJSON([]byte(
+					`{"Color": "red", "Rank": 1, "On": null, "Meta": {"x": 1.5, "tags": ["a", 2]}}`))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(NewTypeTestNode(nil, tf).PerformTest(&WME{ID: "X", Value: rec})).Should(BeTrue())
+
+				Expect(fieldOf(rec, "Rank")).Should(Equal(GVInt(1)))
+				Expect(fieldOf(rec, "Meta.x")).Should(Equal(GVFloat(1.5)))
+				Expect(fieldOf(rec, "Meta.tags")).Should(Equal(NewGVList(GVString("a"), GVInt(2))))
+				Expect(fieldOf(rec, `Meta.tags[1]`)).Should(Equal(GVInt(2)))
+				Expect(fieldOf(rec, `Meta["x"]`)).Should(Equal(GVFloat(1.5)))
+				Expect(fieldOf(rec, "On")).Should(Equal(&GVNil{}))
+				Expect(fieldOf(rec, "On.Color")).Should(Equal(&GVNil{}))
+				Expect(fieldOf(rec, `Meta.tags[2]`)).Should(Equal(&GVNil{}))
+				_, _, err = rec.GetField("Meta.y")
+				Expect(err).Should(MatchError(ErrFieldNotFound))
+				Expect(rec.HasField("Meta.y")).Should(BeFalse())
+				_, _, err = rec.GetField("Color.x")
+				Expect(err).Should(MatchError(ErrFieldNotFound))
+
+				_, err = NewGVRecordFromJSON([]byte(`[1, 2]`))
+				Expect(err).Should(HaveOccurred())
+				_, err = NewGVRecordFromJSON([]byte(`null`))
+				Expect(err).Should(HaveOccurred())
+			})
+
+			It("could look up structs in records", func() {
+				rec := NewGVRecord(map[string]any{"Chess": getTestFacts()[0]})
+				Expect(fieldOf(rec, "Chess.On.Color")).Should(Equal(GVString("blue")))
+				Expect(fieldOf(rec, "Chess.LeftOf.Color")).Should(Equal(&GVNil{}))
+				Expect(rec.HasField("Chess.Weight")).Should(BeFalse())
 			})
 		})
 	})
@@ -149,23 +587,25 @@ var _ = Describe("AlphaNet", func() {
 		})
 
 		It("allowed the same condition to be added for more than one time", func() {
-			am := an.MakeAlphaMem(tf, []Guard{
+			am, err := an.MakeAlphaMem(tf, []Guard{
 				{
 					AliasAttr: "Color",
 					Value:     GVString("red"),
 					TestOp:    TestOpEqual,
 				},
 			})
+			Expect(err).ShouldNot(HaveOccurred())
 			Expect(am).NotTo(BeNil())
 			Expect(an.AlphaRoot()).NotTo(BeNil())
 
-			otherAM := an.MakeAlphaMem(tf, []Guard{
+			otherAM, err := an.MakeAlphaMem(tf, []Guard{
 				{
 					AliasAttr: "Color",
 					Value:     GVString("red"),
 					TestOp:    TestOpEqual,
 				},
 			})
+			Expect(err).ShouldNot(HaveOccurred())
 			Expect(otherAM).Should(BeIdenticalTo(am))
 		})
 
@@ -173,13 +613,15 @@ var _ = Describe("AlphaNet", func() {
 			var child, grandChild AlphaNode
 			var am *AlphaMem
 			BeforeEach(func() {
-				am = an.MakeAlphaMem(tf, []Guard{
+				var err error
+				am, err = an.MakeAlphaMem(tf, []Guard{
 					{
 						AliasAttr: "Color",
 						Value:     GVString("red"),
 						TestOp:    TestOpEqual,
 					},
 				})
+				Expect(err).ShouldNot(HaveOccurred())
 
 				an.AlphaRoot().ForEachChild(func(tn AlphaNode) (stop bool) {
 					child = tn
@@ -238,7 +680,9 @@ var _ = Describe("AlphaNet", func() {
 					Negative:  true,
 					TestOp:    TestOpEqual,
 				}
-				am = an.MakeAlphaMem(tf, []Guard{c})
+				var err error
+				am, err = an.MakeAlphaMem(tf, []Guard{c})
+				Expect(err).ShouldNot(HaveOccurred())
 				grandGrandChild = am.inputAlphaNode
 				grandChild = grandGrandChild.Parent()
 				child = grandChild.Parent()
@@ -254,7 +698,8 @@ var _ = Describe("AlphaNet", func() {
 			It("can share node with its positive node", func() {
 				pCond := c
 				pCond.Negative = false
-				pAm := an.MakeAlphaMem(tf, []Guard{pCond})
+				pAm, err := an.MakeAlphaMem(tf, []Guard{pCond})
+				Expect(err).ShouldNot(HaveOccurred())
 				Expect(pAm.inputAlphaNode).To(BeIdenticalTo(grandChild))
 				Expect(pAm.inputAlphaNode.Parent()).To(BeIdenticalTo(child))
 			})
@@ -274,13 +719,15 @@ var _ = Describe("AlphaNet", func() {
 					"Color": GValueTypeString,
 				},
 			}
-			am = an.MakeAlphaMem(tf, []Guard{
+			var err error
+			am, err = an.MakeAlphaMem(tf, []Guard{
 				{
 					AliasAttr: "Color",
 					Value:     GVString("red"),
 					TestOp:    TestOpEqual,
 				},
 			})
+			Expect(err).ShouldNot(HaveOccurred())
 			lo.ForEach(getTestFacts(), func(item *Chess, _ int) {
 				an.AddFact(Fact{
 					ID:    item.ID,
@@ -301,13 +748,14 @@ var _ = Describe("AlphaNet", func() {
 		})
 
 		It("however won't deconstruct a share construct node", func() {
-			newAM := an.MakeAlphaMem(tf, []Guard{
+			newAM, err := an.MakeAlphaMem(tf, []Guard{
 				{
 					AliasAttr: "Color",
 					Value:     GVString("blue"),
 					TestOp:    TestOpEqual,
 				},
 			})
+			Expect(err).ShouldNot(HaveOccurred())
 			inputNode := am.inputAlphaNode
 			parent := inputNode.Parent()
 			grandParent := inputNode.Parent().Parent()
@@ -332,7 +780,7 @@ var _ = Describe("AlphaNet", func() {
 		})
 
 		It("can destruct negative node without destructing the positive node", func() {
-			negativeAm := an.MakeAlphaMem(tf, []Guard{
+			negativeAm, err := an.MakeAlphaMem(tf, []Guard{
 				{
 					AliasAttr: "Color",
 					Value:     GVString("red"),
@@ -340,6 +788,7 @@ var _ = Describe("AlphaNet", func() {
 					Negative:  true,
 				},
 			})
+			Expect(err).ShouldNot(HaveOccurred())
 			n := am.NItems()
 			Expect(n).ShouldNot(BeZero())
 			an.DestoryAlphaMem(negativeAm)
@@ -381,7 +830,8 @@ var _ = Describe("AlphaNet", func() {
 		BeforeEach(func() {
 			ams = make([]*AlphaMem, 0, len(conds))
 			for _, c := range conds {
-				am := an.MakeAlphaMem(fieldType, []Guard{c})
+				am, err := an.MakeAlphaMem(fieldType, []Guard{c})
+				Expect(err).ShouldNot(HaveOccurred())
 				Expect(am).NotTo(BeNil())
 				ams = append(ams, am)
 			}
@@ -417,7 +867,9 @@ var _ = Describe("AlphaNet", func() {
 
 				pc := conds[2]
 				pc.Negative = false
-				positiveAM = an.MakeAlphaMem(fieldType, []Guard{pc})
+				var err error
+				positiveAM, err = an.MakeAlphaMem(fieldType, []Guard{pc})
+				Expect(err).ShouldNot(HaveOccurred())
 				lo.ForEach(testFacts, func(item *Chess, _ int) {
 					an.AddFact(Fact{ID: GVIdentity(item.ID), Value: NewGVStruct(item)})
 				})
@@ -430,6 +882,494 @@ var _ = Describe("AlphaNet", func() {
 		})
 	})
 
+	Describe("ordering guards", func() {
+		tf := TypeInfo{
+			T: GValueTypeStruct,
+			Fields: map[string]GValueType{
+				"Rank":  GValueTypeInt,
+				"Color": GValueTypeString,
+			},
+		}
+
+		DescribeTable("matching facts by guards",
+			func(g Guard, ids ...GVIdentity) {
+				am, err := an.MakeAlphaMem(tf, []Guard{g})
+				Expect(err).ShouldNot(HaveOccurred())
+				lo.ForEach(getTestFacts(), func(item *Chess, _ int) {
+					an.AddFact(Fact{ID: item.ID, Value: NewGVStruct(item)})
+				})
+				matched := make([]GVIdentity, 0, am.NItems())
+				am.ForEachItem(func(w *WME) (stop bool) {
+					matched = append(matched, w.ID)
+					return false
+				})
+				Expect(matched).Should(ConsistOf(ids))
+			},
+			// guards are tested as TestOp(Value, field)
+			Entry("1 != Rank", Guard{AliasAttr: "Rank", Value: GVInt(1), TestOp: TestOpNotEqual}, GVIdentity("B2"), GVIdentity("B3"), GVIdentity("table")),
+			Entry("2 > Rank", Guard{AliasAttr: "Rank", Value: GVInt(2), TestOp: TestOpGreater}, GVIdentity("B1"), GVIdentity("table")),
+			Entry("2 <= Rank", Guard{AliasAttr: "Rank", Value: GVUint(2), TestOp: TestOpLessEqual}, GVIdentity("B2"), GVIdentity("B3")),
+			Entry("1.5 >= Rank", Guard{AliasAttr: "Rank", Value: GVFloat(1.5), TestOp: TestOpGreaterEqual}, GVIdentity("B1"), GVIdentity("table")),
+			Entry("\"blue\" < Color", Guard{AliasAttr: "Color", Value: GVString("blue"), TestOp: TestOpLess}, GVIdentity("B1"), GVIdentity("B3")),
+			Entry("Color contains \"e\"", Guard{AliasAttr: "Color", Value: GVString("e"), TestOp: TestOpContains}, GVIdentity("B1"), GVIdentity("B2"), GVIdentity("B3")),
+			Entry("Color has prefix \"bl\"", Guard{AliasAttr: "Color", Value: GVString("bl"), TestOp: TestOpHasPrefix}, GVIdentity("B2")),
+			Entry("Color has suffix \"ed\"", Guard{AliasAttr: "Color", Value: GVString("ed"), TestOp: TestOpHasSuffix}, GVIdentity("B1"), GVIdentity("B3")),
+			Entry("Color equals \"RED\" ignoring case", Guard{AliasAttr: "Color", Value: GVString("RED"), TestOp: TestOpEqualFold}, GVIdentity("B1"), GVIdentity("B3")),
+			Entry("Color matches \"^b.*e$\"", Guard{AliasAttr: "Color", Value: GVString("^b.*e$"), TestOp: TestOpMatch}, GVIdentity("B2")),
+			Entry("Color in (\"red\" \"blue\")", Guard{AliasAttr: "Color", Value: NewGVSet(GVString("red"), GVString("blue")), TestOp: TestOpIn}, GVIdentity("B1"), GVIdentity("B2"), GVIdentity("B3")),
+			Entry("On.Color == \"blue\"", Guard{AliasAttr: "On.Color", Value: GVString("blue"), TestOp: TestOpEqual}, GVIdentity("B1")),
+			Entry("On.On.Rank <= 0", Guard{AliasAttr: "On.On.Rank", Value: GVInt(0), TestOp: TestOpGreaterEqual}, GVIdentity("B1")),
+			Entry("Rank not-in (0 2)", Guard{AliasAttr: "Rank", Value: NewGVSet(GVInt(0), GVInt(2)), TestOp: TestOpNotIn}, GVIdentity("B1"), GVIdentity("B3")),
+			Entry("On is nil", Guard{AliasAttr: "On", TestOp: TestOpIsNil}, GVIdentity("table")),
+			Entry("LeftOf is not nil", Guard{AliasAttr: "LeftOf", TestOp: TestOpNotNil}, GVIdentity("B2")),
+			Entry("1 > On.On.Rank", Guard{AliasAttr: "On.On.Rank", Value: GVInt(1), TestOp: TestOpGreater}, GVIdentity("B1")),
+			Entry("\"blue\" != On.Color", Guard{AliasAttr: "On.Color", Value: GVString("blue"), TestOp: TestOpNotEqual}, GVIdentity("B2"), GVIdentity("B3"), GVIdentity("table")),
+			Entry("On.Color has prefix \"bl\"", Guard{AliasAttr: "On.Color", Value: GVString("bl"), TestOp: TestOpHasPrefix}, GVIdentity("B1")),
+		)
+
+		It("can share ConstantTestNode with the same pattern", func() {
+			g := Guard{AliasAttr: "Color", Value: GVString("^r"), TestOp: TestOpMatch}
+			am, err := an.MakeAlphaMem(tf, []Guard{g})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(an.MakeAlphaMem(tf, []Guard{g})).Should(BeIdenticalTo(am))
+
+			other, err := an.MakeAlphaMem(tf, []Guard{{AliasAttr: "Color", Value: GVString("^b"), TestOp: TestOpMatch}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(other).ShouldNot(BeIdenticalTo(am))
+			Expect(other.inputAlphaNode.Parent()).Should(BeIdenticalTo(am.inputAlphaNode.Parent()))
+		})
+
+		It("can share ConstantTestNode with the same set", func() {
+			g := Guard{AliasAttr: "Color", Value: NewGVSet(GVString("red"), GVString("blue")), TestOp: TestOpIn}
+			am, err := an.MakeAlphaMem(tf, []Guard{g})
+			Expect(err).ShouldNot(HaveOccurred())
+			g.Value = NewGVSet(GVString("blue"), GVString("red"))
+			Expect(an.MakeAlphaMem(tf, []Guard{g})).Should(BeIdenticalTo(am))
+
+			g.Value = NewGVSet(GVString("blue"))
+			other, err := an.MakeAlphaMem(tf, []Guard{g})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(other).ShouldNot(BeIdenticalTo(am))
+			Expect(other.inputAlphaNode).ShouldNot(BeIdenticalTo(am.inputAlphaNode))
+		})
+
+		It("can share ConstantTestNode with the same decimal", func() {
+			d := func(s string) GVDecimal {
+				v, err := NewGVDecimal(s)
+				Expect(err).ShouldNot(HaveOccurred())
+				return v
+			}
+			g := Guard{AliasAttr: "Rank", Value: d("1.50"), TestOp: TestOpGreater}
+			am, err := an.MakeAlphaMem(tf, []Guard{g})
+			Expect(err).ShouldNot(HaveOccurred())
+			g.Value = d("1.5")
+			Expect(an.MakeAlphaMem(tf, []Guard{g})).Should(BeIdenticalTo(am))
+
+			g.Value = d("2.5")
+			other, err := an.MakeAlphaMem(tf, []Guard{g})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(other).ShouldNot(BeIdenticalTo(am))
+			lo.ForEach(getTestFacts(), func(item *Chess, _ int) {
+				an.AddFact(Fact{ID: item.ID, Value: NewGVStruct(item)})
+			})
+			matched := make([]GVIdentity, 0, am.NItems())
+			am.ForEachItem(func(w *WME) (stop bool) {
+				matched = append(matched, w.ID)
+				return false
+			})
+			Expect(matched).Should(ConsistOf(GVIdentity("B1"), GVIdentity("table")))
+		})
+
+		It("can test and share nodes with custom TestOp", func() {
+			g := Guard{AliasAttr: "Rank", Value: GVInt(2), TestOp: testOpMultipleOf}
+			am, err := an.MakeAlphaMem(tf, []Guard{g})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(an.MakeAlphaMem(tf, []Guard{g})).Should(BeIdenticalTo(am))
+			lo.ForEach(getTestFacts(), func(item *Chess, _ int) {
+				an.AddFact(Fact{ID: item.ID, Value: NewGVStruct(item)})
+			})
+			matched := make([]GVIdentity, 0, am.NItems())
+			am.ForEachItem(func(w *WME) (stop bool) {
+				matched = append(matched, w.ID)
+				return false
+			})
+			Expect(matched).Should(ConsistOf(GVIdentity("B2"), GVIdentity("table")))
+		})
+
+		It("rejects an invalid pattern", func() {
+			_, err := an.MakeAlphaMem(tf, []Guard{{AliasAttr: "Color", Value: GVString("("), TestOp: TestOpMatch}})
+			var e *TestOpError
+			Expect(errors.As(err, &e)).Should(BeTrue())
+			Expect(e.Field).Should(Equal(GVString("Color")))
+			Expect(e.TestOp).Should(Equal(TestOpMatch))
+
+			// nodes that never match are not built
+			_, err = NewConstantTestNode(newAlphaNode(nil), Guard{AliasAttr: "Color", Value: GVString("("), TestOp: TestOpMatch})
+			Expect(errors.As(err, &e)).Should(BeTrue())
+			Expect(an.AlphaRoot().NChildren()).Should(BeZero())
+		})
+	})
+
+	Describe("collection guards", func() {
+		tf := TypeInfo{
+			T: GValueTypeStruct,
+			Fields: map[string]GValueType{
+				"Tags":   GValueTypeList,
+				"Labels": GValueTypeMap,
+			},
+		}
+		hosts := []*Host{
+			{ID: "H1", Tags: []string{"web", "canary"}, Labels: map[string]string{"env": "prod"}},
+			{ID: "H2", Tags: []string{"db"}, Labels: map[string]string{"env": "dev", "zone": "a"}},
+			{ID: "H3"},
+		}
+
+		DescribeTable("matching facts by guards",
+			func(g Guard, ids ...GVIdentity) {
+				am, err := an.MakeAlphaMem(tf, []Guard{g})
+				Expect(err).ShouldNot(HaveOccurred())
+				for _, h := range hosts {
+					an.AddFact(Fact{ID: h.ID, Value: NewGVStruct(h)})
+				}
+				matched := make([]GVIdentity, 0, am.NItems())
+				am.ForEachItem(func(w *WME) (stop bool) {
+					matched = append(matched, w.ID)
+					return false
+				})
+				Expect(matched).Should(ConsistOf(ids))
+			},
+			Entry("Tags contains \"web\"", Guard{AliasAttr: "Tags", Value: GVString("web"), TestOp: TestOpContains}, GVIdentity("H1")),
+			Entry("Labels contains \"zone\"", Guard{AliasAttr: "Labels", Value: GVString("zone"), TestOp: TestOpContains}, GVIdentity("H2")),
+			Entry("len(Tags) == 0", Guard{AliasAttr: "Tags", Value: GVInt(0), TestOp: LenOf(TestOpEqual, 1)}, GVIdentity("H3")),
+			Entry("any element of Tags has prefix \"ca\"", Guard{AliasAttr: "Tags", Value: GVString("ca"), TestOp: AnyElementOf(TestOpHasPrefix, 1)}, GVIdentity("H1")),
+			Entry("Labels[\"env\"] == \"prod\"", Guard{AliasAttr: `Labels["env"]`, Value: GVString("prod"), TestOp: TestOpEqual}, GVIdentity("H1")),
+			Entry("Labels[\"env\"] != \"prod\"", Guard{AliasAttr: `Labels["env"]`, Value: GVString("prod"), TestOp: TestOpNotEqual}, GVIdentity("H2"), GVIdentity("H3")),
+			Entry("Tags[0] == \"db\"", Guard{AliasAttr: "Tags[0]", Value: GVString("db"), TestOp: TestOpEqual}, GVIdentity("H2")),
+		)
+
+		It("can share nodes with the same derived TestOp", func() {
+			g := Guard{AliasAttr: "Tags", Value: GVInt(1), TestOp: LenOf(TestOpLess, 1)}
+			am, err := an.MakeAlphaMem(tf, []Guard{g})
+			Expect(err).ShouldNot(HaveOccurred())
+			g.TestOp = LenOf(TestOpLess, 1)
+			Expect(an.MakeAlphaMem(tf, []Guard{g})).Should(BeIdenticalTo(am))
+		})
+	})
+
+	Describe("equality guards", func() {
+		tf := TypeInfo{
+			T: GValueTypeStruct,
+			Fields: map[string]GValueType{
+				"Rank":  GValueTypeInt,
+				"Color": GValueTypeString,
+			},
+		}
+		matchedOf := func(am *AlphaMem) []GVIdentity {
+			matched := make([]GVIdentity, 0, am.NItems())
+			am.ForEachItem(func(w *WME) (stop bool) {
+				matched = append(matched, w.ID)
+				return false
+			})
+			return matched
+		}
+
+		It("can dispatch facts to equality guards by the values of fields", func() {
+			three, err := NewGVDecimal("3.0")
+			Expect(err).ShouldNot(HaveOccurred())
+			guards := []Guard{
+				{AliasAttr: "Rank", Value: GVInt(0), TestOp: TestOpEqual},
+				{AliasAttr: "Rank", Value: GVUint(1), TestOp: TestOpEqual},
+				{AliasAttr: "Rank", Value: GVFloat(2), TestOp: TestOpEqual},
+				{AliasAttr: "Rank", Value: three, TestOp: TestOpEqual},
+				{AliasAttr: "Rank", Value: GVInt(4), TestOp: TestOpEqual},
+				{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual},
+				{AliasAttr: "Color", Value: GVString("blue"), TestOp: TestOpEqual},
+				{AliasAttr: "Rank", Value: GVInt(2), TestOp: TestOpGreater},
+			}
+			ams := make([]*AlphaMem, 0, len(guards))
+			for _, g := range guards {
+				am, err := an.MakeAlphaMem(tf, []Guard{g})
+				Expect(err).ShouldNot(HaveOccurred())
+				ams = append(ams, am)
+			}
+			typeNode := ams[0].inputAlphaNode.Parent().(*TypeTestNode)
+			Expect(typeNode.NChildren()).Should(Equal(len(guards)))
+			Expect(typeNode.indexes).Should(HaveLen(2))
+			Expect(typeNode.indexes["Rank"].n).Should(Equal(6))
+			Expect(typeNode.indexes["Color"].n).Should(Equal(2))
+
+			lo.ForEach(getTestFacts(), func(item *Chess, _ int) {
+				an.AddFact(Fact{ID: item.ID, Value: NewGVStruct(item)})
+			})
+			Expect(matchedOf(ams[0])).Should(ConsistOf(GVIdentity("table")))
+			Expect(matchedOf(ams[1])).Should(ConsistOf(GVIdentity("B1")))
+			Expect(matchedOf(ams[2])).Should(ConsistOf(GVIdentity("B2")))
+			Expect(matchedOf(ams[3])).Should(ConsistOf(GVIdentity("B3")))
+			Expect(matchedOf(ams[4])).Should(BeEmpty())
+			Expect(matchedOf(ams[5])).Should(ConsistOf(GVIdentity("B1"), GVIdentity("B3")))
+			Expect(matchedOf(ams[6])).Should(ConsistOf(GVIdentity("B2")))
+			Expect(matchedOf(ams[7])).Should(ConsistOf(GVIdentity("B1"), GVIdentity("table")))
+
+			an.DestoryAlphaMem(ams[5])
+			Expect(typeNode.indexes["Color"].n).Should(Equal(1))
+			an.DestoryAlphaMem(ams[6])
+			Expect(typeNode.indexes).ShouldNot(HaveKey("Color"))
+		})
+
+		It("keeps sharing the nodes of equality guards", func() {
+			g := Guard{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}
+			am, err := an.MakeAlphaMem(tf, []Guard{g})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(an.MakeAlphaMem(tf, []Guard{g})).Should(BeIdenticalTo(am))
+
+			sub, err := an.MakeAlphaMem(tf, []Guard{g, {AliasAttr: "Rank", Value: GVInt(3), TestOp: TestOpEqual}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sub.inputAlphaNode.Parent()).Should(BeIdenticalTo(am.inputAlphaNode))
+			Expect(am.inputAlphaNode.(*ConstantTestNode).indexes["Rank"].n).Should(Equal(1))
+
+			lo.ForEach(getTestFacts(), func(item *Chess, _ int) {
+				an.AddFact(Fact{ID: item.ID, Value: NewGVStruct(item)})
+			})
+			Expect(matchedOf(am)).Should(ConsistOf(GVIdentity("B1"), GVIdentity("B3")))
+			Expect(matchedOf(sub)).Should(ConsistOf(GVIdentity("B3")))
+		})
+
+		It("can dispatch facts to ordering guards by the values of fields", func() {
+			one, err := NewGVDecimal("1")
+			Expect(err).ShouldNot(HaveOccurred())
+			thresholds := []GValue{GVInt(-1), GVInt(0), GVUint(1), one, GVFloat(1.5), GVInt(2), GVFloat(3), GVInt(10)}
+			ops := []TestOp{TestOpLess, TestOpLessEqual, TestOpGreater, TestOpGreaterEqual}
+			ams := make([]*AlphaMem, 0, len(ops)*len(thresholds))
+			for _, op := range ops {
+				for _, v := range thresholds {
+					am, err := an.MakeAlphaMem(tf, []Guard{{AliasAttr: "Rank", Value: v, TestOp: op}})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(am.inputAlphaNode.(*ConstantTestNode).indexed).Should(BeTrue())
+					ams = append(ams, am)
+				}
+			}
+			facts := getTestFacts()
+			lo.ForEach(facts, func(item *Chess, _ int) {
+				an.AddFact(Fact{ID: item.ID, Value: NewGVStruct(item)})
+			})
+
+			// the indexed guards should match the same facts as testing them one by one
+			for _, am := range ams {
+				g := am.guards[0]
+				test, err := bindTestValue(g.TestOp, g.Value)
+				Expect(err).ShouldNot(HaveOccurred())
+				expected := make([]GVIdentity, 0, len(facts))
+				for _, item := range facts {
+					if ok, _ := test(GVInt(item.Rank)); ok {
+						expected = append(expected, item.ID)
+					}
+				}
+				Expect(matchedOf(am)).Should(ConsistOf(expected), "%s %s Rank", g.Value, g.TestOp)
+			}
+		})
+
+		It("can test ordering guards with values which cannot be sorted together", func() {
+			tf := TypeInfoOf[Room]()
+			ams := make([]*AlphaMem, 0, 3)
+			for _, v := range []GValue{GVInt(20), GVFloat(22.5), GVString("A")} {
+				am, err := an.MakeAlphaMem(TypeInfo{T: GValueTypeStruct, Fields: map[string]GValueType{"Temp": GValueTypeUnknown}},
+					[]Guard{{AliasAttr: "Temp", Value: v, TestOp: TestOpGreater}})
+				Expect(err).ShouldNot(HaveOccurred())
+				ams = append(ams, am)
+			}
+			am, err := an.MakeAlphaMem(tf, []Guard{{AliasAttr: "Temp", Value: GVInt(20), TestOp: TestOpLessEqual}})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			rooms := []*Room{{ID: "R1", Temp: 18}, {ID: "R2", Temp: 21.5}, {ID: "R3", Temp: 25}}
+			for _, r := range rooms {
+				an.AddFact(Fact{ID: r.ID, Value: NewGVStruct(r)})
+			}
+			an.AddFact(Fact{ID: "S1", Value: NewGVStruct(struct{ Temp string }{"Z"})})
+			an.AddFact(Fact{ID: "N1", Value: NewGVStruct(struct{ Temp *int }{})})
+			Expect(matchedOf(ams[0])).Should(ConsistOf(GVIdentity("R1")))
+			Expect(matchedOf(ams[1])).Should(ConsistOf(GVIdentity("R1"), GVIdentity("R2")))
+			Expect(matchedOf(ams[2])).Should(BeEmpty())
+			Expect(matchedOf(am)).Should(ConsistOf(GVIdentity("R2"), GVIdentity("R3")))
+		})
+
+		It("won't index equality guards with negative nodes", func() {
+			g := Guard{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}
+			am, err := an.MakeAlphaMem(tf, []Guard{g})
+			Expect(err).ShouldNot(HaveOccurred())
+			node := am.inputAlphaNode.(*ConstantTestNode)
+			Expect(node.indexed).Should(BeTrue())
+
+			g.Negative = true
+			negativeAm, err := an.MakeAlphaMem(tf, []Guard{g})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(node.indexed).Should(BeFalse())
+
+			lo.ForEach(getTestFacts(), func(item *Chess, _ int) {
+				an.AddFact(Fact{ID: item.ID, Value: NewGVStruct(item)})
+			})
+			Expect(matchedOf(am)).Should(ConsistOf(GVIdentity("B1"), GVIdentity("B3")))
+			Expect(matchedOf(negativeAm)).Should(ConsistOf(GVIdentity("B2"), GVIdentity("table")))
+
+			an.DestoryAlphaMem(negativeAm)
+			Expect(node.indexed).Should(BeTrue())
+			Expect(matchedOf(am)).Should(ConsistOf(GVIdentity("B1"), GVIdentity("B3")))
+		})
+	})
+
+	Describe("type dispatching", func() {
+		It("can dispatch facts to TypeTestNodes by their types", func() {
+			tfs := []TypeInfo{
+				TypeInfoOf[Chess](),
+				TypeInfoOf[Room](),
+				{T: GValueTypeStruct, Fields: map[string]GValueType{"Color": GValueTypeString}},
+				{T: GValueTypeStruct, Fields: map[string]GValueType{"Temp": GValueTypeCelsius}},
+				{T: GValueTypeRecord, Fields: map[string]GValueType{"Color": GValueTypeString}},
+				{T: GValueTypeInt},
+				{T: GValueTypeDecimal},
+				{T: GValueTypeCelsius},
+			}
+			ams := make([]*AlphaMem, 0, len(tfs))
+			for _, tf := range tfs {
+				am, err := an.MakeAlphaMem(tf, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				ams = append(ams, am)
+			}
+			root := an.AlphaRoot().(*alphaNode)
+			Expect(root.types.n).Should(Equal(len(tfs)))
+			Expect(root.unindexed).Should(BeEmpty())
+
+			one, err := NewGVDecimal("1")
+			Expect(err).ShouldNot(HaveOccurred())
+			facts := []Fact{
+				{ID: "B1", Value: NewGVStruct(&Chess{ID: "B1", Color: "red"})},
+				{ID: "R1", Value: NewGVStruct(&Room{ID: "R1", Temp: 20})},
+				{ID: "X1", Value: NewGVRecord(map[string]any{"Color": "blue"})},
+				{ID: "I1", Value: GVInt(1)},
+				{ID: "D1", Value: one},
+				{ID: "C1", Value: Celsius(1)},
+				{ID: "S1", Value: GVString("red")},
+			}
+			for _, f := range facts {
+				an.AddFact(f)
+			}
+			matched := lo.Map(ams, func(am *AlphaMem, _ int) []GVIdentity {
+				ids := make([]GVIdentity, 0, am.NItems())
+				am.ForEachItem(func(w *WME) (stop bool) {
+					ids = append(ids, w.ID)
+					return false
+				})
+				return ids
+			})
+			Expect(matched).Should(HaveExactElements(
+				ConsistOf(GVIdentity("B1")),
+				ConsistOf(GVIdentity("R1")),
+				ConsistOf(GVIdentity("B1"), GVIdentity("X1")),
+				ConsistOf(GVIdentity("R1")),
+				ConsistOf(GVIdentity("X1")),
+				ConsistOf(GVIdentity("I1")),
+				ConsistOf(GVIdentity("D1")),
+				ConsistOf(GVIdentity("C1")),
+			))
+
+			// fields of struct types are checked only once
+			tn := ams[2].inputAlphaNode.(*TypeTestNode)
+			Expect(tn.verdicts).Should(Equal(map[reflect.Type]bool{
+				reflect.TypeOf(Chess{}): true,
+				reflect.TypeOf(Room{}):  false,
+			}))
+
+			an.DestoryAlphaMem(ams[0])
+			Expect(root.types.n).Should(Equal(len(tfs) - 1))
+			Expect(root.types.byRType).ShouldNot(HaveKey(reflect.TypeOf(Chess{})))
+		})
+	})
+
+	Describe("modifying fields", func() {
+		tf := TypeInfo{T: GValueTypeStruct, Fields: map[string]GValueType{"Color": GValueTypeString}}
+
+		It("tracks the fields read by the descendants", func() {
+			redAm, err := an.MakeAlphaMem(tf, []Guard{
+				{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual},
+				{AliasAttr: "On.Color", Value: GVString("red"), TestOp: TestOpEqual, Negative: true},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			rankAm, err := an.MakeAlphaMem(tf, []Guard{{AliasAttr: "Rank", Value: GVInt(3), TestOp: TestOpGreater}})
+			Expect(err).ShouldNot(HaveOccurred())
+			root := an.AlphaRoot().(*alphaNode)
+			Expect(lo.Keys(root.reads)).Should(ConsistOf("Color", "On.Color", "Rank"))
+
+			an.DestoryAlphaMem(redAm)
+			Expect(lo.Keys(root.reads)).Should(ConsistOf("Color", "Rank"))
+			an.DestoryAlphaMem(rankAm)
+			Expect(root.reads).Should(BeEmpty())
+
+			// the TypeTestNode detached is attached to the root again
+			_, err = an.MakeAlphaMem(tf, []Guard{{AliasAttr: "LeftOf", Value: &GVNil{}, TestOp: TestOpEqual}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(lo.Keys(root.reads)).Should(ConsistOf("Color", "LeftOf"))
+		})
+
+		It("tells the fields affected by a modification", func() {
+			mask := newFieldMask("On", "Labels[\"env\"]")
+			for f, affected := range map[string]bool{
+				"On":                   true,
+				"On.Color":             true,
+				"On[0]":                true,
+				"Labels":               true,
+				"Labels[\"env\"].Name": true,
+				"Labels[\"app\"]":      false,
+				"Once":                 false,
+				"Rank":                 false,
+				FieldSelf:              true,
+				FieldID:                false,
+			} {
+				Expect(mask.affects(f)).Should(Equal(affected), f)
+			}
+			Expect(newFieldMask()).Should(BeNil())
+			Expect(newFieldMask().affects(FieldID)).Should(BeTrue())
+			Expect(newFieldMask(FieldSelf).affects("Rank")).Should(BeTrue())
+		})
+
+		It("moves facts between AlphaMems by the modified fields", func() {
+			red := Guard{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}
+			redAm, err := an.MakeAlphaMem(tf, []Guard{red})
+			Expect(err).ShouldNot(HaveOccurred())
+			red.Negative = true
+			otherAm, err := an.MakeAlphaMem(tf, []Guard{red})
+			Expect(err).ShouldNot(HaveOccurred())
+			highAm, err := an.MakeAlphaMem(tf, []Guard{{AliasAttr: "Rank", Value: GVInt(2), TestOp: TestOpLess}})
+			Expect(err).ShouldNot(HaveOccurred())
+			itemsOf := func(am *AlphaMem) []GVIdentity {
+				return lo.Map(lo.Keys(am.items), func(w *WME, _ int) GVIdentity { return w.ID })
+			}
+
+			chesses := getTestFacts()
+			for _, c := range chesses {
+				an.AddFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+			}
+			Expect(itemsOf(redAm)).Should(ConsistOf(GVIdentity("B1"), GVIdentity("B3")))
+			Expect(itemsOf(otherAm)).Should(ConsistOf(GVIdentity("B2"), GVIdentity("table")))
+			Expect(itemsOf(highAm)).Should(ConsistOf(GVIdentity("B3")))
+
+			chesses[0].Color, chesses[0].Rank = "blue", 5
+			Expect(an.ModifyFact("B1", "Color")).Should(Succeed())
+			Expect(itemsOf(redAm)).Should(ConsistOf(GVIdentity("B3")))
+			Expect(itemsOf(otherAm)).Should(ConsistOf(GVIdentity("B1"), GVIdentity("B2"), GVIdentity("table")))
+			// Rank is not re-tested since it is not modified as told
+			Expect(itemsOf(highAm)).Should(ConsistOf(GVIdentity("B3")))
+
+			Expect(an.ModifyFact("B1", "Rank")).Should(Succeed())
+			Expect(itemsOf(highAm)).Should(ConsistOf(GVIdentity("B1"), GVIdentity("B3")))
+
+			chesses[1].Color = "red"
+			Expect(an.ModifyFact("B2", "Rank")).Should(Succeed())
+			Expect(itemsOf(redAm)).Should(ConsistOf(GVIdentity("B3")))
+			Expect(an.ModifyFact("B2", "Color")).Should(Succeed())
+			Expect(itemsOf(redAm)).Should(ConsistOf(GVIdentity("B2"), GVIdentity("B3")))
+			Expect(itemsOf(otherAm)).Should(ConsistOf(GVIdentity("B1"), GVIdentity("table")))
+		})
+	})
+
 	Describe("Remove facts", func() {
 		var (
 			testChess []*Chess
@@ -458,7 +1398,8 @@ var _ = Describe("AlphaNet", func() {
 		BeforeEach(func() {
 			ams = make([]*AlphaMem, 0, len(conds))
 			for _, c := range conds {
-				am := an.MakeAlphaMem(tf, []Guard{c})
+				am, err := an.MakeAlphaMem(tf, []Guard{c})
+				Expect(err).ShouldNot(HaveOccurred())
 				Expect(am).NotTo(BeNil())
 				ams = append(ams, am)
 			}
@@ -0,0 +1,259 @@
+package rete
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/ccbhj/grete/types"
+)
+
+var chessTypeInfo = TypeInfo{
+	T: GValueTypeStruct,
+	Fields: map[string]GValueType{
+		"Color": GValueTypeString,
+		"Rank":  GValueTypeInt,
+		"On":    GValueTypeStruct,
+	},
+}
+
+func BenchmarkGVStructGetField(b *testing.B) {
+	fields := []string{"Color", "Rank", "On", "On.Color", "On.On.Rank"}
+	for _, f := range fields {
+		b.Run(f, func(b *testing.B) {
+			v := NewGVStruct(getTestFacts()[0])
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := v.GetField(f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+	// the fields are looked up by names in each call, without the cached access plans,
+	// wrapping them into GValues is not counted
+	for _, f := range fields {
+		b.Run("FieldByName/"+f, func(b *testing.B) {
+			v := getTestFacts()[0]
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, ok := fieldByName(reflect.ValueOf(v), f); !ok {
+					b.Fatal("field not found")
+				}
+			}
+		})
+	}
+}
+
+// fieldByName look up the dotted path f in v by reflect.Value.FieldByName
+func fieldByName(v reflect.Value, f string) (any, bool) {
+	for _, name := range strings.Split(f, ".") {
+		if v = reflect.Indirect(v); v.Kind() != reflect.Struct {
+			return nil, false
+		}
+		if v = v.FieldByName(name); !v.IsValid() {
+			return nil, false
+		}
+	}
+	return v.Interface(), true
+}
+
+func BenchmarkGeneratedGetField(b *testing.B) {
+	table := &Piece{ID: "table"}
+	for _, f := range []string{"color", "Rank", "On", "On.ID"} {
+		b.Run(f, func(b *testing.B) {
+			v := NewGVStruct(&Piece{ID: "P1", Color: "red", On: table, Rank: 1})
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := v.GetField(f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGVStructHasField(b *testing.B) {
+	b.Run("On.Color", func(b *testing.B) {
+		v := NewGVStruct(getTestFacts()[0])
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if !v.HasField("On.Color") {
+				b.Fatal("field not found")
+			}
+		}
+	})
+	b.Run("FieldByName/On.Color", func(b *testing.B) {
+		t := reflect.TypeOf(getTestFacts()[0])
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if !hasFieldByName(t, "On.Color") {
+				b.Fatal("field not found")
+			}
+		}
+	})
+}
+
+// hasFieldByName check the dotted path f in type t by reflect.Type.FieldByName
+func hasFieldByName(t reflect.Type, f string) bool {
+	for _, name := range strings.Split(f, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+		sf, ok := t.FieldByName(name)
+		if !ok {
+			return false
+		}
+		t = sf.Type
+	}
+	return true
+}
+
+func BenchmarkAlphaNetworkAddFact(b *testing.B) {
+	an := NewAlphaNetwork()
+	for _, color := range []string{"red", "blue", ""} {
+		_, err := an.MakeAlphaMem(chessTypeInfo, []Guard{
+			{AliasAttr: "Color", Value: GVString(color), TestOp: TestOpEqual},
+			{AliasAttr: "Rank", Value: GVInt(3), TestOp: TestOpGreater},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	facts := getTestFacts()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range facts {
+			an.AddFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+		}
+		for _, c := range facts {
+			an.RemoveFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+		}
+	}
+}
+
+func BenchmarkAlphaNetworkEqualityGuards(b *testing.B) {
+	an := NewAlphaNetwork()
+	for i := 0; i < 500; i++ {
+		_, err := an.MakeAlphaMem(chessTypeInfo, []Guard{
+			{AliasAttr: "Color", Value: GVString(fmt.Sprintf("color-%d", i)), TestOp: TestOpEqual},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	if _, err := an.MakeAlphaMem(chessTypeInfo, []Guard{{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}}); err != nil {
+		b.Fatal(err)
+	}
+	facts := getTestFacts()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range facts {
+			an.AddFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+		}
+		for _, c := range facts {
+			an.RemoveFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+		}
+	}
+}
+
+// testOpLessUnindexed is TestOpLess which is not indexed by the alpha network
+var testOpLessUnindexed = RegisterTestOp("unindexed-less", 2, TestLess)
+
+// BenchmarkAlphaNetworkThresholdGuards compare indexed threshold guards like `Rank > 42` with unindexed ones
+func BenchmarkAlphaNetworkThresholdGuards(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		for _, op := range []TestOp{TestOpLess, testOpLessUnindexed} {
+			b.Run(fmt.Sprintf("%s/%d", op, n), func(b *testing.B) {
+				an := NewAlphaNetwork()
+				for i := 0; i < n; i++ {
+					if _, err := an.MakeAlphaMem(chessTypeInfo, []Guard{{AliasAttr: "Rank", Value: GVInt(i), TestOp: op}}); err != nil {
+						b.Fatal(err)
+					}
+				}
+				facts := getTestFacts()
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					for _, c := range facts {
+						an.AddFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+					}
+					for _, c := range facts {
+						an.RemoveFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkAlphaNetworkTypeDispatch(b *testing.B) {
+	an := NewAlphaNetwork()
+	tfs := []TypeInfo{chessTypeInfo, TypeInfoOf[Chess]()}
+	for i := 0; i < 100; i++ {
+		tfs = append(tfs,
+			TypeInfo{T: GValueTypeStruct, Fields: map[string]GValueType{fmt.Sprintf("Field%d", i): GValueTypeString}},
+			TypeInfo{T: GValueTypeRecord, Fields: map[string]GValueType{fmt.Sprintf("Key%d", i): GValueTypeInt}},
+		)
+	}
+	for _, tf := range tfs {
+		if _, err := an.MakeAlphaMem(tf, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	facts := getTestFacts()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range facts {
+			an.AddFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+		}
+		for _, c := range facts {
+			an.RemoveFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+		}
+	}
+}
+
+func BenchmarkBetaNetworkAddFact(b *testing.B) {
+	bn := NewBetaNetwork(NewAlphaNetwork())
+	_, err := bn.AddProduction(Production{
+		ID: "x on y",
+		When: []AliasDeclaration{
+			{
+				Alias:  "X",
+				Type:   chessTypeInfo,
+				Guards: []Guard{{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}},
+			},
+			{Alias: "Y", Type: chessTypeInfo},
+		},
+		Match: []JoinTest{
+			{Alias: []Selector{{"X", "On"}, {"Y", FieldSelf}}, TestOp: TestOpEqual},
+			{Alias: []Selector{{"X", "Rank"}, {"Y", "Rank"}}, TestOp: TestOpLess},
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	facts := getTestFacts()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range facts {
+			bn.AddFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+		}
+		for _, c := range facts {
+			bn.RemoveFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+		}
+	}
+}
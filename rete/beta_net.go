@@ -2,6 +2,7 @@ package rete
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -43,6 +44,7 @@ type (
 		an          *AlphaNetwork
 		topNode     ReteNode
 		productions map[string]*PNode
+		onError     func(error) // called with the errors of join tests, see SetErrorHandler
 	}
 )
 
@@ -140,6 +142,95 @@ func (t *Token) destory() {
 	}
 }
 
+// alphaMem return the AlphaMem where t.wme comes from, or nil if t is a dummy token
+func (t *Token) alphaMem() *AlphaMem {
+	for node := range t.nodes {
+		if node == nil {
+			continue
+		}
+		// t is forked by a BetaMem or a PNode right below the JoinNode of the AlphaMem
+		if jn, ok := node.Parent().(*JoinNode); ok && jn.amem != nil {
+			return jn.amem
+		}
+	}
+	return nil
+}
+
+// reevaluate re-run the join tests below the node where t is forked, after the fields in mask of t.wme are changed
+func (t *Token) reevaluate(mask fieldMask) {
+	for node := range t.nodes {
+		if node == nil {
+			continue
+		}
+		if jn, ok := node.Parent().(*JoinNode); ok && jn.amem != nil {
+			reevaluateToken(t, node, t.wme, mask)
+			return
+		}
+	}
+}
+
+// reevaluateToken re-run the join tests below node for tk, which is stored in node, after the fields in mask of w are changed:
+// tk is retracted from the nodes whose tests it no longer passes, and propagated to the ones it passes now,
+// the tokens whose results of join tests are unchanged are kept, so do the tests not reading the changed fields
+func reevaluateToken(tk *Token, node ReteNode, w *WME, mask fieldMask) {
+	node.ForEachChildNonStop(func(child ReteNode) {
+		jn, ok := child.(*JoinNode)
+		if !ok {
+			return
+		}
+		jn.ForEachChildNonStop(func(mem ReteNode) {
+			if jn.amem != nil {
+				// JoinNodes of AlphaMems have no test, only the tokens forked from tk are re-evaluated
+				for _, ct := range lo.Keys(tk.children) {
+					if ct.nodes.Contains(mem) {
+						reevaluateToken(ct, mem, w, mask)
+					}
+				}
+				return
+			}
+
+			stored := tk.nodes.Contains(mem)
+			if !testsAffected(jn.tests, tk, w, mask) {
+				if stored {
+					reevaluateToken(tk, mem, w, mask)
+				}
+				return
+			}
+			passed := jn.performTests(tk, nil)
+			switch {
+			case passed && stored:
+				reevaluateToken(tk, mem, w, mask)
+			case !passed && stored:
+				retractToken(tk, mem)
+			case passed && !stored:
+				if bn, ok := mem.(BetaNode); ok {
+					bn.leftActivate(tk, nil)
+				}
+			}
+		})
+	})
+}
+
+// retractToken remove tk from node and the nodes below it, along with the tokens forked from tk below node
+func retractToken(tk *Token, node ReteNode) {
+	if tm, ok := node.(tokenMemory); ok {
+		tm.removeToken(tk)
+	}
+	tk.nodes.Del(node)
+	node.ForEachChildNonStop(func(child ReteNode) {
+		child.ForEachChildNonStop(func(mem ReteNode) {
+			for _, ct := range lo.Keys(tk.children) {
+				if ct.nodes.Contains(mem) {
+					ct.destory()
+				}
+			}
+			if tk.nodes.Contains(mem) {
+				retractToken(tk, mem)
+			}
+		})
+	})
+}
+
 type (
 	// BetaMem holds all the tokens that match all the previous conditions,
 	// in another word, all the tokens pass all the join test and usually got activated by an join node
@@ -211,7 +302,9 @@ type (
 	TestAtJoinNode struct {
 		AliasOffsets []int
 		AliasAttr    []string
+		Args         []GValue
 		TestOp       TestOp
+		Negative     bool
 	}
 
 	// JoinNode perform join tests between wmes from an alpha memory and tokens from beta mem,
@@ -227,7 +320,7 @@ type (
 )
 
 func (t TestAtJoinNode) Hash() uint64 {
-	return hashAny(t)
+	return hashValues(hashAny(t), t.Args...)
 }
 
 func (t TestAtJoinNode) String() string {
@@ -235,12 +328,18 @@ func (t TestAtJoinNode) String() string {
 	for i := range t.AliasOffsets {
 		s = append(s, fmt.Sprintf("$%d.%s", t.AliasOffsets[i], t.AliasAttr[i]))
 	}
+	for _, arg := range t.Args {
+		s = append(s, fmt.Sprintf("%v", arg))
+	}
 
+	if t.Negative {
+		return fmt.Sprintf("(not (%s %s))", t.TestOp, strings.Join(s, " "))
+	}
 	return fmt.Sprintf("(%s %s)", t.TestOp, strings.Join(s, " "))
 }
 
 func (t TestAtJoinNode) performTest(token *Token) (bool, error) {
-	args := make([]GValue, 0, len(t.AliasOffsets))
+	args := make([]GValue, 0, len(t.AliasOffsets)+len(t.Args))
 	wmes := token.toWMEs()
 	for i, offset := range t.AliasOffsets {
 		wme := wmes[offset]
@@ -251,12 +350,44 @@ func (t TestAtJoinNode) performTest(token *Token) (bool, error) {
 		}
 		args = append(args, value)
 	}
+	args = append(args, t.Args...)
+
+	ok, err := t.TestOp.ToFunc()(args...)
+	if err != nil {
+		return false, err
+	}
+	return ok != t.Negative, nil
+}
 
-	return t.TestOp.ToFunc()(args...)
+// affectedBy tell whether t reads any field in mask of w, wmes are the WMEs of the token tested
+func (t TestAtJoinNode) affectedBy(wmes []*WME, w *WME, mask fieldMask) bool {
+	for i, offset := range t.AliasOffsets {
+		if wmes[offset] == w && mask.affects(t.AliasAttr[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// testsAffected tell whether the result of tests on tk may be changed by the changes in mask of w
+func testsAffected(tests []*TestAtJoinNode, tk *Token, w *WME, mask fieldMask) bool {
+	if mask == nil {
+		return true
+	}
+	wmes := tk.toWMEs()
+	for _, t := range tests {
+		if t.affectedBy(wmes, w, mask) {
+			return true
+		}
+	}
+	return false
 }
 
 // buildJoinTestFromConds convert JoinTest into positional arguments for TestOp
 func buildJoinTestFromConds(c JoinTest, orders map[GVIdentity]int) (*TestAtJoinNode, error) {
+	if arity := c.TestOp.Arity(); arity != len(c.Alias)+len(c.Args) {
+		return nil, errors.Errorf("TestOp %s requires %d args, but got %d", c.TestOp, arity, len(c.Alias)+len(c.Args))
+	}
 	aliasOffset := make([]int, 0, 2)
 	aliastAttr := make([]string, 0, 2)
 	for _, s := range c.Alias {
@@ -271,7 +402,9 @@ func buildJoinTestFromConds(c JoinTest, orders map[GVIdentity]int) (*TestAtJoinN
 	return &TestAtJoinNode{
 		AliasOffsets: aliasOffset,
 		AliasAttr:    aliastAttr,
+		Args:         c.Args,
 		TestOp:       c.TestOp,
+		Negative:     c.Negative,
 	}, nil
 }
 
@@ -303,13 +436,15 @@ func calJoinTestSum(tests []*TestAtJoinNode) uint64 {
 	return sum
 }
 
-func performTests(tests []*TestAtJoinNode, tk *Token, wme *WME) bool {
+// performTests perform the tests of n on tk joined with wme, a test fails if it returns an error,
+// which is passed to the error handler of n.bn since checkJoinTest cannot reject all of them before facts are added,
+// like a TestOp on fields of unknown types or a user-defined TestOp
+func (n *JoinNode) performTests(tk *Token, wme *WME) bool {
 	tk = forkTokenIfWMEPresent(nil, tk, wme)
-	for _, test := range tests {
+	for _, test := range n.tests {
 		ok, err := test.performTest(tk)
 		if err != nil {
-			// TODO: handle error
-			log.L("fail to perform join test %s: %s", test, err)
+			n.bn.reportError(errors.WithMessagef(err, "fail to perform join test %s on %v", test, tk.toWMEIDs()))
 			return false
 		}
 		if !ok {
@@ -328,7 +463,7 @@ func (n *JoinNode) rightActivate(w *WME) int {
 
 	for tk := range bm.items {
 		if tk.wme == nil || // tk is a dummy token, let it pass(see papar page 25)
-			performTests(n.tests, tk, w) {
+			n.performTests(tk, w) {
 			n.ForEachChildNonStop(func(child ReteNode) {
 				if bn, ok := child.(BetaNode); ok {
 					ret += bn.leftActivate(tk, w)
@@ -347,7 +482,7 @@ func (n *JoinNode) leftActivate(tk *Token, _ *WME) int {
 	)
 
 	if am == nil {
-		if performTests(n.tests, tk, nil) {
+		if n.performTests(tk, nil) {
 			n.ForEachChildNonStop(func(child ReteNode) {
 				if bn, ok := child.(BetaNode); ok {
 					ret += bn.leftActivate(tk, nil)
@@ -363,7 +498,7 @@ func (n *JoinNode) leftActivate(tk *Token, _ *WME) int {
 
 	am.ForEachItem(func(w *WME) (stop bool) {
 		if tk.wme == nil || // tk is a dummy token, let it pass(see papar page 25)
-			performTests(n.tests, tk, w) {
+			n.performTests(tk, w) {
 			n.ForEachChildNonStop(func(child ReteNode) {
 				if bn, ok := child.(BetaNode); ok {
 					ret += bn.leftActivate(tk, w)
@@ -465,7 +600,23 @@ func NewBetaNetwork(an *AlphaNetwork) *BetaNetwork {
 	}
 }
 
-func (bn *BetaNetwork) buildOrShareNetwork(parent ReteNode, aliasDecl []AliasDeclaration, joinTests []JoinTest) ReteNode {
+// SetErrorHandler set fn to be called with the errors of join tests performed while facts are propagated,
+// the tests fail with the errors, which are logged if fn is nil
+func (bn *BetaNetwork) SetErrorHandler(fn func(error)) {
+	bn.onError = fn
+}
+
+func (bn *BetaNetwork) reportError(err error) {
+	if bn == nil || bn.onError == nil {
+		log.L("%s", err)
+		return
+	}
+	bn.onError(err)
+}
+
+// buildOrShareNetwork build or share the nodes for aliasDecl and joinTests,
+// they should be checked by checkProduction so that nothing is left when it fails
+func (bn *BetaNetwork) buildOrShareNetwork(parent ReteNode, aliasDecl []AliasDeclaration, joinTests []JoinTest) (ReteNode, error) {
 	var (
 		currentNode ReteNode
 		aliasOrders = make(map[GVIdentity]int, len(aliasDecl))
@@ -473,8 +624,14 @@ func (bn *BetaNetwork) buildOrShareNetwork(parent ReteNode, aliasDecl []AliasDec
 
 	currentNode = parent
 	for i, decl := range aliasDecl {
+		am, err := bn.an.MakeAlphaMem(decl.Type, decl.Guards)
+		if err != nil {
+			if e := (*TestOpError)(nil); errors.As(err, &e) {
+				e.Alias = decl.Alias
+			}
+			return nil, err
+		}
 		currentNode = bn.buildOrShareBetaMem(currentNode)
-		am := bn.an.MakeAlphaMem(decl.Type, decl.Guards)
 		bn.an.InitAlphaMem(am)
 		currentNode = bn.buildOrShareJoinNode(currentNode, am, nil)
 		aliasOrders[decl.Alias] = i
@@ -482,18 +639,35 @@ func (bn *BetaNetwork) buildOrShareNetwork(parent ReteNode, aliasDecl []AliasDec
 	for _, jt := range joinTests {
 		jn, err := buildJoinTestFromConds(jt, aliasOrders)
 		if err != nil {
-			// TODO: handle error here
-			panic(err)
+			return nil, err
 		}
 		currentNode = bn.buildOrShareBetaMem(currentNode)
 		currentNode = bn.buildOrShareJoinNode(currentNode, nil, []*TestAtJoinNode{jn})
 	}
 
-	return currentNode
+	return currentNode, nil
 }
 
-func isCondNeedNegativeJoin(c Guard) bool {
-	return c.Negative && c.Value.Type() == GValueTypeIdentity
+// checkProduction check the guards and join tests of a production before any node is built for it
+func checkProduction(aliasDecls []AliasDeclaration, joinTests []JoinTest) error {
+	types := make(map[GVIdentity]TypeInfo, len(aliasDecls))
+	for _, decl := range aliasDecls {
+		for _, g := range decl.Guards {
+			if err := checkGuard(decl.Type, g); err != nil {
+				if e := (*TestOpError)(nil); errors.As(err, &e) {
+					e.Alias = decl.Alias
+				}
+				return err
+			}
+		}
+		types[decl.Alias] = decl.Type
+	}
+	for _, jt := range joinTests {
+		if err := checkJoinTest(types, jt); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (bn *BetaNetwork) buildOrShareJoinNode(parent ReteNode, am *AlphaMem, tests []*TestAtJoinNode) *JoinNode {
@@ -599,10 +773,25 @@ func (bn *BetaNetwork) RemoveFact(fact Fact) {
 	bn.an.RemoveFact(fact)
 }
 
+// UpdateFact change the value of the fact with ID id, and propagate the change incrementally,
+// see AlphaNetwork.UpdateFact
+func (bn *BetaNetwork) UpdateFact(id GVIdentity, value GValue) error {
+	log.D("update fact %q", id)
+	return bn.an.UpdateFact(id, value)
+}
+
+// ModifyFact propagate the change of the fact with ID id after its fields are modified in place,
+// see AlphaNetwork.ModifyFact
+func (bn *BetaNetwork) ModifyFact(id GVIdentity, fields ...string) error {
+	log.D("modify fields %v of fact %q", fields, id)
+	return bn.an.ModifyFact(id, fields...)
+}
+
 type AliasDeclaration struct {
-	Alias  GVIdentity
-	Type   TypeInfo
-	Guards []Guard
+	Alias    GVIdentity
+	Type     TypeInfo
+	TypeName string // name of a type registered by RegisterType, Type is ignored if it is set
+	Guards   []Guard
 }
 
 type Production struct {
@@ -611,24 +800,53 @@ type Production struct {
 	Match []JoinTest
 }
 
-// AddProduction add an production and register its unique id
-func (bn *BetaNetwork) AddProduction(p Production) *PNode {
-	aliasDecls := p.When
-	if len(aliasDecls) == 0 {
-		panic("need some guards")
+// AddProduction add an production and register its unique id,
+// a *TestOpError is returned if any guard or join test of it cannot be performed on the types of its operands
+func (bn *BetaNetwork) AddProduction(p Production) (*PNode, error) {
+	if len(p.When) == 0 {
+		return nil, errors.Errorf("production %s has no alias declared", p.ID)
 	}
 
 	id := p.ID
 	if pn, in := bn.productions[id]; in {
-		return pn
+		return pn, nil
+	}
+	aliasDecls, err := resolveTypeNames(p.When)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkProduction(aliasDecls, p.Match); err != nil {
+		return nil, errors.WithMessagef(err, "invalid production %s", id)
 	}
 
-	jt := p.Match
-	currentNode := bn.buildOrShareNetwork(bn.topNode, aliasDecls, jt)
+	currentNode, err := bn.buildOrShareNetwork(bn.topNode, aliasDecls, p.Match)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid production %s", id)
+	}
 	pn := NewPNode(currentNode, aliasDecls)
 	bn.updateNewNodeWithMatchesFromAbove(pn)
 	bn.productions[id] = pn
-	return pn
+	return pn, nil
+}
+
+// resolveTypeNames fill the Type of AliasDeclarations by their TypeName
+func resolveTypeNames(decls []AliasDeclaration) ([]AliasDeclaration, error) {
+	resolved := decls
+	for i, decl := range decls {
+		if decl.TypeName == "" {
+			continue
+		}
+		ti, ok := TypeInfoByName(decl.TypeName)
+		if !ok {
+			return nil, errors.Errorf("type %s of alias %s is not registered", decl.TypeName, decl.Alias)
+		}
+		if &resolved[0] == &decls[0] {
+			// don't modify the production passed in
+			resolved = slices.Clone(decls)
+		}
+		resolved[i].Type = ti
+	}
+	return resolved, nil
 }
 
 // GetProduction query an production by its id
@@ -1,8 +1,12 @@
 package rete
 
 import (
+	"maps"
+	"reflect"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 	"github.com/samber/lo"
 
 	. "github.com/ccbhj/grete/types"
@@ -25,6 +29,33 @@ func ignoreIndex[T any](fn func(T)) func(T, int) {
 	}
 }
 
+var _ = RegisterType[Chess]("Chess")
+
+// testOpBetween test if args[0] < args[1] < args[2]
+var testOpBetween = RegisterTestOp("between", 3, func(args ...GValue) (bool, error) {
+	lower, err := TestLess(args[0], args[1])
+	if err != nil || !lower {
+		return false, err
+	}
+	return TestLess(args[1], args[2])
+})
+
+// testsCounted count the calls of the TestOps registered by countedTestOp by their names
+var testsCounted = make(map[string]int)
+
+// countedTestOp register a TestOp counting its calls in testsCounted
+func countedTestOp(name string, arity int, fn TestFunc) TestOp {
+	return RegisterTestOp(name, arity, func(args ...GValue) (bool, error) {
+		testsCounted[name]++
+		return fn(args...)
+	})
+}
+
+var (
+	testOpCountedEqual = countedTestOp("counted-equal", 2, TestEqual)
+	testOpCountedLess  = countedTestOp("counted-less", 2, TestLess)
+)
+
 var _ = Describe("BetaNet", func() {
 	var (
 		bn *BetaNetwork
@@ -81,7 +112,8 @@ var _ = Describe("BetaNet", func() {
 		var p0, p1, p2 *PNode
 
 		BeforeEach(func() {
-			p0 = bn.AddProduction(Production{
+			var err error
+			p0, err = bn.AddProduction(Production{
 				ID: "p0",
 				When: []AliasDeclaration{
 					{
@@ -114,8 +146,9 @@ var _ = Describe("BetaNet", func() {
 					},
 				},
 			})
+			Expect(err).ShouldNot(HaveOccurred())
 
-			p1 = bn.AddProduction(Production{
+			p1, err = bn.AddProduction(Production{
 				ID: "p1",
 				When: []AliasDeclaration{
 					{
@@ -147,8 +180,9 @@ var _ = Describe("BetaNet", func() {
 						TestOp: TestOpLess,
 					},
 				}})
+			Expect(err).ShouldNot(HaveOccurred())
 
-			p2 = bn.AddProduction(Production{
+			p2, err = bn.AddProduction(Production{
 				ID: "p1",
 				When: []AliasDeclaration{
 					{
@@ -181,6 +215,7 @@ var _ = Describe("BetaNet", func() {
 					},
 				},
 			})
+			Expect(err).ShouldNot(HaveOccurred())
 		})
 
 		It("can share alpha memory even though the alias is not the same", func() {
@@ -262,7 +297,8 @@ var _ = Describe("BetaNet", func() {
 				},
 				Match: []JoinTest{},
 			}
-			pNode := bn.AddProduction(p)
+			pNode, err := bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
 			Expect(pNode.AnyMatches()).To(BeFalse())
 			if Expect(pNode.Parent()).To(BeAssignableToTypeOf(&JoinNode{})) {
 				if Expect(pNode.Parent().Parent()).To(BeAssignableToTypeOf(&BetaMem{})) {
@@ -333,7 +369,8 @@ var _ = Describe("BetaNet", func() {
 					},
 				},
 			}
-			pNode := bn.AddProduction(p)
+			pNode, err := bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
 
 			Expect(pNode.AnyMatches()).To(BeFalse())
 			if Expect(pNode.Parent()).To(BeAssignableToTypeOf(&JoinNode{})) {
@@ -367,11 +404,272 @@ var _ = Describe("BetaNet", func() {
 			Expect(pNode.AnyMatches()).Should(BeFalse())
 
 			// add production back
-			pNode = bn.AddProduction(p)
+			pNode, err = bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
 			addFacts()
 			Expect(pNode.AnyMatches()).Should(BeTrue())
 		})
 
+		It("can add an production with ordering join tests", func() {
+			p := Production{
+				ID: "production with ordering join tests",
+				When: []AliasDeclaration{
+					{
+						Alias: "X",
+						Type:  tf,
+						Guards: []Guard{
+							{
+								AliasAttr: "Color",
+								Value:     GVString("red"),
+								TestOp:    TestOpEqual,
+							},
+						},
+					},
+					{
+						Alias: "Y",
+						Type:  tf,
+						Guards: []Guard{
+							{
+								AliasAttr: "Color",
+								Value:     GVString("blue"),
+								TestOp:    TestOpEqual,
+							},
+						},
+					},
+				},
+				Match: []JoinTest{
+					{
+						Alias:  []Selector{{"X", "Rank"}, {"Y", "Rank"}},
+						TestOp: TestOpGreaterEqual,
+					},
+					{
+						Alias:  []Selector{{"X", "Color"}, {"Y", "Color"}},
+						TestOp: TestOpGreater,
+					},
+				},
+			}
+			pNode, err := bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
+			addFacts()
+
+			chesses := getTestFacts()
+			Expect(pNode.Matches()).To(ConsistOf(map[GVIdentity]any{
+				"X": chesses[2], // B3
+				"Y": chesses[1], // B2
+			}))
+		})
+
+		It("can add an production with custom join tests", func() {
+			colorIs := func(alias GVIdentity, color string) AliasDeclaration {
+				return AliasDeclaration{
+					Alias:  alias,
+					Type:   tf,
+					Guards: []Guard{{AliasAttr: "Color", Value: GVString(color), TestOp: TestOpEqual}},
+				}
+			}
+			p := Production{
+				ID:   "production with custom join tests",
+				When: []AliasDeclaration{colorIs("X", "red"), colorIs("Y", "blue"), colorIs("Z", "red")},
+				Match: []JoinTest{
+					{
+						Alias:  []Selector{{"X", "Rank"}, {"Y", "Rank"}, {"Z", "Rank"}},
+						TestOp: testOpBetween,
+					},
+				},
+			}
+			pNode, err := bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
+			addFacts()
+
+			chesses := getTestFacts()
+			Expect(pNode.Matches()).To(ConsistOf(map[GVIdentity]any{
+				"X": chesses[0], // B1
+				"Y": chesses[1], // B2
+				"Z": chesses[2], // B3
+			}))
+
+			p.ID = "production with wrong number of args"
+			p.Match[0].Alias = p.Match[0].Alias[:2]
+			_, err = bn.AddProduction(p)
+			var e *TestOpError
+			Expect(errors.As(err, &e)).Should(BeTrue())
+			Expect(bn.GetProduction(p.ID)).Should(BeNil())
+		})
+
+		It("can add an production with constant args in join tests", func() {
+			p := Production{
+				ID: "production with constant args",
+				When: []AliasDeclaration{
+					{
+						Alias:  "X",
+						Type:   tf,
+						Guards: []Guard{{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}},
+					},
+					{
+						Alias:  "Y",
+						Type:   tf,
+						Guards: []Guard{{AliasAttr: "Color", Value: GVString("blue"), TestOp: TestOpEqual}},
+					},
+				},
+				Match: []JoinTest{
+					{
+						Alias:  []Selector{{"X", "Rank"}, {"Y", "Rank"}},
+						Args:   []GValue{GVInt(3)},
+						TestOp: testOpBetween,
+					},
+				},
+			}
+			pNode, err := bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
+			addFacts()
+
+			chesses := getTestFacts()
+			Expect(pNode.Matches()).To(ConsistOf(map[GVIdentity]any{
+				"X": chesses[0], // B1
+				"Y": chesses[1], // B2
+			}))
+		})
+
+		It("can add an production with join tests on collections", func() {
+			hostTf := TypeInfo{
+				T:      GValueTypeStruct,
+				Fields: map[string]GValueType{"Tags": GValueTypeList, "Labels": GValueTypeMap},
+			}
+			p := Production{
+				ID: "production with join tests on collections",
+				When: []AliasDeclaration{
+					{Alias: "X", Type: hostTf},
+					{Alias: "Y", Type: hostTf},
+				},
+				Match: []JoinTest{
+					{
+						// Y.Labels["role"] in X.Tags
+						Alias:  []Selector{{"Y", `Labels["role"]`}, {"X", "Tags"}},
+						TestOp: TestOpContains,
+					},
+					{
+						// any of X.Labels equals to any of Y.Tags
+						Alias:  []Selector{{"X", "Labels"}, {"Y", "Tags"}},
+						TestOp: AnyElementOf(AnyElementOf(TestOpEqual, 1), 0),
+					},
+				},
+			}
+			pNode, err := bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
+			hosts := []*Host{
+				{ID: "H1", Tags: []string{"web", "prod"}, Labels: map[string]string{"env": "prod"}},
+				{ID: "H2", Tags: []string{"prod"}, Labels: map[string]string{"role": "web"}},
+				{ID: "H3", Tags: []string{"dev"}, Labels: map[string]string{"role": "web"}},
+			}
+			for _, h := range hosts {
+				bn.AddFact(Fact{ID: h.ID, Value: NewGVStruct(h)})
+			}
+			Expect(pNode.Matches()).To(ConsistOf(map[GVIdentity]any{
+				"X": hosts[0],
+				"Y": hosts[1],
+			}))
+		})
+
+		It("can add an production with join tests on nested fields", func() {
+			p := Production{
+				ID: "production with join tests on nested fields",
+				When: []AliasDeclaration{
+					{Alias: "X", Type: tf},
+					{Alias: "Y", Type: tf},
+				},
+				Match: []JoinTest{
+					{
+						// nil pointers along the path are GVNil, and never equal to a chess
+						Alias:  []Selector{{"X", "On.On"}, {"Y", FieldSelf}},
+						TestOp: TestOpEqual,
+					},
+					{
+						Alias:  []Selector{{"X", "On.LeftOf.Color"}, {"X", "Color"}},
+						TestOp: TestOpEqual,
+					},
+				},
+			}
+			pNode, err := bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
+			addFacts()
+
+			chesses := getTestFacts()
+			Expect(pNode.Matches()).To(ConsistOf(map[GVIdentity]any{
+				"X": chesses[0], // B1 is on B2, which is on the table and left of B3 in red
+				"Y": chesses[3], // table
+			}))
+		})
+
+		It("can add an production with types referred by name", func() {
+			p := Production{
+				ID: "production with type names",
+				When: []AliasDeclaration{
+					{
+						Alias:    "X",
+						TypeName: "Chess",
+						Guards:   []Guard{{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}},
+					},
+					{Alias: "Y", TypeName: "Chess"},
+				},
+				Match: []JoinTest{
+					{Alias: []Selector{{"X", "On"}, {"Y", FieldSelf}}, TestOp: TestOpEqual},
+				},
+			}
+			pNode, err := bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(p.When[0].Type).Should(BeZero())
+			addFacts()
+			bn.AddFact(Fact{ID: "P1", Value: NewGVStruct(&Piece{ID: "P1", Color: "red"})})
+
+			chesses := getTestFacts()
+			Expect(pNode.Matches()).To(ConsistOf(
+				map[GVIdentity]any{"X": chesses[0], "Y": chesses[1]}, // B1 on B2
+				map[GVIdentity]any{"X": chesses[2], "Y": chesses[3]}, // B3 on table
+			))
+
+			p.ID = "production with unknown type"
+			p.When[1].TypeName = "Block"
+			_, err = bn.AddProduction(p)
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("can add an production matching both structs and records", func() {
+			p := Production{
+				ID: "production with records",
+				When: []AliasDeclaration{
+					{
+						Alias:  "X",
+						Type:   tf,
+						Guards: []Guard{{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}},
+					},
+					{Alias: "Y", Type: tf},
+				},
+				Match: []JoinTest{
+					{Alias: []Selector{{"X", "On"}, {"Y", FieldSelf}}, TestOp: TestOpEqual},
+				},
+			}
+			pNode, err := bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
+			addFacts()
+
+			chesses := getTestFacts()
+			r1, err := NewGVRecordFromJSON([]byte(`{"ID": "R1", "Color": "red", "On": null}`))
+			Expect(err).ShouldNot(HaveOccurred())
+			r2 := NewGVRecord(map[string]any{"ID": "R2", "Color": "red", "On": testFacts[1]})
+			r3 := NewGVRecord(map[string]any{"ID": "R3", "Color": "red"}) // no On
+			for _, r := range []*GVRecord{r1, r2, r3} {
+				bn.AddFact(Fact{ID: GVIdentity(r.M["ID"].(string)), Value: r})
+			}
+			r4 := NewGVRecord(map[string]any{"ID": "R4", "Color": "blue", "On": r1.M})
+			bn.AddFact(Fact{ID: "R4", Value: r4}) // R4 is on an object like R1, but not the fact R1
+
+			Expect(pNode.Matches()).To(ConsistOf(
+				map[GVIdentity]any{"X": chesses[0], "Y": chesses[1]}, // B1 on B2
+				map[GVIdentity]any{"X": chesses[2], "Y": chesses[3]}, // B3 on table
+				map[GVIdentity]any{"X": r2.M, "Y": chesses[1]},       // R2 on B2
+			))
+		})
+
 		It("can add an production on the fly", func() {
 			const joinTestsPrd = "production with join tests"
 			p := Production{
@@ -407,12 +705,13 @@ var _ = Describe("BetaNet", func() {
 					},
 				},
 			}
-			pNode := bn.AddProduction(p)
+			pNode, err := bn.AddProduction(p)
+			Expect(err).ShouldNot(HaveOccurred())
 			addFacts()
 			Expect(pNode.AnyMatches()).To(BeTrue())
 
 			// add another production
-			tablepNode := bn.AddProduction(Production{
+			tablepNode, err := bn.AddProduction(Production{
 				ID: "match table",
 				When: []AliasDeclaration{
 					{
@@ -428,9 +727,314 @@ var _ = Describe("BetaNet", func() {
 					},
 				},
 			})
+			Expect(err).ShouldNot(HaveOccurred())
 			Expect(tablepNode.AnyMatches()).Should(BeTrue())
 		})
 
 	})
 
+	Describe("type checking", func() {
+		chess := TypeInfoOf[Chess]()
+		DescribeTable("rejecting productions with incompatible operands",
+			func(p Production, alias GVIdentity, field GVString, op TestOp) {
+				_, err := bn.AddProduction(p)
+				var e *TestOpError
+				Expect(errors.As(err, &e)).Should(BeTrue())
+				Expect(e.Alias).Should(Equal(alias))
+				Expect(e.Field).Should(Equal(field))
+				Expect(e.TestOp).Should(Equal(op))
+				Expect(err.Error()).Should(ContainSubstring(string(field)))
+				Expect(bn.GetProduction(p.ID)).Should(BeNil())
+				Expect(an.cond2AlphaMem).Should(BeEmpty())
+			},
+			Entry("string == int", Production{ID: "P", When: []AliasDeclaration{
+				{Alias: "X", Type: chess, Guards: []Guard{{AliasAttr: "Color", Value: GVInt(1), TestOp: TestOpEqual}}},
+			}}, GVIdentity("X"), GVString("Color"), TestOpEqual),
+			Entry("string == int on a type with VT only", Production{ID: "P", When: []AliasDeclaration{
+				{Alias: "X", Type: TypeInfo{T: GValueTypeStruct, VT: reflect.TypeOf(Chess{})},
+					Guards: []Guard{{AliasAttr: "Color", Value: GVInt(1), TestOp: TestOpEqual}}},
+			}}, GVIdentity("X"), GVString("Color"), TestOpEqual),
+			Entry("string < int", Production{ID: "P", When: []AliasDeclaration{
+				{Alias: "X", Type: chess},
+				{Alias: "Y", Type: chess, Guards: []Guard{{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}, {AliasAttr: "Rank", Value: GVString("1"), TestOp: TestOpLess}}},
+			}}, GVIdentity("Y"), GVString("Rank"), TestOpLess),
+			Entry("has prefix of int", Production{ID: "P", When: []AliasDeclaration{
+				{Alias: "X", Type: chess, Guards: []Guard{{AliasAttr: "Rank", Value: GVString("1"), TestOp: TestOpHasPrefix}}},
+			}}, GVIdentity("X"), GVString("Rank"), TestOpHasPrefix),
+			Entry("in a non-set", Production{ID: "P", When: []AliasDeclaration{
+				{Alias: "X", Type: chess, Guards: []Guard{{AliasAttr: "Rank", Value: GVInt(1), TestOp: TestOpIn}}},
+			}}, GVIdentity("X"), GVString("Rank"), TestOpIn),
+			Entry("alias as value", Production{ID: "P", When: []AliasDeclaration{
+				{Alias: "X", Type: chess, Guards: []Guard{{AliasAttr: "On", Value: GVIdentity("Y"), TestOp: TestOpEqual}}},
+			}}, GVIdentity("X"), GVString("On"), TestOpEqual),
+			Entry("joining string with int", Production{ID: "P",
+				When:  []AliasDeclaration{{Alias: "X", Type: chess}, {Alias: "Y", Type: chess}},
+				Match: []JoinTest{{Alias: []Selector{{"X", "Color"}, {"Y", "Rank"}}, TestOp: TestOpGreater}},
+			}, GVIdentity("X"), GVString("Color"), TestOpGreater),
+			Entry("joining unguarded alias", Production{ID: "P",
+				When:  []AliasDeclaration{{Alias: "X", Type: chess}},
+				Match: []JoinTest{{Alias: []Selector{{"X", "Rank"}, {"Y", "Rank"}}, TestOp: TestOpEqual}},
+			}, GVIdentity("Y"), GVString("Rank"), TestOpEqual),
+			Entry("within of strings", Production{ID: "P",
+				When:  []AliasDeclaration{{Alias: "X", Type: chess}, {Alias: "Y", Type: chess}},
+				Match: []JoinTest{{Alias: []Selector{{"X", "Color"}, {"Y", "Color"}}, Args: []GValue{GVDuration(1)}, TestOp: TestOpWithinAfter}},
+			}, GVIdentity("X"), GVString("Color"), TestOpWithinAfter),
+		)
+
+		It("accepts operands of compatible types", func() {
+			_, err := bn.AddProduction(Production{ID: "P",
+				When: []AliasDeclaration{
+					{Alias: "X", Type: chess, Guards: []Guard{
+						{AliasAttr: "Rank", Value: GVFloat(1.5), TestOp: TestOpLess},
+						{AliasAttr: "Color", Value: &GVNil{}, TestOp: TestOpNotEqual},
+						{AliasAttr: "Color", Value: NewGVSet(GVString("red")), TestOp: TestOpIn},
+					}},
+					{Alias: "Y", Type: TypeInfo{T: GValueTypeStruct}, Guards: []Guard{
+						{AliasAttr: "Anything", Value: GVInt(1), TestOp: TestOpEqual},
+					}},
+				},
+				Match: []JoinTest{
+					{Alias: []Selector{{"X", "Rank"}, {"Y", "Rank"}}, TestOp: TestOpLess},
+					{Alias: []Selector{{"X", "Rank"}, {"Y", "Rank"}}, TestOp: testOpMultipleOf},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("reports the errors of join tests on fields of unknown types", func() {
+			var errs []error
+			bn.SetErrorHandler(func(err error) { errs = append(errs, err) })
+			pNode, err := bn.AddProduction(Production{ID: "P",
+				When: []AliasDeclaration{
+					{Alias: "X", Type: chess, Guards: []Guard{{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}}},
+					{Alias: "Y", Type: TypeInfo{T: GValueTypeRecord}},
+				},
+				Match: []JoinTest{{Alias: []Selector{{"X", "Rank"}, {"Y", "Rank"}}, TestOp: TestOpLess}},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			addFacts()
+			bn.AddFact(Fact{ID: "R1", Value: NewGVRecord(map[string]any{"Rank": "high"})})
+			bn.AddFact(Fact{ID: "R2", Value: NewGVRecord(map[string]any{"Rank": 10})})
+
+			Expect(pNode.AnyMatches()).Should(BeTrue())
+			Expect(errs).ShouldNot(BeEmpty())
+			Expect(errs[0].Error()).Should(ContainSubstring("R1"))
+		})
+	})
+
+	Describe("updating facts", func() {
+		chess := TypeInfoOf[Chess]()
+		production := Production{
+			ID: "red chess on a higher one",
+			When: []AliasDeclaration{
+				{Alias: "X", Type: chess, Guards: []Guard{{AliasAttr: "Color", Value: GVString("red"), TestOp: TestOpEqual}}},
+				{Alias: "Y", Type: chess},
+			},
+			Match: []JoinTest{
+				{Alias: []Selector{{"X", "On"}, {"Y", FieldSelf}}, TestOp: TestOpEqual},
+				{Alias: []Selector{{"X", "Rank"}, {"Y", "Rank"}}, TestOp: TestOpLess},
+			},
+		}
+		// matchesOf return the matches of production in a new network with the current facts
+		matchesOf := func(chesses []*Chess) []map[GVIdentity]any {
+			bn := NewBetaNetwork(NewAlphaNetwork())
+			pNode, err := bn.AddProduction(production)
+			Expect(err).ShouldNot(HaveOccurred())
+			for _, c := range chesses {
+				bn.AddFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+			}
+			matches, err := pNode.Matches()
+			Expect(err).ShouldNot(HaveOccurred())
+			return matches
+		}
+		var (
+			chesses []*Chess
+			pNode   *PNode
+		)
+
+		BeforeEach(func() {
+			var err error
+			pNode, err = bn.AddProduction(production)
+			Expect(err).ShouldNot(HaveOccurred())
+			chesses = getTestFacts()
+			for _, c := range chesses {
+				bn.AddFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+			}
+			Expect(pNode.Matches()).Should(ConsistOf(
+				map[GVIdentity]any{"X": chesses[0], "Y": chesses[1]},
+			))
+		})
+
+		It("can update the values of facts", func() {
+			b2 := *chesses[1]
+			b2.Color, b2.Rank = "red", 5
+			Expect(bn.UpdateFact("B2", NewGVStruct(&b2))).Should(Succeed())
+			chesses[1] = &b2
+			// B1 is still on the old B2
+			Expect(pNode.AnyMatches()).Should(BeFalse())
+
+			b1 := *chesses[0]
+			b1.On = &b2
+			Expect(bn.UpdateFact("B1", NewGVStruct(&b1))).Should(Succeed())
+			chesses[0] = &b1
+			Expect(pNode.Matches()).Should(ConsistOf(
+				map[GVIdentity]any{"X": &b1, "Y": &b2},
+			))
+			Expect(pNode.Matches()).Should(ConsistOf(matchesOf(chesses)))
+		})
+
+		It("can re-evaluate facts modified in place", func() {
+			tokens := lo.Keys(pNode.items)
+			chesses[1].Rank = 1
+			Expect(bn.ModifyFact("B2")).Should(Succeed())
+			Expect(pNode.AnyMatches()).Should(BeFalse())
+
+			chesses[1].Rank = 5
+			Expect(bn.ModifyFact("B2")).Should(Succeed())
+			Expect(pNode.Matches()).Should(ConsistOf(
+				map[GVIdentity]any{"X": chesses[0], "Y": chesses[1]},
+			))
+
+			// tokens whose join results are unchanged are kept
+			tokens = lo.Keys(pNode.items)
+			chesses[1].Color = "green"
+			Expect(bn.ModifyFact("B2")).Should(Succeed())
+			Expect(lo.Keys(pNode.items)).Should(ConsistOf(tokens))
+
+			chesses[3].Rank = 10
+			chesses[0].Rank = 4
+			chesses[2].On = chesses[0]
+			Expect(bn.ModifyFact("table")).Should(Succeed())
+			Expect(bn.ModifyFact("B1")).Should(Succeed())
+			Expect(bn.ModifyFact("B3")).Should(Succeed())
+			Expect(pNode.Matches()).Should(ConsistOf(
+				map[GVIdentity]any{"X": chesses[0], "Y": chesses[1]},
+				map[GVIdentity]any{"X": chesses[2], "Y": chesses[0]},
+			))
+			Expect(pNode.Matches()).Should(ConsistOf(matchesOf(chesses)))
+		})
+
+		It("keeps the same matches as adding the facts again", func() {
+			for i := 0; i < 100; i++ {
+				c := chesses[i%len(chesses)]
+				c.Rank = (c.Rank*7 + i) % 5
+				c.On = chesses[(i*3)%len(chesses)]
+				if i%4 == 0 {
+					c.Color = lo.Ternary(c.Color == "red", "blue", "red")
+				}
+				Expect(bn.ModifyFact(c.ID)).Should(Succeed())
+				Expect(pNode.Matches()).Should(ConsistOf(matchesOf(chesses)), "step %d", i)
+			}
+		})
+
+		It("keeps the same matches when the modified fields are given", func() {
+			for i := 0; i < 100; i++ {
+				c := chesses[i%len(chesses)]
+				var fields []string
+				switch i % 3 {
+				case 0:
+					c.Rank = (c.Rank*7 + i) % 5
+					fields = []string{"Rank"}
+				case 1:
+					c.On = chesses[(i*3)%len(chesses)]
+					fields = []string{"On"}
+				case 2:
+					c.Color = lo.Ternary(c.Color == "red", "blue", "red")
+					c.Rank = (c.Rank + i) % 5
+					fields = []string{"Color", "Rank"}
+				}
+				Expect(bn.ModifyFact(c.ID, fields...)).Should(Succeed())
+				Expect(pNode.Matches()).Should(ConsistOf(matchesOf(chesses)), "step %d", i)
+			}
+		})
+
+		It("only re-runs the tests reading the modified fields", func() {
+			bn := NewBetaNetwork(NewAlphaNetwork())
+			pNode, err := bn.AddProduction(Production{
+				ID: "counted red chess on a higher one",
+				When: []AliasDeclaration{
+					{Alias: "X", Type: chess, Guards: []Guard{{AliasAttr: "Color", Value: GVString("red"), TestOp: testOpCountedEqual}}},
+					{Alias: "Y", Type: chess},
+				},
+				Match: []JoinTest{
+					{Alias: []Selector{{"X", "On"}, {"Y", FieldSelf}}, TestOp: TestOpEqual},
+					{Alias: []Selector{{"X", "Rank"}, {"Y", "Rank"}}, TestOp: testOpCountedLess},
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			for _, c := range chesses {
+				bn.AddFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+			}
+			Expect(pNode.Matches()).Should(ConsistOf(
+				map[GVIdentity]any{"X": chesses[0], "Y": chesses[1]},
+			))
+			modify := func(id GVIdentity, fields ...string) map[string]int {
+				clear(testsCounted)
+				Expect(bn.ModifyFact(id, fields...)).Should(Succeed())
+				return maps.Clone(testsCounted)
+			}
+
+			chesses[1].Rank = 0
+			Expect(modify("B2", "Rank")).Should(Equal(map[string]int{"counted-less": 1}))
+			Expect(pNode.AnyMatches()).Should(BeFalse())
+
+			chesses[1].Color = "green"
+			Expect(modify("B2", "Color")).Should(Equal(map[string]int{"counted-equal": 1}))
+			chesses[1].LeftOf = nil
+			Expect(modify("B2", "LeftOf")).Should(BeEmpty())
+			Expect(modify("B2", "LeftOf.Rank")).Should(BeEmpty())
+
+			chesses[1].Rank = 5
+			Expect(modify("B2", "ID", "Rank")).Should(Equal(map[string]int{"counted-less": 1}))
+			Expect(pNode.Matches()).Should(ConsistOf(
+				map[GVIdentity]any{"X": chesses[0], "Y": chesses[1]},
+			))
+			Expect(modify("B2")).Should(Equal(map[string]int{"counted-equal": 1, "counted-less": 1}))
+			Expect(modify("B2", FieldSelf)).Should(Equal(map[string]int{"counted-equal": 1, "counted-less": 1}))
+		})
+
+		It("cannot update facts that are missing or ambiguous", func() {
+			Expect(errors.Is(bn.UpdateFact("B4", GVInt(1)), ErrFactNotFound)).Should(BeTrue())
+			Expect(errors.Is(bn.ModifyFact("B4"), ErrFactNotFound)).Should(BeTrue())
+
+			bn.AddFact(Fact{ID: "B1", Value: GVString("B1")})
+			Expect(bn.UpdateFact("B1", GVInt(1))).ShouldNot(Succeed())
+			bn.RemoveFact(Fact{ID: "B1", Value: GVString("B1")})
+			Expect(bn.UpdateFact("B1", GVInt(1))).Should(Succeed())
+			Expect(pNode.AnyMatches()).Should(BeFalse())
+		})
+
+		It("merges the updated fact into an equal one", func() {
+			bn.AddFact(Fact{ID: "X", Value: GVInt(1)})
+			bn.AddFact(Fact{ID: "Y", Value: GVInt(2)})
+			Expect(bn.UpdateFact("Y", GVInt(1))).Should(Succeed())
+			Expect(an.workingMems).Should(HaveKey(Fact{ID: "Y", Value: GVInt(1)}.Hash()))
+			Expect(an.workingMems).ShouldNot(HaveKey(Fact{ID: "Y", Value: GVInt(2)}.Hash()))
+
+			bn.AddFact(Fact{ID: "Y", Value: GVInt(2)})
+			// both Y=1 and Y=2 are found
+			Expect(bn.UpdateFact("Y", GVInt(1))).ShouldNot(Succeed())
+		})
+
+		It("does not merge the updated fact into an unequal one with the same hash", func() {
+			am, err := an.MakeAlphaMem(TypeInfoOf[Badge](), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			bn.AddFact(Fact{ID: "G", Value: NewGVStruct(&Badge{Name: "gold"})})
+			bn.AddFact(Fact{ID: "G", Value: NewGVStruct(&Badge{Name: "silver"})})
+			Expect(am.NItems()).Should(Equal(2))
+			// all the badges have the same hash
+			ws := an.workingMems[Fact{ID: "G", Value: NewGVStruct(&Badge{})}.Hash()]
+			Expect(ws).Should(HaveLen(2))
+
+			// facts with the same ID are ambiguous to UpdateFact, so update the WME directly
+			w, other := ws[0], ws[1]
+			an.updateWME(w, NewGVStruct(&Badge{Name: "bronze"}), nil)
+			Expect(am.NItems()).Should(Equal(2))
+			Expect(am.hasWME(w)).Should(BeTrue())
+
+			an.updateWME(w, other.Value, nil)
+			Expect(am.NItems()).Should(Equal(1))
+			Expect(am.hasWME(w)).Should(BeFalse())
+		})
+	})
 })
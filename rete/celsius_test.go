@@ -0,0 +1,152 @@
+package rete
+
+import (
+	"math"
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+
+	. "github.com/ccbhj/grete/types"
+)
+
+// Celsius is a user-defined GValue which can be compared with numbers
+type Celsius float64
+
+var GValueTypeCelsius = RegisterGValueType[Celsius]("Celsius")
+
+var _ Comparable = Celsius(0)
+
+func (Celsius) Type() GValueType      { return GValueTypeCelsius }
+func (c Celsius) Hash() uint64        { return math.Float64bits(float64(c)) }
+func (c Celsius) RType() reflect.Type { return reflect.TypeOf(c) }
+func (c Celsius) ToGoValue() any      { return c }
+
+func (c Celsius) GetField(f string) (GValue, any, error) {
+	switch f {
+	case FieldSelf:
+		return c, c, nil
+	case "Fahrenheit":
+		return GVFloat(c*9/5 + 32), float64(c*9/5 + 32), nil
+	}
+	return nil, nil, ErrFieldNotFound
+}
+
+func (c Celsius) HasField(f string) bool {
+	return f == FieldSelf || f == "Fahrenheit"
+}
+
+func (c Celsius) Equal(w GValue) bool {
+	ret, err := c.Compare(w)
+	return err == nil && ret == 0
+}
+
+func (c Celsius) Compare(w GValue) (int, error) {
+	switch w := w.(type) {
+	case Celsius:
+		return cmpFloat(float64(c), float64(w)), nil
+	case GVInt:
+		return cmpFloat(float64(c), float64(w)), nil
+	case GVFloat:
+		return cmpFloat(float64(c), float64(w)), nil
+	}
+	return 0, errors.Errorf("cannot compare Celsius with %s", w.Type())
+}
+
+func cmpFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+type Room struct {
+	ID   GVIdentity
+	Temp Celsius
+	Max  *Celsius
+}
+
+var _ = Describe("user-defined GValue", func() {
+	It("can be registered only once", func() {
+		Expect(GValueTypeCelsius.IsCustom()).Should(BeTrue())
+		Expect(GValueTypeCelsius.String()).Should(Equal("Celsius"))
+		Expect(GValueTypeCelsius.RType()).Should(Equal(reflect.TypeOf(Celsius(0))))
+		Expect(func() { RegisterGValueType[Celsius]("Temperature") }).Should(Panic())
+		Expect(func() { RegisterGValueType[*Celsius]("Celsius") }).Should(Panic())
+		Expect(func() { RegisterGValueType[*Celsius]("Int") }).Should(Panic())
+		Expect(func() { RegisterGValueType[GVInt]("Integer") }).Should(Panic())
+	})
+
+	It("can be accessed as fields of structs", func() {
+		max := Celsius(30)
+		room := NewGVStruct(&Room{ID: "R1", Temp: 21.5, Max: &max})
+		v, _, err := room.GetField("Temp")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(v).Should(Equal(Celsius(21.5)))
+		v, _, err = room.GetField("Max")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(v).Should(Equal(Celsius(30)))
+		v, _, err = NewGVStruct(&Room{}).GetField("Max")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(v).Should(Equal(&GVNil{}))
+
+		Expect(TypeInfoOf[Room]().Fields).Should(Equal(map[string]GValueType{
+			"ID":   GValueTypeIdentity,
+			"Temp": GValueTypeCelsius,
+			"Max":  GValueTypeCelsius,
+		}))
+		Expect(TypeInfoOf[Celsius]()).Should(Equal(TypeInfo{T: GValueTypeCelsius}))
+
+		n := NewTypeTestNode(nil, TypeInfo{T: GValueTypeStruct, Fields: map[string]GValueType{
+			"Temp": GValueTypeCelsius,
+			"Max":  GValueTypeCelsius,
+		}})
+		Expect(n.PerformTest(&WME{ID: "R1", Value: room})).Should(BeTrue())
+		Expect(n.PerformTest(&WME{ID: "X", Value: NewGVStruct(struct{ Temp, Max float64 }{})})).Should(BeFalse())
+	})
+
+	It("can be tested by its type", func() {
+		n := NewTypeTestNode(nil, TypeInfo{T: GValueTypeCelsius})
+		Expect(n.PerformTest(&WME{ID: "X", Value: Celsius(1)})).Should(BeTrue())
+		Expect(n.PerformTest(&WME{ID: "X", Value: GVFloat(1)})).Should(BeFalse())
+
+		w := &WME{ID: "X", Value: Celsius(100)}
+		Expect(w.HasAttr("Fahrenheit")).Should(BeTrue())
+		Expect(w.GetAttrValue("Fahrenheit")).Should(Equal(GVFloat(212)))
+	})
+
+	It("can be matched by guards and join tests", func() {
+		an := NewAlphaNetwork()
+		bn := NewBetaNetwork(an)
+		tf := TypeInfoOf[Room]()
+		pNode, err := bn.AddProduction(Production{
+			ID: "rooms hotter than others",
+			When: []AliasDeclaration{
+				{
+					Alias:  "X",
+					Type:   tf,
+					Guards: []Guard{{AliasAttr: "Temp", Value: GVInt(20), TestOp: TestOpLess}},
+				},
+				{Alias: "Y", Type: tf},
+			},
+			Match: []JoinTest{
+				{Alias: []Selector{{"Y", "Temp"}, {"X", "Temp"}}, TestOp: TestOpLess},
+			},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		rooms := []*Room{{ID: "R1", Temp: 18}, {ID: "R2", Temp: 21.5}, {ID: "R3", Temp: 25}}
+		for _, r := range rooms {
+			bn.AddFact(Fact{ID: r.ID, Value: NewGVStruct(r)})
+		}
+		Expect(pNode.Matches()).To(ConsistOf(
+			map[GVIdentity]any{"X": rooms[1], "Y": rooms[0]},
+			map[GVIdentity]any{"X": rooms[2], "Y": rooms[0]},
+			map[GVIdentity]any{"X": rooms[2], "Y": rooms[1]},
+		))
+	})
+})
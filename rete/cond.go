@@ -1,6 +1,15 @@
 package rete
 
 import (
+	"cmp"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
 	"github.com/pkg/errors"
 
 	. "github.com/ccbhj/grete/types"
@@ -12,7 +21,9 @@ type (
 		AliasAttr GVString
 	}
 
-	// Guard define constant test on value
+	// Guard define constant test on value,
+	// the test is performed as TestOp(Value, value of AliasAttr), or TestOp(value of AliasAttr) if TestOp is unary,
+	// and Value is ignored then
 	Guard struct {
 		AliasAttr GVString
 		Value     GValue // should never be GVIdentity
@@ -20,9 +31,11 @@ type (
 		TestOp    TestOp
 	}
 
-	// JoinTest define join tests between two or more than two values
+	// JoinTest define join tests between two or more than two values,
+	// the test is performed as TestOp(values of Alias..., Args...)
 	JoinTest struct {
 		Alias    []Selector
+		Args     []GValue // constant args passed after the values of Alias, like the offset of time
 		TestOp   TestOp
 		Negative bool
 	}
@@ -31,21 +44,21 @@ type (
 /*
  * Some constants to generate hash for Condition
  *
- * negative?  reserved          test_op_type(8)      name/attr/value hash(32)
+ * negative?  reserved      test_op_type(16)       name/attr/value hash(32)
  *    ^          ^                  ^                     ^
  *    |          |                  |                     |
- *   +-+------------------------+-------+------------------......--------------+
- * 63 62                     39     32 31                                    0
+ *   +-+------------------+-------------+------------------......--------------+
+ * 63 62               47            32 31                                    0
  */
 const (
 	condTestOpTypeOffset uint64 = 32
-	condTestOpTypeMask   uint64 = ((1 << 8) - 1) << condTestOpTypeOffset
+	condTestOpTypeMask   uint64 = ((1 << 16) - 1) << condTestOpTypeOffset
 	condTestNegativeFlag uint64 = 1 << 63
 )
 
 func (c Guard) Hash() uint64 {
 	var x uint64
-	x = mix64(c.Value.Hash(), c.AliasAttr.Hash())
+	x = mix64(hashValues(0, c.Value), c.AliasAttr.Hash())
 	x = uint64(mix32(uint32(x), uint32(x>>32)))
 	ret := ((uint64(c.TestOp) << condTestOpTypeOffset) & condTestOpTypeMask) | x
 	if c.Negative {
@@ -59,6 +72,9 @@ type Fact struct {
 	Value GValue
 }
 
+// ErrFactNotFound is returned when updating a fact which has not been added
+var ErrFactNotFound = errors.New("fact not found")
+
 func (f Fact) WMEFromFact() *WME {
 	return NewWME(f.ID, f.Value)
 }
@@ -67,14 +83,24 @@ func (f Fact) Hash() uint64 {
 	return mix64(f.ID.Hash(), f.Value.Hash())
 }
 
+// Equal tell whether f and g are the same fact, facts with the same hash are not always equal,
+// like structs compared by StructEqualityMethod without a Hash method
+func (f Fact) Equal(g Fact) bool {
+	if f.ID != g.ID || f.Value == nil || g.Value == nil {
+		return f.ID == g.ID && f.Value == g.Value
+	}
+	return f.Value.Equal(g.Value)
+}
+
 func (f Fact) GetValue(field string) (GValue, error) {
 	if field == FieldSelf {
 		return f.Value, nil
 	}
-	if f.Value.Type() != GValueTypeStruct {
+	v, ok := f.Value.(FieldAccessor)
+	if !ok {
 		return nil, errors.Errorf("cannot get field %s from %s", field, f.Value.Type())
 	}
-	ret, _, err := f.Value.(*GVStruct).GetField(field)
+	ret, _, err := v.GetField(field)
 	return ret, err
 }
 
@@ -82,10 +108,11 @@ func (f Fact) HasField(field string) bool {
 	if field == FieldSelf {
 		return true
 	}
-	if f.Value.Type() != GValueTypeStruct {
+	v, ok := f.Value.(FieldAccessor)
+	if !ok {
 		panic(errors.Errorf("cannot get field %s from %s", field, f.Value.Type()))
 	}
-	return f.Value.(*GVStruct).HasField(field)
+	return v.HasField(field)
 }
 
 type TestOp int
@@ -96,72 +123,759 @@ type TestFunc func(...GValue) (bool, error)
 const (
 	TestOpEqual TestOp = iota
 	TestOpLess
+	TestOpNotEqual
+	TestOpGreater
+	TestOpLessEqual
+	TestOpGreaterEqual
+
+	// string matching, args[0] is the pattern and args[1] is the string to be tested,
+	// so that a Guard use its Value as the pattern
+	TestOpContains
+	TestOpHasPrefix
+	TestOpHasSuffix
+	TestOpEqualFold
+	TestOpMatch
 
-	NTestOp
+	// set membership, args[0] is a *GVSet and args[1] is the value to be looked up
+	TestOpIn
+	TestOpNotIn
+
+	// temporal tests between two times, args[2] is the max duration between args[0] and args[1]
+	TestOpWithinAfter
+	TestOpWithinBefore
+
+	// unary tests on whether args[0] is nil, like a nil pointer or a null in a record
+	TestOpIsNil
+	TestOpNotNil
+
+	NTestOp // number of builtin TestOps, TestOps registered by RegisterTestOp are after it
 )
 
 func (t TestOp) String() string {
-	switch t {
-	case TestOpEqual:
-		return "eq"
-	case TestOpLess:
-		return "less"
+	if e, ok := lookupTestOp(t); ok {
+		return e.name
 	}
 	return "unknown"
 }
 
-var testOp2Func = [NTestOp]TestFunc{
-	TestOpEqual: TestEqual,
-	TestOpLess:  TestLess,
+// Arity return how many arguments should be passed to the TestOp, or -1 if t is not registered
+func (t TestOp) Arity() int {
+	if e, ok := lookupTestOp(t); ok {
+		return e.arity
+	}
+	return -1
 }
 
 func (t TestOp) ToFunc() TestFunc {
-	return testOp2Func[t]
+	if e, ok := lookupTestOp(t); ok {
+		return e.fn
+	}
+	return func(...GValue) (bool, error) {
+		return false, errors.Errorf("unknown TestOp %d", t)
+	}
+}
+
+// maxTestOp is the max number of TestOps limited by the bits reserved for TestOp in the hash of a Guard
+const maxTestOp = 1 << 16
+
+type testOpEntry struct {
+	name  string
+	arity int
+	fn    TestFunc
+}
+
+var (
+	testOpMu sync.Mutex // held when registering a TestOp
+	// testOpTab is replaced instead of modified in RegisterTestOp so that it can be read without locking
+	testOpTab = newBuiltinTestOpTab()
+)
+
+func newBuiltinTestOpTab() *atomic.Pointer[[]testOpEntry] {
+	tab := []testOpEntry{
+		TestOpEqual:        {"eq", 2, TestEqual},
+		TestOpLess:         {"less", 2, TestLess},
+		TestOpNotEqual:     {"not-eq", 2, TestNotEqual},
+		TestOpGreater:      {"greater", 2, TestGreater},
+		TestOpLessEqual:    {"less-eq", 2, TestLessEqual},
+		TestOpGreaterEqual: {"greater-eq", 2, TestGreaterEqual},
+		TestOpContains:     {"contains", 2, TestContains},
+		TestOpHasPrefix:    {"has-prefix", 2, TestHasPrefix},
+		TestOpHasSuffix:    {"has-suffix", 2, TestHasSuffix},
+		TestOpEqualFold:    {"eq-fold", 2, TestEqualFold},
+		TestOpMatch:        {"match", 2, TestMatch},
+		TestOpIn:           {"in", 2, TestIn},
+		TestOpNotIn:        {"not-in", 2, TestNotIn},
+		TestOpWithinAfter:  {"within-after", 3, TestWithinAfter},
+		TestOpWithinBefore: {"within-before", 3, TestWithinBefore},
+		TestOpIsNil:        {"is-nil", 1, TestIsNil},
+		TestOpNotNil:       {"not-nil", 1, TestNotNil},
+	}
+	p := new(atomic.Pointer[[]testOpEntry])
+	p.Store(&tab)
+	return p
+}
+
+func lookupTestOp(t TestOp) (testOpEntry, bool) {
+	tab := *testOpTab.Load()
+	if t < 0 || int(t) >= len(tab) {
+		return testOpEntry{}, false
+	}
+	return tab[t], true
+}
+
+// RegisterTestOp register a TestFunc as a TestOp named `name` which can be used in Guard and JoinTest,
+// arity is the number of arguments fn requires, it should be 1 or 2 for a TestOp used in Guard.
+// RegisterTestOp panics if the name is registered or fn is nil, so it is usually called in init().
+func RegisterTestOp(name string, arity int, fn TestFunc) TestOp {
+	if name == "" || fn == nil || arity < 1 {
+		panic(errors.Errorf("invalid TestOp %q with arity %d", name, arity))
+	}
+
+	testOpMu.Lock()
+	defer testOpMu.Unlock()
+	old := *testOpTab.Load()
+	for _, e := range old {
+		if e.name == name {
+			panic(errors.Errorf("TestOp %q is already registered", name))
+		}
+	}
+	if len(old) >= maxTestOp {
+		panic(errors.Errorf("too many TestOps, cannot register %q", name))
+	}
+
+	tab := make([]testOpEntry, len(old), len(old)+1)
+	copy(tab, old)
+	tab = append(tab, testOpEntry{name: name, arity: arity, fn: fn})
+	testOpTab.Store(&tab)
+	return TestOp(len(tab) - 1)
+}
+
+type derivedTestOpKey struct {
+	kind string
+	op   TestOp
+	arg  int
+}
+
+var (
+	derivedTestOpMu sync.Mutex // held when deriving a TestOp
+	derivedTestOps  = make(map[derivedTestOpKey]TestOp)
+)
+
+// LenOf return a TestOp that performs op with the length of args[arg] instead of args[arg] itself,
+// the length of a string, a list, a set or a map can be taken
+func LenOf(op TestOp, arg int) TestOp {
+	return deriveTestOp("len", op, arg, lenOf)
+}
+
+// AnyElementOf return a TestOp that performs op with each element of args[arg] instead of args[arg] itself,
+// and succeeds if any of them succeeds, elements of a map are its values
+func AnyElementOf(op TestOp, arg int) TestOp {
+	return deriveTestOp("any-element", op, arg, elementsOf)
+}
+
+// deriveTestOp register a TestOp which performs op with each value converted from args[arg] by conv,
+// TestOps derived from the same op are registered only once
+func deriveTestOp(kind string, op TestOp, arg int, conv func(GValue) ([]GValue, error)) TestOp {
+	arity := op.Arity()
+	if arg < 0 || arg >= arity {
+		panic(errors.Errorf("cannot derive %s from TestOp %s at arg %d", kind, op, arg))
+	}
+
+	key := derivedTestOpKey{kind: kind, op: op, arg: arg}
+	derivedTestOpMu.Lock()
+	defer derivedTestOpMu.Unlock()
+	if derived, in := derivedTestOps[key]; in {
+		return derived
+	}
+
+	fn := op.ToFunc()
+	derived := RegisterTestOp(fmt.Sprintf("%s:%d:%s", kind, arg, op), arity, func(args ...GValue) (bool, error) {
+		if len(args) <= arg {
+			return false, errors.Errorf("%s of TestOp %s requires at least %d args, but got %d",
+				kind, op, arg+1, len(args))
+		}
+		values, err := conv(args[arg])
+		if err != nil {
+			return false, err
+		}
+		converted := slices.Clone(args)
+		for _, v := range values {
+			converted[arg] = v
+			if ok, err := fn(converted...); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	})
+	derivedTestOps[key] = derived
+	return derived
+}
+
+// lenOf take the length of v, the length of nil is 0 like the one of a nil slice
+func lenOf(v GValue) ([]GValue, error) {
+	if isNil(v) {
+		return []GValue{GVInt(0)}, nil
+	}
+	switch v := v.(type) {
+	case GVString:
+		return []GValue{GVInt(len(v))}, nil
+	case *GVList:
+		return []GValue{GVInt(v.Len())}, nil
+	case *GVSet:
+		return []GValue{GVInt(v.Len())}, nil
+	case *GVMap:
+		return []GValue{GVInt(v.Len())}, nil
+	}
+	return nil, errors.Errorf("cannot take the length of %s", v.Type())
+}
+
+// elementsOf take the elements of v, nil has no element
+func elementsOf(v GValue) ([]GValue, error) {
+	if isNil(v) {
+		return nil, nil
+	}
+	switch v := v.(type) {
+	case *GVList:
+		return v.Elems, nil
+	case *GVSet:
+		return v.Elems, nil
+	case *GVMap:
+		values := make([]GValue, 0, v.Len())
+		for _, e := range v.Entries {
+			values = append(values, e.Value)
+		}
+		return values, nil
+	}
+	return nil, errors.Errorf("cannot take elements of %s", v.Type())
+}
+
+// TestOpByName lookup a TestOp by its name, including the builtin ones
+func TestOpByName(name string) (TestOp, bool) {
+	for i, e := range *testOpTab.Load() {
+		if e.name == name {
+			return TestOp(i), true
+		}
+	}
+	return 0, false
+}
+
+// bindTestValue bind v as the first argument of a TestOp,
+// anything that can be reused in each test is prepared here, like compiling the regexp
+func bindTestValue(op TestOp, v GValue) (func(GValue) (bool, error), error) {
+	switch op {
+	case TestOpMatch:
+		re, err := compileRegexp(v)
+		if err != nil {
+			return nil, err
+		}
+		return func(x GValue) (bool, error) {
+			s, ok := x.(GVString)
+			if !ok {
+				return false, errors.Errorf("TestOpMatch requires string args, but got %s", x.Type())
+			}
+			return re.MatchString(string(s)), nil
+		}, nil
+	case TestOpIn, TestOpNotIn:
+		if _, ok := v.(*GVSet); !ok {
+			return nil, errors.Errorf("%s requires a set as value, but got %s", op, v.Type())
+		}
+	}
+
+	fn := op.ToFunc()
+	switch arity := op.Arity(); arity {
+	case 1:
+		return func(x GValue) (bool, error) {
+			return fn(x)
+		}, nil
+	case 2:
+		return func(x GValue) (bool, error) {
+			return fn(v, x)
+		}, nil
+	default:
+		return nil, errors.Errorf("TestOp %s with arity %d cannot be used in Guard", op, arity)
+	}
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
 // Testing functions for TestOp
+//
+// Nil, either a nil GValue or a GVNil, is null-safe in all the builtin TestOps:
+//   - nil equals to nil only, so TestNotEqual between nil and anything else is true;
+//   - nil is a value in sets, so TestIn is true if the set contains nil;
+//   - any other test involving nil is false, like ordering, string matching and temporal tests.
+//
+// The result of a negative Guard or JoinTest is the opposite, so a nil field passes a negative ordering test.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 
+// isNil tell whether v is a nil GValue or a GVNil
+func isNil(v GValue) bool {
+	return v == nil || v.Type() == GValueTypeNil
+}
+
+// anyNil tell whether any of the first n args is nil
+func anyNil(args []GValue, n int) bool {
+	for _, v := range args[:min(n, len(args))] {
+		if isNil(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIsNil(args ...GValue) (bool, error) {
+	if len(args) < 1 {
+		return false, errors.Errorf("TestOpIsNil requires at least one arg, but got %d", len(args))
+	}
+	return isNil(args[0]), nil
+}
+
+func TestNotNil(args ...GValue) (bool, error) {
+	if len(args) < 1 {
+		return false, errors.Errorf("TestOpNotNil requires at least one arg, but got %d", len(args))
+	}
+	return !isNil(args[0]), nil
+}
+
 func TestEqual(args ...GValue) (bool, error) {
 	if len(args) < 2 {
 		return false, errors.Errorf("TestOpEqual requires at least two args, but got %d", len(args))
 	}
 	x, y := args[0], args[1]
-	// TODO: check types of x and y
+	if isNil(x) || isNil(y) {
+		return isNil(x) && isNil(y), nil
+	}
+	// numbers of different types are compared as floats, or exactly if any of them is a decimal
+	if x.Type() != y.Type() {
+		if l, r, ok := decimalsOf(x, y); ok {
+			return l.Cmp(r) == 0, nil
+		}
+		if l, ok := conv2Float(x); ok {
+			if r, ok := conv2Float(y); ok {
+				return l == r, nil
+			}
+		}
+	}
+	// user-defined GValues know how to be compared with builtin ones, but not the other way around
+	if y.Type().IsCustom() && !x.Type().IsCustom() {
+		return y.Equal(x), nil
+	}
+	// values of incompatible types are never equal, guards and join tests comparing them are rejected by checkOperandTypes
 	return x.Equal(y), nil
 }
 
+func TestNotEqual(args ...GValue) (bool, error) {
+	if len(args) < 2 {
+		return false, errors.Errorf("TestOpNotEqual requires at least two args, but got %d", len(args))
+	}
+	eq, err := TestEqual(args...)
+	if err != nil {
+		return false, err
+	}
+	return !eq, nil
+}
+
 func TestLess(args ...GValue) (bool, error) {
 	if len(args) < 2 {
 		return false, errors.Errorf("TestOpLess requires at least two args, but got %d", len(args))
 	}
-	l, r := args[0], args[1]
+	if anyNil(args, 2) {
+		return false, nil
+	}
+	c, err := compareValue(args[0], args[1])
+	if err != nil {
+		return false, err
+	}
+	return c < 0, nil
+}
+
+func TestGreater(args ...GValue) (bool, error) {
+	if len(args) < 2 {
+		return false, errors.Errorf("TestOpGreater requires at least two args, but got %d", len(args))
+	}
+	if anyNil(args, 2) {
+		return false, nil
+	}
+	c, err := compareValue(args[0], args[1])
+	if err != nil {
+		return false, err
+	}
+	return c > 0, nil
+}
+
+func TestLessEqual(args ...GValue) (bool, error) {
+	if len(args) < 2 {
+		return false, errors.Errorf("TestOpLessEqual requires at least two args, but got %d", len(args))
+	}
+	if anyNil(args, 2) {
+		return false, nil
+	}
+	c, err := compareValue(args[0], args[1])
+	if err != nil {
+		return false, err
+	}
+	return c <= 0, nil
+}
+
+func TestGreaterEqual(args ...GValue) (bool, error) {
+	if len(args) < 2 {
+		return false, errors.Errorf("TestOpGreaterEqual requires at least two args, but got %d", len(args))
+	}
+	if anyNil(args, 2) {
+		return false, nil
+	}
+	c, err := compareValue(args[0], args[1])
+	if err != nil {
+		return false, err
+	}
+	return c >= 0, nil
+}
+
+// compareValue return a negative number when l < r, zero when l == r, or a positive number when l > r.
+// Numbers of different types are compared as floats, or exactly if any of them is a decimal,
+// strings are compared lexicographically, and user-defined GValues are compared by themselves if they are Comparable
+func compareValue(l, r GValue) (int, error) {
+	if c, ok := l.(Comparable); ok {
+		return c.Compare(r)
+	}
+	if c, ok := r.(Comparable); ok {
+		ret, err := c.Compare(l)
+		return -ret, err
+	}
 	if l.Type() != r.Type() {
+		if x, y, ok := decimalsOf(l, r); ok {
+			return x.Cmp(y), nil
+		}
 		if x, ok := conv2Float(l); ok {
 			if y, ok := conv2Float(r); ok {
-				return x < y, nil
+				return cmp.Compare(x, y), nil
 			}
 		}
-		return false, errors.New("cannot compare value with different type")
+		return 0, errors.Errorf("cannot compare value of type %s with type %s", l.Type(), r.Type())
 	}
 	switch l.Type() {
 	case GValueTypeInt:
-		return l.(GVInt) < r.(GVInt), nil
+		return cmp.Compare(l.(GVInt), r.(GVInt)), nil
 	case GValueTypeUint:
-		return l.(GVUint) < r.(GVUint), nil
+		return cmp.Compare(l.(GVUint), r.(GVUint)), nil
 	case GValueTypeFloat:
-		return l.(GVFloat) < r.(GVFloat), nil
+		return cmp.Compare(l.(GVFloat), r.(GVFloat)), nil
+	case GValueTypeString:
+		return cmp.Compare(l.(GVString), r.(GVString)), nil
+	case GValueTypeTime:
+		return l.(GVTime).Compare(r.(GVTime).Time), nil
+	case GValueTypeDuration:
+		return cmp.Compare(l.(GVDuration), r.(GVDuration)), nil
+	case GValueTypeDecimal:
+		return l.(GVDecimal).Cmp(r.(GVDecimal)), nil
 	}
-	return false, errors.Errorf("less operator is unsupported for type %s", l.Type())
+	return 0, errors.Errorf("ordering is unsupported for type %s", l.Type())
+}
+
+// decimalsOf convert two numbers into decimals if any of them is a decimal
+func decimalsOf(x, y GValue) (GVDecimal, GVDecimal, bool) {
+	if x.Type() != GValueTypeDecimal && y.Type() != GValueTypeDecimal {
+		return GVDecimal{}, GVDecimal{}, false
+	}
+	l, ok := DecimalOf(x)
+	if !ok {
+		return GVDecimal{}, GVDecimal{}, false
+	}
+	r, ok := DecimalOf(y)
+	return l, r, ok
 }
 
 func conv2Float(v GValue) (GVFloat, bool) {
-	f, ok := v.(interface {
-		toFloat() GVFloat
-	})
+	switch v := v.(type) {
+	case GVInt:
+		return GVFloat(v), true
+	case GVUint:
+		return GVFloat(v), true
+	case GVFloat:
+		return v, true
+	}
+	return 0, false
+}
+
+// TestContains test if args[1] contains args[0], args[1] can be a string, a list, a set or a map(contains the key)
+func TestContains(args ...GValue) (bool, error) {
+	if len(args) >= 2 {
+		if isNil(args[1]) {
+			return false, nil
+		}
+		switch c := args[1].(type) {
+		case *GVList:
+			for _, e := range c.Elems {
+				if eq, err := TestEqual(args[0], e); err != nil || eq {
+					return eq, err
+				}
+			}
+			return false, nil
+		case *GVSet:
+			return TestIn(c, args[0])
+		case *GVMap:
+			if args[0] == nil {
+				return false, nil
+			}
+			for _, k := range append([]GValue{args[0]}, numberVariants(args[0])...) {
+				if _, in := c.Get(k); in {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+	}
+	if anyNil(args, 2) {
+		return false, nil
+	}
+	sub, s, err := stringArgs("TestOpContains", args)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(s, sub), nil
+}
+
+func TestHasPrefix(args ...GValue) (bool, error) {
+	if anyNil(args, 2) {
+		return false, nil
+	}
+	prefix, s, err := stringArgs("TestOpHasPrefix", args)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(s, prefix), nil
+}
+
+func TestHasSuffix(args ...GValue) (bool, error) {
+	if anyNil(args, 2) {
+		return false, nil
+	}
+	suffix, s, err := stringArgs("TestOpHasSuffix", args)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasSuffix(s, suffix), nil
+}
+
+func TestEqualFold(args ...GValue) (bool, error) {
+	if anyNil(args, 2) {
+		return false, nil
+	}
+	x, y, err := stringArgs("TestOpEqualFold", args)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(x, y), nil
+}
+
+// TestMatch look up the compiled pattern in regexpCache, use bindTestValue to compile it only once in guards
+func TestMatch(args ...GValue) (bool, error) {
+	if anyNil(args, 2) {
+		return false, nil
+	}
+	pattern, s, err := stringArgs("TestOpMatch", args)
+	if err != nil {
+		return false, err
+	}
+	re, err := cachedRegexp(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
+
+const maxCachedRegexps = 1024
+
+var (
+	// patterns of join tests come from facts, so at most maxCachedRegexps of them are cached
+	regexpCache     sync.Map // map[string]*regexp.Regexp
+	regexpCacheSize atomic.Int64
+)
+
+func cachedRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexpCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid pattern %q", pattern)
+	}
+	if regexpCacheSize.Load() < maxCachedRegexps {
+		if _, loaded := regexpCache.LoadOrStore(pattern, re); !loaded {
+			regexpCacheSize.Add(1)
+		}
+	}
+	return re, nil
+}
+
+func compileRegexp(v GValue) (*regexp.Regexp, error) {
+	pattern, ok := v.(GVString)
 	if !ok {
-		return 0, false
+		return nil, errors.Errorf("expecting a string as pattern, but got %s", v.Type())
+	}
+	re, err := regexp.Compile(string(pattern))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid pattern %q", pattern)
+	}
+	return re, nil
+}
+
+// stringArgs extract the first two args of a string matching TestOp
+func stringArgs(name string, args []GValue) (string, string, error) {
+	if len(args) < 2 {
+		return "", "", errors.Errorf("%s requires at least two args, but got %d", name, len(args))
+	}
+	x, ok := args[0].(GVString)
+	if !ok {
+		return "", "", errors.Errorf("%s requires string args, but got %s", name, args[0].Type())
+	}
+	y, ok := args[1].(GVString)
+	if !ok {
+		return "", "", errors.Errorf("%s requires string args, but got %s", name, args[1].Type())
+	}
+	return string(x), string(y), nil
+}
+
+func TestIn(args ...GValue) (bool, error) {
+	if len(args) < 2 {
+		return false, errors.Errorf("TestOpIn requires at least two args, but got %d", len(args))
+	}
+	if isNil(args[0]) {
+		return false, nil
+	}
+	set, ok := args[0].(*GVSet)
+	if !ok {
+		return false, errors.Errorf("TestOpIn requires a set as its first arg, but got %s", args[0].Type())
+	}
+	x := args[1]
+	if x == nil {
+		x = &GVNil{}
+	}
+	if set.Contains(x) {
+		return true, nil
+	}
+	// numbers of different types are equal if they have the same value, like TestEqual
+	for _, n := range numberVariants(x) {
+		if set.Contains(n) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestNotIn(args ...GValue) (bool, error) {
+	if len(args) < 2 {
+		return false, errors.Errorf("TestOpNotIn requires at least two args, but got %d", len(args))
+	}
+	if isNil(args[0]) {
+		return false, nil
+	}
+	in, err := TestIn(args...)
+	if err != nil {
+		return false, err
+	}
+	return !in, nil
+}
+
+// numberVariants return the values of other numeric types that equal to v
+func numberVariants(v GValue) []GValue {
+	switch v := v.(type) {
+	case GVInt:
+		d, _ := DecimalOf(v)
+		if v >= 0 {
+			return []GValue{GVFloat(v), GVUint(v), d}
+		}
+		return []GValue{GVFloat(v), d}
+	case GVUint:
+		d, _ := DecimalOf(v)
+		if v <= math.MaxInt64 {
+			return []GValue{GVFloat(v), GVInt(v), d}
+		}
+		return []GValue{GVFloat(v), d}
+	case GVFloat:
+		ret := make([]GValue, 0, 3)
+		if d, ok := DecimalOf(v); ok {
+			ret = append(ret, d)
+		}
+		if v != GVFloat(math.Trunc(float64(v))) {
+			return ret
+		}
+		if v >= math.MinInt64 && v < math.MaxInt64 {
+			ret = append(ret, GVInt(v))
+		}
+		if v >= 0 && v < math.MaxUint64 {
+			ret = append(ret, GVUint(v))
+		}
+		return ret
+	case GVDecimal:
+		return decimalVariants(v)
+	}
+	return nil
+}
+
+// decimalVariants are numbers of other types equal to decimal v
+func decimalVariants(v GVDecimal) []GValue {
+	r := v.Rat()
+	ret := make([]GValue, 0, 3)
+	// floats are converted into decimals by their shortest representations, so is the other way around
+	f, _ := r.Float64()
+	if d, ok := DecimalOf(GVFloat(f)); ok && d.Cmp(v) == 0 {
+		ret = append(ret, GVFloat(f))
+	}
+	if !r.IsInt() {
+		return ret
+	}
+	if n := r.Num(); n.IsInt64() {
+		ret = append(ret, GVInt(n.Int64()))
+	}
+	if n := r.Num(); n.IsUint64() {
+		ret = append(ret, GVUint(n.Uint64()))
+	}
+	return ret
+}
+
+// TestWithinAfter test if args[0] is within args[2] after args[1], that is args[1] <= args[0] <= args[1]+args[2]
+func TestWithinAfter(args ...GValue) (bool, error) {
+	if anyNil(args, 3) {
+		return false, nil
+	}
+	x, y, d, err := temporalArgs("TestOpWithinAfter", args)
+	if err != nil {
+		return false, err
+	}
+	return !x.Before(y.Time) && !x.After(y.Add(d).Time), nil
+}
+
+// TestWithinBefore test if args[0] is within args[2] before args[1], that is args[1]-args[2] <= args[0] <= args[1]
+func TestWithinBefore(args ...GValue) (bool, error) {
+	if anyNil(args, 3) {
+		return false, nil
+	}
+	x, y, d, err := temporalArgs("TestOpWithinBefore", args)
+	if err != nil {
+		return false, err
+	}
+	return !x.Before(y.Add(-d).Time) && !x.After(y.Time), nil
+}
+
+// temporalArgs extract the two times and the duration between them of a temporal TestOp
+func temporalArgs(name string, args []GValue) (GVTime, GVTime, GVDuration, error) {
+	if len(args) < 3 {
+		return GVTime{}, GVTime{}, 0, errors.Errorf("%s requires at least three args, but got %d", name, len(args))
+	}
+	x, ok := args[0].(GVTime)
+	if !ok {
+		return GVTime{}, GVTime{}, 0, errors.Errorf("%s requires time args, but got %s", name, args[0].Type())
+	}
+	y, ok := args[1].(GVTime)
+	if !ok {
+		return GVTime{}, GVTime{}, 0, errors.Errorf("%s requires time args, but got %s", name, args[1].Type())
+	}
+	d, ok := args[2].(GVDuration)
+	if !ok {
+		return GVTime{}, GVTime{}, 0, errors.Errorf("%s requires a duration arg, but got %s", name, args[2].Type())
+	}
+	if d < 0 {
+		return GVTime{}, GVTime{}, 0, errors.Errorf("%s requires a non-negative duration, but got %s", name, d)
 	}
-	return f.toFloat(), ok
+	return x, y, d, nil
 }
@@ -1,6 +1,11 @@
 package rete_test
 
 import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -39,3 +44,272 @@ var _ = Describe("Cond", func() {
 		Expect(hash).Should(BeEquivalentTo(otherCond.Hash()))
 	})
 })
+
+func mustDecimal(s string) GVDecimal {
+	d, err := NewGVDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var _ = Describe("TestOp", func() {
+	It("can generate different hash for different TestOp", func() {
+		hashes := make(map[uint64]TestOp)
+		for op := TestOpEqual; op < NTestOp; op++ {
+			h := Guard{AliasAttr: "field", Value: GVInt(1), TestOp: op}.Hash()
+			Expect(hashes).ShouldNot(HaveKey(h))
+			hashes[h] = op
+		}
+	})
+
+	DescribeTable("comparing values",
+		func(op TestOp, x, y GValue, expected bool) {
+			Expect(op.ToFunc()(x, y)).Should(Equal(expected))
+		},
+		Entry("int == int", TestOpEqual, GVInt(1), GVInt(1), true),
+		Entry("int == float", TestOpEqual, GVInt(1), GVFloat(1), true),
+		Entry("uint != int", TestOpNotEqual, GVUint(1), GVInt(2), true),
+		Entry("string != string", TestOpNotEqual, GVString("a"), GVString("a"), false),
+		Entry("int < uint", TestOpLess, GVInt(-1), GVUint(1), true),
+		Entry("float > int", TestOpGreater, GVFloat(1.5), GVInt(1), true),
+		Entry("int > int", TestOpGreater, GVInt(1), GVInt(1), false),
+		Entry("uint <= uint", TestOpLessEqual, GVUint(1), GVUint(1), true),
+		Entry("float <= int", TestOpLessEqual, GVFloat(2.5), GVInt(2), false),
+		Entry("int >= float", TestOpGreaterEqual, GVInt(2), GVFloat(1.5), true),
+		Entry("string < string", TestOpLess, GVString("abc"), GVString("abd"), true),
+		Entry("string > string", TestOpGreater, GVString("b"), GVString("abc"), true),
+		Entry("string >= string", TestOpGreaterEqual, GVString("ab"), GVString("abc"), false),
+		Entry("contains", TestOpContains, GVString("ell"), GVString("hello"), true),
+		Entry("not contains", TestOpContains, GVString("hello"), GVString("ell"), false),
+		Entry("has prefix", TestOpHasPrefix, GVString("he"), GVString("hello"), true),
+		Entry("has no prefix", TestOpHasPrefix, GVString("lo"), GVString("hello"), false),
+		Entry("has suffix", TestOpHasSuffix, GVString("lo"), GVString("hello"), true),
+		Entry("has no suffix", TestOpHasSuffix, GVString("he"), GVString("hello"), false),
+		Entry("equal ignoring case", TestOpEqualFold, GVString("Hello"), GVString("hELLO"), true),
+		Entry("not equal ignoring case", TestOpEqualFold, GVString("Hello"), GVString("hELL"), false),
+		Entry("match regexp", TestOpMatch, GVString(`^host-\d+$`), GVString("host-01"), true),
+		Entry("not match regexp", TestOpMatch, GVString(`^host-\d+$`), GVString("host-a"), false),
+		Entry("in", TestOpIn, NewGVSet(GVString("red"), GVString("blue")), GVString("blue"), true),
+		Entry("not in", TestOpIn, NewGVSet(GVString("red"), GVString("blue")), GVString("green"), false),
+		Entry("int in floats", TestOpIn, NewGVSet(GVFloat(1), GVFloat(2.5)), GVInt(1), true),
+		Entry("float in uints", TestOpIn, NewGVSet(GVUint(1), GVUint(2)), GVFloat(2), true),
+		Entry("float not in ints", TestOpIn, NewGVSet(GVInt(1), GVInt(2)), GVFloat(1.5), false),
+		Entry("not-in", TestOpNotIn, NewGVSet(GVInt(1), GVInt(2)), GVUint(3), true),
+		Entry("not-in with value in set", TestOpNotIn, NewGVSet(GVInt(1), GVInt(2)), GVUint(2), false),
+		Entry("time == time in other location", TestOpEqual, NewGVTime(epoch), NewGVTime(epoch.In(time.FixedZone("X", 3600))), true),
+		Entry("time < time", TestOpLess, NewGVTime(epoch), NewGVTime(epoch.Add(time.Second)), true),
+		Entry("time >= time", TestOpGreaterEqual, NewGVTime(epoch), NewGVTime(epoch.Add(time.Second)), false),
+		Entry("duration > duration", TestOpGreater, GVDuration(time.Minute), GVDuration(time.Second), true),
+		Entry("duration <= duration", TestOpLessEqual, GVDuration(time.Minute), GVDuration(time.Minute), true),
+		Entry("list contains", TestOpContains, GVInt(2), NewGVList(GVInt(1), GVFloat(2)), true),
+		Entry("list not contains", TestOpContains, GVInt(3), NewGVList(GVInt(1), GVInt(2)), false),
+		Entry("set contains", TestOpContains, GVString("a"), NewGVSet(GVString("a")), true),
+		Entry("map contains key", TestOpContains, GVString("env"), NewGVMap(GVMapEntry{Key: GVString("env"), Value: GVString("prod")}), true),
+		Entry("map not contains value", TestOpContains, GVString("prod"), NewGVMap(GVMapEntry{Key: GVString("env"), Value: GVString("prod")}), false),
+		Entry("custom == custom", TestOpEqual, Celsius(1), Celsius(1), true),
+		Entry("int == custom", TestOpEqual, GVInt(1), Celsius(1), true),
+		Entry("custom < float", TestOpLess, Celsius(1), GVFloat(1.5), true),
+		Entry("int > custom", TestOpGreater, GVInt(2), Celsius(1.5), true),
+		Entry("custom in set", TestOpIn, NewGVSet(Celsius(1), Celsius(2)), Celsius(2), true),
+		Entry("decimal == decimal", TestOpEqual, mustDecimal("1.10"), mustDecimal("1.1"), true),
+		Entry("decimal == float", TestOpEqual, mustDecimal("0.3"), GVFloat(0.3), true),
+		Entry("float != decimal", TestOpEqual, GVFloat(0.30000000000000004), mustDecimal("0.3"), false),
+		Entry("int == decimal", TestOpEqual, GVInt(2), mustDecimal("2.00"), true),
+		Entry("decimal < decimal", TestOpLess, mustDecimal("100.09"), mustDecimal("100.10"), true),
+		Entry("decimal < int", TestOpLess, mustDecimal("99.99"), GVInt(100), true),
+		Entry("uint <= decimal", TestOpLessEqual, GVUint(100), mustDecimal("99.99"), false),
+		Entry("big decimal > int", TestOpGreater, mustDecimal("9223372036854775808"), GVInt(math.MaxInt64), true),
+		Entry("int in decimals", TestOpIn, NewGVSet(mustDecimal("1.0"), mustDecimal("2.5")), GVInt(1), true),
+		Entry("decimal in floats", TestOpIn, NewGVSet(GVFloat(0.1), GVFloat(2.5)), mustDecimal("0.10"), true),
+		Entry("decimal in ints", TestOpIn, NewGVSet(GVInt(1), GVInt(2)), mustDecimal("2"), true),
+		Entry("decimal not in ints", TestOpIn, NewGVSet(GVInt(1), GVInt(2)), mustDecimal("1.5"), false),
+		Entry("map contains int key", TestOpContains, GVUint(1), NewGVMap(GVMapEntry{Key: GVInt(1), Value: GVString("one")}), true),
+	)
+
+	DescribeTable("testing collections",
+		func(op TestOp, x, y GValue, expected bool) {
+			Expect(op.ToFunc()(x, y)).Should(Equal(expected))
+		},
+		Entry("len of list", LenOf(TestOpEqual, 1), GVInt(2), NewGVList(GVInt(1), GVInt(1)), true),
+		Entry("len of set", LenOf(TestOpEqual, 1), GVInt(2), NewGVSet(GVInt(1), GVInt(1)), false),
+		Entry("len of map", LenOf(TestOpLess, 0), NewGVMap(GVMapEntry{Key: GVString("a"), Value: GVInt(1)}), GVInt(2), true),
+		Entry("len of string", LenOf(TestOpGreater, 1), GVInt(3), GVString("ab"), true),
+		Entry("any element of list", AnyElementOf(TestOpLess, 1), GVInt(2), NewGVList(GVInt(1), GVInt(3)), true),
+		Entry("no element of list", AnyElementOf(TestOpLess, 1), GVInt(3), NewGVList(GVInt(1), GVInt(3)), false),
+		Entry("any value of map", AnyElementOf(TestOpHasPrefix, 1), GVString("pr"),
+			NewGVMap(GVMapEntry{Key: GVString("env"), Value: GVString("prod")}), true),
+		Entry("any element of empty list", AnyElementOf(TestOpEqual, 0), NewGVList(), GVInt(1), false),
+	)
+
+	It("can derive a TestOp only once", func() {
+		op := AnyElementOf(TestOpEqual, 1)
+		Expect(op).Should(BeNumerically(">=", NTestOp))
+		Expect(op).Should(Equal(AnyElementOf(TestOpEqual, 1)))
+		Expect(op).ShouldNot(Equal(AnyElementOf(TestOpEqual, 0)))
+		Expect(op.String()).Should(Equal("any-element:1:eq"))
+		Expect(op.Arity()).Should(Equal(2))
+		Expect(LenOf(op, 1).String()).Should(Equal("len:1:any-element:1:eq"))
+
+		Expect(func() { LenOf(TestOpEqual, 2) }).Should(Panic())
+		_, err := LenOf(TestOpEqual, 1).ToFunc()(GVInt(1), GVInt(1))
+		Expect(err).Should(HaveOccurred())
+		_, err = AnyElementOf(TestOpEqual, 1).ToFunc()(GVInt(1), GVString("1"))
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("fail to match strings with non-string values or invalid pattern", func() {
+		for _, op := range []TestOp{TestOpContains, TestOpHasPrefix, TestOpHasSuffix, TestOpEqualFold, TestOpMatch} {
+			_, err := op.ToFunc()(GVString("1"), GVInt(1))
+			Expect(err).Should(HaveOccurred())
+		}
+		_, err := TestMatch(GVString("("), GVString("("))
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("match strings with a cached pattern", func() {
+		for i := 0; i < 2; i++ {
+			Expect(TestMatch(GVString(`^b\d+$`), GVString("b12"))).Should(BeTrue())
+			Expect(TestMatch(GVString(`^b\d+$`), GVString("c12"))).Should(BeFalse())
+		}
+	})
+
+	DescribeTable("testing times within a duration",
+		func(op TestOp, x, y time.Time, d time.Duration, expected bool) {
+			Expect(op.ToFunc()(NewGVTime(x), NewGVTime(y), GVDuration(d))).Should(Equal(expected))
+		},
+		Entry("within after", TestOpWithinAfter, epoch.Add(time.Minute), epoch, 5*time.Minute, true),
+		Entry("within after at the edge", TestOpWithinAfter, epoch.Add(5*time.Minute), epoch, 5*time.Minute, true),
+		Entry("too late", TestOpWithinAfter, epoch.Add(6*time.Minute), epoch, 5*time.Minute, false),
+		Entry("before but not after", TestOpWithinAfter, epoch.Add(-time.Minute), epoch, 5*time.Minute, false),
+		Entry("within before", TestOpWithinBefore, epoch.Add(-time.Minute), epoch, 5*time.Minute, true),
+		Entry("too early", TestOpWithinBefore, epoch.Add(-6*time.Minute), epoch, 5*time.Minute, false),
+		Entry("after but not before", TestOpWithinBefore, epoch.Add(time.Minute), epoch, 5*time.Minute, false),
+	)
+
+	DescribeTable("testing nil",
+		func(op TestOp, args []GValue, expected bool) {
+			Expect(op.ToFunc()(args...)).Should(Equal(expected))
+		},
+		Entry("is nil", TestOpIsNil, []GValue{&GVNil{}}, true),
+		Entry("nil GValue is nil", TestOpIsNil, []GValue{nil}, true),
+		Entry("int is not nil", TestOpNotNil, []GValue{GVInt(0)}, true),
+		Entry("nil == nil", TestOpEqual, []GValue{&GVNil{}, nil}, true),
+		Entry("nil == int", TestOpEqual, []GValue{&GVNil{}, GVInt(0)}, false),
+		Entry("int == nil", TestOpEqual, []GValue{GVInt(0), nil}, false),
+		Entry("nil != string", TestOpNotEqual, []GValue{GVString(""), &GVNil{}}, true),
+		Entry("nil < int", TestOpLess, []GValue{&GVNil{}, GVInt(1)}, false),
+		Entry("int >= nil", TestOpGreaterEqual, []GValue{GVInt(1), nil}, false),
+		Entry("celsius < nil", TestOpLess, []GValue{Celsius(1), &GVNil{}}, false),
+		Entry("nil has prefix", TestOpHasPrefix, []GValue{GVString(""), &GVNil{}}, false),
+		Entry("nil matches", TestOpMatch, []GValue{GVString(".*"), &GVNil{}}, false),
+		Entry("nil contains", TestOpContains, []GValue{GVString(""), &GVNil{}}, false),
+		Entry("list contains nil", TestOpContains, []GValue{&GVNil{}, NewGVList(GVInt(1), &GVNil{})}, true),
+		Entry("map contains nil", TestOpContains, []GValue{nil, NewGVMap()}, false),
+		Entry("nil in set", TestOpIn, []GValue{NewGVSet(GVInt(1), &GVNil{}), nil}, true),
+		Entry("nil not in set", TestOpNotIn, []GValue{NewGVSet(GVInt(1)), &GVNil{}}, true),
+		Entry("int in nil", TestOpIn, []GValue{&GVNil{}, GVInt(1)}, false),
+		Entry("int not in nil", TestOpNotIn, []GValue{&GVNil{}, GVInt(1)}, false),
+		Entry("nil within after", TestOpWithinAfter, []GValue{&GVNil{}, NewGVTime(epoch), GVDuration(1)}, false),
+		Entry("len of nil", LenOf(TestOpEqual, 1), []GValue{GVInt(0), &GVNil{}}, true),
+		Entry("any element of nil", AnyElementOf(TestOpEqual, 1), []GValue{GVInt(0), &GVNil{}}, false),
+	)
+
+	It("fail to test times within a duration with invalid args", func() {
+		for _, op := range []TestOp{TestOpWithinAfter, TestOpWithinBefore} {
+			_, err := op.ToFunc()(NewGVTime(epoch), NewGVTime(epoch), GVInt(1))
+			Expect(err).Should(HaveOccurred())
+			_, err = op.ToFunc()(NewGVTime(epoch), GVInt(1), GVDuration(1))
+			Expect(err).Should(HaveOccurred())
+			_, err = op.ToFunc()(NewGVTime(epoch), NewGVTime(epoch), GVDuration(-1))
+			Expect(err).Should(HaveOccurred())
+			_, err = op.ToFunc()(NewGVTime(epoch), NewGVTime(epoch))
+			Expect(err).Should(HaveOccurred())
+		}
+	})
+
+	It("fail to test membership without a set", func() {
+		for _, op := range []TestOp{TestOpIn, TestOpNotIn} {
+			_, err := op.ToFunc()(GVString("1"), GVString("1"))
+			Expect(err).Should(HaveOccurred())
+		}
+	})
+
+	It("can tell sets apart regardless of the order of values", func() {
+		x := NewGVSet(GVString("red"), GVString("blue"), GVString("red"))
+		y := NewGVSet(GVString("blue"), GVString("red"))
+		Expect(x.Len()).Should(Equal(2))
+		Expect(x.Equal(y)).Should(BeTrue())
+		Expect(x.Hash()).Should(Equal(y.Hash()))
+
+		z := NewGVSet(GVString("blue"), GVString("green"))
+		Expect(x.Equal(z)).Should(BeFalse())
+		Expect(x.Hash()).ShouldNot(Equal(z.Hash()))
+		Expect(Guard{AliasAttr: "Color", Value: x, TestOp: TestOpIn}.Hash()).
+			ShouldNot(Equal(Guard{AliasAttr: "Color", Value: z, TestOp: TestOpIn}.Hash()))
+	})
+
+	It("fail to order values that are not comparable", func() {
+		for _, op := range []TestOp{TestOpLess, TestOpGreater, TestOpLessEqual, TestOpGreaterEqual} {
+			_, err := op.ToFunc()(GVString("1"), GVInt(1))
+			Expect(err).Should(HaveOccurred())
+			_, err = op.ToFunc()(GVInt(1))
+			Expect(err).Should(HaveOccurred())
+		}
+	})
+})
+
+// testOpDivisible test if args[1] is divisible by args[0]
+var testOpDivisible = RegisterTestOp("divisible-by", 2, func(args ...GValue) (bool, error) {
+	d, ok := args[0].(GVInt)
+	if !ok || d == 0 {
+		return false, errors.Errorf("invalid divisor %v", args[0])
+	}
+	x, ok := args[1].(GVInt)
+	if !ok {
+		return false, errors.Errorf("expecting an int, but got %s", args[1].Type())
+	}
+	return x%d == 0, nil
+})
+
+var _ = Describe("Custom TestOp", func() {
+	It("can be registered with name and arity", func() {
+		Expect(testOpDivisible).Should(BeNumerically(">=", NTestOp))
+		Expect(testOpDivisible.String()).Should(Equal("divisible-by"))
+		Expect(testOpDivisible.Arity()).Should(Equal(2))
+		Expect(testOpDivisible.ToFunc()(GVInt(3), GVInt(9))).Should(BeTrue())
+		Expect(testOpDivisible.ToFunc()(GVInt(3), GVInt(10))).Should(BeFalse())
+
+		op, ok := TestOpByName("divisible-by")
+		Expect(ok).Should(BeTrue())
+		Expect(op).Should(Equal(testOpDivisible))
+		op, ok = TestOpByName("less-eq")
+		Expect(ok).Should(BeTrue())
+		Expect(op).Should(Equal(TestOpLessEqual))
+	})
+
+	It("panics when registering a name twice or an invalid TestOp", func() {
+		fn := func(...GValue) (bool, error) { return true, nil }
+		Expect(func() { RegisterTestOp("divisible-by", 2, fn) }).Should(Panic())
+		Expect(func() { RegisterTestOp("eq", 2, fn) }).Should(Panic())
+		Expect(func() { RegisterTestOp("nil-func", 2, nil) }).Should(Panic())
+		Expect(func() { RegisterTestOp("no-args", 0, fn) }).Should(Panic())
+	})
+
+	It("can generate hash different from builtin TestOps", func() {
+		g := Guard{AliasAttr: "field", Value: GVInt(1), TestOp: testOpDivisible}
+		for op := TestOpEqual; op < NTestOp; op++ {
+			Expect(g.Hash()).ShouldNot(Equal(Guard{AliasAttr: "field", Value: GVInt(1), TestOp: op}.Hash()))
+		}
+	})
+
+	It("fail to test with an unknown TestOp", func() {
+		op := TestOp(1 << 20)
+		Expect(op.String()).Should(Equal("unknown"))
+		Expect(op.Arity()).Should(Equal(-1))
+		_, err := op.ToFunc()(GVInt(1), GVInt(1))
+		Expect(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,56 @@
+package rete
+
+import (
+	"strings"
+
+	. "github.com/ccbhj/grete/types"
+)
+
+// fieldMask is the set of fields changed by a modification of a fact, nil means all the fields are changed
+type fieldMask set[string]
+
+func newFieldMask(fields ...string) fieldMask {
+	if len(fields) == 0 {
+		return nil
+	}
+	return fieldMask(setFrom(fields...))
+}
+
+// affects tell whether the value of field f may be changed by the changes of the fields in m,
+// a change of a field changes the fields along its path, like `On` and `On.Color` change each other,
+// FieldSelf is changed by any change while FieldID is never changed
+func (m fieldMask) affects(f string) bool {
+	switch {
+	case m == nil, f == FieldSelf:
+		return true
+	case f == FieldID:
+		return false
+	}
+	for c := range m {
+		if c == f || c == FieldSelf || isSubField(f, c) || isSubField(c, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// affectsAny tell whether any of fields is affected by m
+func (m fieldMask) affectsAny(fields ...string) bool {
+	if m == nil {
+		return true
+	}
+	for _, f := range fields {
+		if m.affects(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubField tell whether f is a field along the path of parent, like `On.Color` or `On["Color"]` of `On`
+func isSubField(f, parent string) bool {
+	if len(f) <= len(parent) || !strings.HasPrefix(f, parent) {
+		return false
+	}
+	return f[len(parent)] == '.' || f[len(parent)] == '['
+}
@@ -0,0 +1,81 @@
+// Code generated by gretegen -type Piece; DO NOT EDIT.
+
+package rete
+
+import grete "github.com/ccbhj/grete/types"
+
+// GetField implements types.FieldAccessor for Piece
+func (x *Piece) GetField(f string) (grete.GValue, any, error) {
+	if x == nil {
+		return grete.GetFieldByReflect(x, f)
+	}
+	switch f {
+	case "At":
+		return grete.GVString(x.At), x.At, nil
+	case "Captured":
+		return grete.GVBool(x.Captured), x.Captured, nil
+	case "File":
+		return grete.GVInt(x.Position.File), x.Position.File, nil
+	case "ID":
+		return x.ID, x.ID, nil
+	case "LastMove":
+		if x.LastMove == nil {
+			return &grete.GVNil{}, nil, nil
+		}
+		return grete.GVTime{Time: *x.LastMove}, x.LastMove, nil
+	case "MovedAt":
+		return grete.GVTime{Time: x.MovedAt}, x.MovedAt, nil
+	case "On":
+		if x.On == nil {
+			return &grete.GVNil{}, nil, nil
+		}
+		return grete.NewGVStruct(x.On), x.On, nil
+	case "Rank":
+		return grete.GVInt(x.Rank), x.Rank, nil
+	case "Set":
+		if x.Set == nil {
+			return &grete.GVNil{}, nil, nil
+		}
+		return x.Set, x.Set, nil
+	case "Timeout":
+		return grete.GVDuration(x.Timeout), x.Timeout, nil
+	case "Weight":
+		return grete.GVFloat(x.Weight), x.Weight, nil
+	case "color":
+		return grete.GVString(x.Color), x.Color, nil
+	}
+	return grete.GetFieldByReflect(x, f)
+}
+
+// HasField implements types.FieldAccessor for Piece
+func (x *Piece) HasField(f string) bool {
+	switch f {
+	case "At", "Captured", "File", "ID", "LastMove", "MaxTemp", "MovedAt", "On", "Rank", "Set", "Tags", "Temp", "Timeout", "Weight", "color":
+		return true
+	}
+	return grete.HasFieldByReflect(x, f)
+}
+
+// NewPieceTypeInfo return the TypeInfo of Piece with the types of all its fields
+func NewPieceTypeInfo() grete.TypeInfo {
+	return grete.TypeInfo{
+		T: grete.GValueTypeStruct,
+		Fields: map[string]grete.GValueType{
+			"At":       grete.GValueTypeString,
+			"Captured": grete.GValueTypeBool,
+			"File":     grete.GValueTypeInt,
+			"ID":       grete.GValueTypeIdentity,
+			"LastMove": grete.GValueTypeTime,
+			"MaxTemp":  grete.GValueTypeOf[*Celsius](),
+			"MovedAt":  grete.GValueTypeTime,
+			"On":       grete.GValueTypeStruct,
+			"Rank":     grete.GValueTypeInt,
+			"Set":      grete.GValueTypeSet,
+			"Tags":     grete.GValueTypeList,
+			"Temp":     grete.GValueTypeOf[Celsius](),
+			"Timeout":  grete.GValueTypeDuration,
+			"Weight":   grete.GValueTypeFloat,
+			"color":    grete.GValueTypeString,
+		},
+	}
+}
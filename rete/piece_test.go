@@ -0,0 +1,118 @@
+package rete
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/ccbhj/grete/types"
+)
+
+//go:generate go run ../cmd/gretegen -type Piece
+
+type (
+	Square string
+
+	Position struct {
+		File, Rank int
+	}
+
+	// Piece is a Chess with accessors generated by gretegen
+	Piece struct {
+		ID       GVIdentity
+		Color    string `grete:"color"`
+		On       *Piece
+		Rank     int
+		Weight   float32
+		Captured bool
+		At       Square
+		MovedAt  time.Time
+		LastMove *time.Time
+		Timeout  time.Duration
+		Tags     []string
+		Secret   string `grete:"-"`
+		Position `grete:",inline"`
+		Set      *GVSet
+		Temp     Celsius
+		MaxTemp  *Celsius
+		score    int
+	}
+)
+
+var _ = Describe("Generated accessors", func() {
+	var pieces []*Piece
+
+	BeforeEach(func() {
+		now := time.Now()
+		table := &Piece{ID: "table"}
+		pieces = []*Piece{
+			{
+				ID: "P1", Color: "red", On: table, Rank: 1, Weight: 1.5, Captured: true, At: "e4",
+				MovedAt: now, LastMove: &now, Timeout: time.Minute, Tags: []string{"pawn"}, Secret: "x",
+				Position: Position{File: 5, Rank: 4}, Set: NewGVSet(GVInt(1)), Temp: 20, score: 1,
+			},
+			table,
+		}
+	})
+
+	It("should access fields like reflection", func() {
+		fields := []string{
+			"ID", "color", "On", "Rank", "Weight", "Captured", "At", "MovedAt", "LastMove", "Timeout", "Tags",
+			"File", "Set", "Temp", "MaxTemp", "On.ID", "Tags[0]",
+			"Color", "Secret", "Position", "score", "Missing",
+		}
+		for _, p := range pieces {
+			for _, f := range fields {
+				v, raw, err := p.GetField(f)
+				rv, rraw, rerr := GetFieldByReflect(p, f)
+				if rerr != nil {
+					Expect(err).Should(MatchError(rerr.Error()), f)
+				} else {
+					Expect(err).ShouldNot(HaveOccurred(), f)
+					Expect(v).Should(Equal(rv), f)
+					if rraw == nil {
+						Expect(raw).Should(BeNil(), f)
+					} else {
+						Expect(raw).Should(Equal(rraw), f)
+					}
+				}
+				Expect(p.HasField(f)).Should(Equal(HasFieldByReflect(p, f)), f)
+			}
+		}
+	})
+
+	It("should be used by GVStruct", func() {
+		var _ FieldAccessor = pieces[0]
+		v, _, err := NewGVStruct(pieces[0]).GetField("color")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(v).Should(Equal(GVString("red")))
+		Expect(NewGVStruct(pieces[0]).HasField("Color")).Should(BeFalse())
+	})
+
+	It("should provide the TypeInfo", func() {
+		tf := NewPieceTypeInfo()
+		Expect(tf.T).Should(Equal(GValueTypeStruct))
+		Expect(tf.Fields).Should(HaveKeyWithValue("color", GValueTypeString))
+		Expect(tf.Fields).Should(HaveKeyWithValue("At", GValueTypeString))
+		Expect(tf.Fields).Should(HaveKeyWithValue("LastMove", GValueTypeTime))
+		Expect(tf.Fields).Should(HaveKeyWithValue("Tags", GValueTypeList))
+		Expect(tf.Fields).Should(HaveKeyWithValue("Set", GValueTypeSet))
+		Expect(tf.Fields).ShouldNot(HaveKey("Secret"))
+		Expect(tf.Fields).Should(HaveKeyWithValue("File", GValueTypeInt))
+		Expect(tf.Fields).Should(HaveKeyWithValue("Temp", GValueTypeCelsius))
+		Expect(tf.Fields).Should(HaveKeyWithValue("MaxTemp", GValueTypeCelsius))
+		reflected := TypeInfoOf[Piece]()
+		for f, vt := range tf.Fields {
+			Expect(reflected.Fields).Should(HaveKeyWithValue(f, vt))
+		}
+
+		an := NewAlphaNetwork()
+		am, err := an.MakeAlphaMem(tf, []Guard{{AliasAttr: "color", Value: GVString("red"), TestOp: TestOpEqual}})
+		Expect(err).ShouldNot(HaveOccurred())
+		for _, p := range pieces {
+			an.AddFact(Fact{ID: p.ID, Value: NewGVStruct(p)})
+		}
+		Expect(am.NItems()).Should(Equal(1))
+	})
+})
@@ -0,0 +1,194 @@
+package rete
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/ccbhj/grete/types"
+)
+
+type Address struct {
+	City   string
+	Street string
+}
+
+// Version is compared by Major and Minor only
+type Version struct {
+	Major, Minor int
+	Note         string
+}
+
+func (v Version) Equal(w Version) bool { return v.Major == w.Major && v.Minor == w.Minor }
+func (v *Version) Hash() uint64        { return uint64(v.Major)<<32 | uint64(v.Minor) }
+
+// Badge is compared by its Equal method, and all the badges have the same hash since it has no Hash method
+type Badge struct {
+	Name string
+}
+
+func (b Badge) Equal(c Badge) bool { return b.Name == c.Name }
+
+// Secret is compared deeply with its unexported fields, which are not hashed
+type Secret struct {
+	Name string
+	pin  int
+}
+
+// Link is compared deeply, and links may refer to each other
+type Link struct {
+	Name    string
+	Next    *Link
+	OnVisit func()
+}
+
+type Customer struct {
+	ID   GVIdentity
+	Addr Address
+}
+
+type Shipment struct {
+	ID   GVIdentity
+	Addr *Address
+}
+
+func init() {
+	SetStructEquality[Address](StructEqualityDeep)
+	SetStructEquality[*Version](StructEqualityMethod)
+	SetStructEquality[Badge](StructEqualityMethod)
+	SetStructEquality[Secret](StructEqualityDeep)
+	SetStructEquality[Link](StructEqualityDeep)
+}
+
+var _ = Describe("struct equality", func() {
+	It("compare structs by identity by default", func() {
+		x, y := NewGVStruct(&Chess{ID: "B1"}), NewGVStruct(&Chess{ID: "B1"})
+		Expect(StructEqualityOf(reflect.TypeOf(x.V))).Should(Equal(StructEqualityIdentity))
+		Expect(x.Equal(y)).Should(BeFalse())
+		Expect(x.Equal(NewGVStruct(x.V))).Should(BeTrue())
+		Expect(x.Hash()).Should(Equal(NewGVStruct(x.V).Hash()))
+		Expect(NewGVStruct(struct{ L []int }{}).Equal(NewGVStruct(struct{ L []int }{}))).Should(BeFalse())
+	})
+
+	It("can compare structs deeply", func() {
+		x := NewGVStruct(&Address{City: "Paris", Street: "Rue de Rivoli"})
+		y := NewGVStruct(Address{City: "Paris", Street: "Rue de Rivoli"})
+		Expect(StructEqualityOf(reflect.TypeOf(Customer{}))).Should(Equal(StructEqualityIdentity))
+		Expect(StructEqualityOf(reflect.TypeOf(x.V))).Should(Equal(StructEqualityDeep))
+		Expect(x.Equal(y)).Should(BeTrue())
+		Expect(x.Hash()).Should(Equal(y.Hash()))
+		Expect(TestEqual(x, y)).Should(BeTrue())
+
+		z := NewGVStruct(&Address{City: "Paris"})
+		Expect(x.Equal(z)).Should(BeFalse())
+		Expect(x.Equal(NewGVStruct((*Address)(nil)))).Should(BeFalse())
+		Expect(NewGVStruct((*Address)(nil)).Equal(NewGVStruct((*Address)(nil)))).Should(BeTrue())
+		Expect(NewGVSet(x, y, z).Len()).Should(Equal(2))
+	})
+
+	It("can hash cyclic structs and structs with fields that cannot be hashed deeply", func() {
+		x, y := &Link{Name: "L"}, &Link{Name: "L"}
+		x.Next, y.Next = x, &Link{Name: "L", Next: y}
+		Expect(NewGVStruct(x).Equal(NewGVStruct(y))).Should(BeTrue())
+		Expect(NewGVStruct(x).Hash()).Should(Equal(NewGVStruct(y).Hash()))
+		Expect(NewGVStruct(x).Hash()).ShouldNot(Equal(NewGVStruct(&Link{Name: "M", Next: x}).Hash()))
+
+		z := &Link{Name: "L", Next: x, OnVisit: func() {}}
+		Expect(NewGVStruct(z).Hash()).Should(Equal(NewGVStruct(x).Hash()))
+		Expect(NewGVStruct(z).Equal(NewGVStruct(x))).Should(BeFalse())
+	})
+
+	It("can compare structs by their Equal methods", func() {
+		x := NewGVStruct(Version{Major: 1, Minor: 2, Note: "initial"})
+		y := NewGVStruct(&Version{Major: 1, Minor: 2})
+		Expect(x.Equal(y)).Should(BeTrue())
+		Expect(x.Hash()).Should(Equal(y.Hash()))
+		Expect(x.Equal(NewGVStruct(&Version{Major: 1, Minor: 3}))).Should(BeFalse())
+
+		Expect(func() { SetStructEquality[Address](StructEqualityMethod) }).Should(Panic())
+		Expect(func() { SetStructEquality[int](StructEqualityDeep) }).Should(Panic())
+		Expect(StructEqualityOf(reflect.TypeOf(&Address{}))).Should(Equal(StructEqualityDeep))
+	})
+
+	It("deduplicate facts with equal structs in working memory", func() {
+		an := NewAlphaNetwork()
+		am, err := an.MakeAlphaMem(TypeInfoOf[Address](), nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		an.AddFact(Fact{ID: "A1", Value: NewGVStruct(&Address{City: "Paris"})})
+		an.AddFact(Fact{ID: "A1", Value: NewGVStruct(&Address{City: "Paris"})})
+		Expect(am.NItems()).Should(Equal(1))
+		an.AddFact(Fact{ID: "A1", Value: NewGVStruct(&Address{City: "Lyon"})})
+		Expect(am.NItems()).Should(Equal(2))
+
+		an.RemoveFact(Fact{ID: "A1", Value: NewGVStruct(&Address{City: "Paris"})})
+		Expect(am.NItems()).Should(Equal(1))
+	})
+
+	It("keep unequal facts with the same hash in working memory", func() {
+		an := NewAlphaNetwork()
+		for _, tc := range []struct {
+			tf   TypeInfo
+			x, y any
+		}{
+			{TypeInfoOf[Badge](), Badge{Name: "gold"}, &Badge{Name: "silver"}},
+			{TypeInfoOf[Secret](), Secret{Name: "S", pin: 1}, &Secret{Name: "S", pin: 2}},
+		} {
+			am, err := an.MakeAlphaMem(tc.tf, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			x, y := Fact{ID: "X", Value: NewGVStruct(tc.x)}, Fact{ID: "X", Value: NewGVStruct(tc.y)}
+			Expect(x.Hash()).Should(Equal(y.Hash()))
+			Expect(x.Equal(y)).Should(BeFalse())
+
+			an.AddFact(x)
+			an.AddFact(y)
+			an.AddFact(x)
+			Expect(am.NItems()).Should(Equal(2))
+
+			an.RemoveFact(x)
+			Expect(am.NItems()).Should(Equal(1))
+			am.ForEachItem(func(w *WME) (stop bool) {
+				Expect(w.Value).Should(Equal(y.Value))
+				return false
+			})
+			an.RemoveFact(x)
+			Expect(am.NItems()).Should(Equal(1))
+			an.RemoveFact(y)
+			Expect(am.NItems()).Should(BeZero())
+		}
+	})
+
+	It("can join facts by equal structs", func() {
+		bn := NewBetaNetwork(NewAlphaNetwork())
+		pNode, err := bn.AddProduction(Production{
+			ID: "ship to customers",
+			When: []AliasDeclaration{
+				{Alias: "C", Type: TypeInfoOf[Customer]()},
+				{Alias: "S", Type: TypeInfoOf[Shipment]()},
+			},
+			Match: []JoinTest{
+				{Alias: []Selector{{"C", "Addr"}, {"S", "Addr"}}, TestOp: TestOpEqual},
+			},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		customers := []*Customer{
+			{ID: "C1", Addr: Address{City: "Paris", Street: "Rue de Rivoli"}},
+			{ID: "C2", Addr: Address{City: "Lyon"}},
+		}
+		shipments := []*Shipment{
+			{ID: "S1", Addr: &Address{City: "Paris", Street: "Rue de Rivoli"}},
+			{ID: "S2", Addr: &Address{City: "Paris"}},
+			{ID: "S3"},
+		}
+		for _, c := range customers {
+			bn.AddFact(Fact{ID: c.ID, Value: NewGVStruct(c)})
+		}
+		for _, s := range shipments {
+			bn.AddFact(Fact{ID: s.ID, Value: NewGVStruct(s)})
+		}
+		Expect(pNode.Matches()).Should(ConsistOf(
+			map[GVIdentity]any{"C": customers[0], "S": shipments[0]},
+		))
+	})
+})
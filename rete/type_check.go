@@ -0,0 +1,198 @@
+package rete
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	. "github.com/ccbhj/grete/types"
+)
+
+// TestOpError is returned when a Guard or a JoinTest is rejected since its TestOp cannot be performed on its operands,
+// like comparing a string field with an int
+type TestOpError struct {
+	Alias  GVIdentity // alias of the rejected operand, empty if it is unknown like in AlphaNetwork.MakeAlphaMem
+	Field  GVString   // field of the rejected operand
+	TestOp TestOp
+	cause  error
+}
+
+func (e *TestOpError) Error() string {
+	if e.Alias == "" {
+		return fmt.Sprintf("invalid TestOp %s on field %s: %s", e.TestOp, e.Field, e.cause)
+	}
+	return fmt.Sprintf("invalid TestOp %s on %s.%s: %s", e.TestOp, e.Alias, e.Field, e.cause)
+}
+
+func (e *TestOpError) Unwrap() error {
+	return e.cause
+}
+
+func (e *TestOpError) Cause() error {
+	return e.cause
+}
+
+func testOpErrorf(s Selector, op TestOp, f string, args ...any) error {
+	return &TestOpError{Alias: s.Alias, Field: s.AliasAttr, TestOp: op, cause: errors.Errorf(f, args...)}
+}
+
+// checkGuard check if g can be performed on the field of values of type ti
+func checkGuard(ti TypeInfo, g Guard) error {
+	s := Selector{AliasAttr: g.AliasAttr}
+	// the value of a guard is ignored by unary TestOps
+	types := []GValueType{fieldTypeOf(ti, g.AliasAttr)}
+	if g.TestOp.Arity() != 1 {
+		if g.Value == nil {
+			return testOpErrorf(s, g.TestOp, "value is missing")
+		}
+		if g.Value.Type() == GValueTypeIdentity {
+			return testOpErrorf(s, g.TestOp, "alias as value is not allowed in guard")
+		}
+		types = append([]GValueType{g.Value.Type()}, types...)
+	}
+	if _, err := bindTestValue(g.TestOp, g.Value); err != nil {
+		return &TestOpError{Field: g.AliasAttr, TestOp: g.TestOp, cause: err}
+	}
+	if err := checkOperandTypes(g.TestOp, types...); err != nil {
+		return &TestOpError{Field: g.AliasAttr, TestOp: g.TestOp, cause: err}
+	}
+	return nil
+}
+
+// checkJoinTest check if jt can be performed on the fields of the aliases declared in decls
+func checkJoinTest(decls map[GVIdentity]TypeInfo, jt JoinTest) error {
+	var first Selector
+	if len(jt.Alias) > 0 {
+		first = jt.Alias[0]
+	}
+	if arity := jt.TestOp.Arity(); arity != len(jt.Alias)+len(jt.Args) {
+		return testOpErrorf(first, jt.TestOp, "%d args are required, but got %d", arity, len(jt.Alias)+len(jt.Args))
+	}
+
+	types := make([]GValueType, 0, len(jt.Alias)+len(jt.Args))
+	for _, s := range jt.Alias {
+		ti, in := decls[s.Alias]
+		if !in {
+			return testOpErrorf(s, jt.TestOp, "unguarded alias %s", s.Alias)
+		}
+		types = append(types, fieldTypeOf(ti, s.AliasAttr))
+	}
+	for _, arg := range jt.Args {
+		if arg == nil {
+			return testOpErrorf(first, jt.TestOp, "arg is missing")
+		}
+		types = append(types, arg.Type())
+	}
+	if err := checkOperandTypes(jt.TestOp, types...); err != nil {
+		operands := make([]string, 0, len(jt.Alias))
+		for i, s := range jt.Alias {
+			operands = append(operands, fmt.Sprintf("%s.%s(%s)", s.Alias, s.AliasAttr, types[i]))
+		}
+		return testOpErrorf(first, jt.TestOp, "%s: %s", strings.Join(operands, ", "), err)
+	}
+	return nil
+}
+
+// fieldTypeOf return the GValueType of field f declared in ti or derived from ti.VT, or GValueTypeUnknown if it is unknown
+func fieldTypeOf(ti TypeInfo, f GVString) GValueType {
+	switch f {
+	case FieldSelf:
+		return ti.T
+	case FieldID:
+		return GValueTypeIdentity
+	}
+	return ti.FieldType(string(f))
+}
+
+// checkOperandTypes check if values of types can be passed to the builtin TestOp op,
+// GValueTypeUnknown matches any type, and user-defined GValues are trusted to handle any type,
+// TestOps registered by RegisterTestOp are not checked
+func checkOperandTypes(op TestOp, types ...GValueType) error {
+	if len(types) < op.Arity() {
+		return errors.Errorf("%d args are required, but got %d", op.Arity(), len(types))
+	}
+	switch op {
+	case TestOpEqual, TestOpNotEqual:
+		if !equalableTypes(types[0], types[1]) {
+			return errors.Errorf("values of type %s and %s are never equal", types[0], types[1])
+		}
+	case TestOpLess, TestOpGreater, TestOpLessEqual, TestOpGreaterEqual:
+		if !orderableTypes(types[0], types[1]) {
+			return errors.Errorf("cannot compare value of type %s with type %s", types[0], types[1])
+		}
+	case TestOpHasPrefix, TestOpHasSuffix, TestOpEqualFold, TestOpMatch:
+		if !anyTypeOf(types[0], GValueTypeString) || !anyTypeOf(types[1], GValueTypeString) {
+			return errors.Errorf("string args are required, but got %s and %s", types[0], types[1])
+		}
+	case TestOpContains:
+		switch {
+		case anyTypeOf(types[1], GValueTypeList, GValueTypeSet, GValueTypeMap):
+		case anyTypeOf(types[1], GValueTypeString) && anyTypeOf(types[0], GValueTypeString):
+		default:
+			return errors.Errorf("cannot look up value of type %s in type %s", types[0], types[1])
+		}
+	case TestOpIn, TestOpNotIn:
+		if !anyTypeOf(types[0], GValueTypeSet) {
+			return errors.Errorf("a set is required, but got %s", types[0])
+		}
+	case TestOpWithinAfter, TestOpWithinBefore:
+		if !anyTypeOf(types[0], GValueTypeTime) || !anyTypeOf(types[1], GValueTypeTime) ||
+			!anyTypeOf(types[2], GValueTypeDuration) {
+			return errors.Errorf("two times and a duration are required, but got %s, %s and %s",
+				types[0], types[1], types[2])
+		}
+	}
+	return nil
+}
+
+// anyTypeOf tell whether t is one of expected, GValueTypeUnknown and user-defined types match any of them
+func anyTypeOf(t GValueType, expected ...GValueType) bool {
+	if t == GValueTypeUnknown || t.IsCustom() {
+		return true
+	}
+	for _, e := range expected {
+		if t == e {
+			return true
+		}
+	}
+	return false
+}
+
+// equalableTypes tell whether values of type x may equal to values of type y,
+// nil can be compared with anything, so do numbers of different types
+func equalableTypes(x, y GValueType) bool {
+	switch {
+	case x == y,
+		x == GValueTypeUnknown || y == GValueTypeUnknown,
+		x == GValueTypeNil || y == GValueTypeNil,
+		x.IsCustom() || y.IsCustom(),
+		isNumberGValueType(x) && isNumberGValueType(y):
+		return true
+	}
+	return false
+}
+
+// orderableTypes tell whether values of type x can be compared with values of type y by compareValue
+func orderableTypes(x, y GValueType) bool {
+	switch {
+	case x == GValueTypeUnknown || y == GValueTypeUnknown,
+		x.IsCustom() || y.IsCustom(),
+		isNumberGValueType(x) && isNumberGValueType(y):
+		return true
+	case x == y:
+		switch x {
+		case GValueTypeString, GValueTypeTime, GValueTypeDuration:
+			return true
+		}
+	}
+	return false
+}
+
+func isNumberGValueType(t GValueType) bool {
+	switch t {
+	case GValueTypeInt, GValueTypeUint, GValueTypeFloat, GValueTypeDecimal:
+		return true
+	}
+	return false
+}
@@ -129,6 +129,19 @@ func mix64(x, y uint64) uint64 {
 	return hi ^ lo
 }
 
+// hashValues mix hashes of values into h, since values like GVDecimal are hidden from hashAny by unexported fields,
+// a nil GValue is mixed as 0
+func hashValues(h uint64, vs ...GValue) uint64 {
+	for _, v := range vs {
+		if v == nil {
+			h = mix64(h|1, 0)
+			continue
+		}
+		h = mix64(h|1, v.Hash()^uint64(v.Type())<<56)
+	}
+	return h
+}
+
 func typeOf[T any](v any) bool {
 	_, ok := v.(T)
 	return ok
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// FieldAccessor is implemented by structs that access their fields without reflection,
+// like those generated by cmd/gretegen, GVStruct uses it when it is implemented by the struct it holds
+type FieldAccessor interface {
+	GetField(f string) (GValue, any, error)
+	HasField(f string) bool
+}
+
+// GetFieldByReflect extract field value by field name `f` in struct v by reflection,
+// fields are named by their `grete` tags if any(see TagName),
+// `f` can be a dotted path of fields like `On.Color`, and each field can be followed by keys
+// to look up in a map or a slice, like `Labels["env"]` or `Tags[0]`,
+// GVNil is returned if a nil pointer is met along the path or a key is not found
+func GetFieldByReflect(v any, f string) (GValue, any, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil, nil, errors.New("nil value in TVStruct")
+	}
+
+	plan := fieldPlanOf(rv.Type(), f)
+	if plan.err != nil {
+		return nil, nil, plan.err
+	}
+	fv, found, err := plan.lookup(rv, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return &GVNil{}, nil, nil
+	}
+
+	conv := plan.conv
+	if conv == nil {
+		conv = converterOf(fv.Type())
+	}
+	ret, raw, err := conv(fv)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "field=%s", f)
+	}
+	return ret, raw, nil
+}
+
+// HasFieldByReflect check if struct v has field `f` by reflection
+func HasFieldByReflect(v any, f string) bool {
+	if v == nil {
+		return false
+	}
+	_, in := LookupFieldType(reflect.TypeOf(v), f)
+	return in
+}
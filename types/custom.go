@@ -0,0 +1,113 @@
+package types
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Comparable is implemented by user-defined GValues that are ordered, like decimals or IP addresses,
+// ordering TestOps like TestLess dispatch to Compare when their first argument implements it
+type Comparable interface {
+	GValue
+	// Compare return a negative number when the value < w, zero when the value == w,
+	// or a positive number when the value > w, and an error if they cannot be compared
+	Compare(w GValue) (int, error)
+}
+
+// maxGValueType is the max number of GValueTypes limited by the size of GValueType
+const maxGValueType = 1 << 8
+
+// customGValueTab is the table of GValueTypes registered by RegisterGValueType
+type customGValueTab struct {
+	names   []string       // indexed by GValueType - GValueTypeCustom
+	rtypes  []reflect.Type // indexed by GValueType - GValueTypeCustom
+	byRType map[reflect.Type]GValueType
+}
+
+var (
+	customGValueMu sync.Mutex // held when registering a GValueType
+	// customGValues is replaced instead of modified in RegisterGValueType so that it can be read without locking
+	customGValues atomic.Pointer[customGValueTab]
+)
+
+func init() {
+	customGValues.Store(&customGValueTab{byRType: make(map[reflect.Type]GValueType)})
+}
+
+// RegisterGValueType register V as a user-defined GValue named `name`, and return the GValueType of it,
+// which should be what V.Type() returns, like
+//
+//	var GValueTypeIP = RegisterGValueType[IP]("IP")
+//
+//	func (IP) Type() GValueType { return GValueTypeIP }
+//
+// Fields of type V or *V in a struct are wrapped into V as they are when they are accessed by GetField,
+// TestEqual dispatches to V.Equal, ordering TestOps dispatch to V.Compare if V implements Comparable,
+// and TypeTestNode test values of V by the GValueType.
+// RegisterGValueType panics if the name or V is registered, so it is usually called in init().
+func RegisterGValueType[V GValue](name string) GValueType {
+	rt := reflect.TypeOf((*V)(nil)).Elem()
+	if name == "" || rt.Kind() == reflect.Interface {
+		panic(errors.Errorf("invalid GValueType %q of %s", name, rt))
+	}
+
+	customGValueMu.Lock()
+	defer customGValueMu.Unlock()
+	old := customGValues.Load()
+	if _, in := rType2testValueType[rt]; in {
+		panic(errors.Errorf("GValue %s is already registered", rt))
+	}
+	if _, in := old.byRType[rt]; in {
+		panic(errors.Errorf("GValue %s is already registered", rt))
+	}
+	for _, n := range gValueTypeDict {
+		if n == name {
+			panic(errors.Errorf("GValueType %q is already registered", name))
+		}
+	}
+	for _, n := range old.names {
+		if n == name {
+			panic(errors.Errorf("GValueType %q is already registered", name))
+		}
+	}
+	n := int(GValueTypeCustom) + len(old.names)
+	if n >= maxGValueType {
+		panic(errors.Errorf("too many GValueTypes, cannot register %q", name))
+	}
+	t := GValueType(n)
+
+	tab := &customGValueTab{
+		names:   append(old.names[:len(old.names):len(old.names)], name),
+		rtypes:  append(old.rtypes[:len(old.rtypes):len(old.rtypes)], rt),
+		byRType: make(map[reflect.Type]GValueType, len(old.byRType)+1),
+	}
+	for k, v := range old.byRType {
+		tab.byRType[k] = v
+	}
+	tab.byRType[rt] = t
+	customGValues.Store(tab)
+	return t
+}
+
+// IsCustom tell whether t is registered by RegisterGValueType
+func (t GValueType) IsCustom() bool {
+	return t >= GValueTypeCustom
+}
+
+// lookupCustomGValueType tell the GValueType of values of type t if they are,
+// or they are pointers to, GValues registered by RegisterGValueType
+func lookupCustomGValueType(t reflect.Type) (vt GValueType, ptr bool, ok bool) {
+	tab := customGValues.Load()
+	if vt, ok = tab.byRType[t]; ok {
+		return vt, false, true
+	}
+	if t.Kind() == reflect.Pointer {
+		if vt, ok = tab.byRType[t.Elem()]; ok {
+			return vt, true, true
+		}
+	}
+	return GValueTypeUnknown, false, false
+}
@@ -0,0 +1,164 @@
+package types
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dolthub/maphash"
+	"github.com/pkg/errors"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+)
+
+// GVDecimal is an arbitrary-precision decimal like a money amount, which is compared exactly,
+// it is immutable, and the zero value is 0
+type GVDecimal struct {
+	r *big.Rat
+}
+
+// NewGVDecimal parse a decimal like "19.99" or "1.5e3"
+func NewGVDecimal(s string) (GVDecimal, error) {
+	r, ok := new(big.Rat).SetString(strings.ReplaceAll(s, "_", ""))
+	if !ok || strings.ContainsRune(s, '/') {
+		return GVDecimal{}, errors.Errorf("invalid decimal %q", s)
+	}
+	return GVDecimal{r: r}, nil
+}
+
+// NewGVDecimalFromRat create a GVDecimal from a copy of r
+func NewGVDecimalFromRat(r *big.Rat) GVDecimal {
+	return GVDecimal{r: new(big.Rat).Set(r)}
+}
+
+// NewGVDecimalFromInt create a GVDecimal from a copy of i
+func NewGVDecimalFromInt(i *big.Int) GVDecimal {
+	return GVDecimal{r: new(big.Rat).SetInt(i)}
+}
+
+var tvDecimalHasher = maphash.NewHasher[string]()
+
+func (GVDecimal) Type() GValueType      { return GValueTypeDecimal }
+func (v GVDecimal) Hash() uint64        { return tvDecimalHasher.Hash(v.rat().RatString()) }
+func (v GVDecimal) RType() reflect.Type { return reflect.TypeOf(v) }
+func (v GVDecimal) Equal(w GValue) bool {
+	return w != nil && w.Type() == GValueTypeDecimal && v.Cmp(w.(GVDecimal)) == 0
+}
+func (v GVDecimal) GetField(f string) (GValue, any, error) {
+	if f != FieldSelf {
+		return nil, nil, ErrFieldNotFound
+	}
+	return v, v.Rat(), nil
+}
+func (v GVDecimal) ToGoValue() any { return v.Rat() }
+
+// Rat return a copy of the value
+func (v GVDecimal) Rat() *big.Rat { return new(big.Rat).Set(v.rat()) }
+
+// Cmp return -1 when v < w, 0 when v == w, or 1 when v > w
+func (v GVDecimal) Cmp(w GVDecimal) int { return v.rat().Cmp(w.rat()) }
+
+// String format the decimal without losing any digit if it is a finite decimal, like "19.99",
+// or a fraction like "1/3" if it is not
+func (v GVDecimal) String() string {
+	r := v.rat()
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// a fraction is a finite decimal only if its denominator is in form of 2^m*5^n
+	d := new(big.Int).Set(r.Denom())
+	var twos, fives int
+	for d.Bit(0) == 0 {
+		d.Rsh(d, 1)
+		twos++
+	}
+	five, m := big.NewInt(5), new(big.Int)
+	for {
+		q, rem := new(big.Int).QuoRem(d, five, m)
+		if rem.Sign() != 0 {
+			break
+		}
+		d = q
+		fives++
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return r.RatString()
+	}
+	return r.FloatString(max(twos, fives))
+}
+
+func (v GVDecimal) rat() *big.Rat {
+	if v.r == nil {
+		return new(big.Rat)
+	}
+	return v.r
+}
+
+// DecimalOf convert a number into GVDecimal exactly, floats are converted by their shortest representations,
+// so that GVFloat(0.1) is converted into 0.1 but not 0.1000000000000000055511151231257827
+func DecimalOf(v GValue) (GVDecimal, bool) {
+	switch v := v.(type) {
+	case GVDecimal:
+		return v, true
+	case GVInt:
+		return GVDecimal{r: new(big.Rat).SetInt64(int64(v))}, true
+	case GVUint:
+		return GVDecimal{r: new(big.Rat).SetUint64(uint64(v))}, true
+	case GVFloat:
+		if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+			return GVDecimal{}, false
+		}
+		r, ok := new(big.Rat).SetString(strconv.FormatFloat(float64(v), 'g', -1, 64))
+		return GVDecimal{r: r}, ok
+	}
+	return GVDecimal{}, false
+}
+
+// bigNumberConverter build the converter of big.Int, big.Rat and big.Float, ok is false if t is not one of them
+func bigNumberConverter(t reflect.Type) (conv gvalueConv, ok bool) {
+	ptr := t.Kind() == reflect.Pointer
+	if ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case bigIntType:
+		conv = func(v reflect.Value) (GValue, any, error) {
+			return NewGVDecimalFromInt(bigNumberPtr(v, ptr).(*big.Int)), v.Interface(), nil
+		}
+	case bigRatType:
+		conv = func(v reflect.Value) (GValue, any, error) {
+			return NewGVDecimalFromRat(bigNumberPtr(v, ptr).(*big.Rat)), v.Interface(), nil
+		}
+	case bigFloatType:
+		conv = func(v reflect.Value) (GValue, any, error) {
+			f := bigNumberPtr(v, ptr).(*big.Float)
+			if f.IsInf() {
+				return nil, nil, errors.Errorf("cannot convert %s into decimal", f)
+			}
+			r, _ := f.Rat(nil)
+			return GVDecimal{r: r}, v.Interface(), nil
+		}
+	default:
+		return nil, false
+	}
+	return conv, true
+}
+
+// bigNumberPtr return the pointer to the big number in v
+func bigNumberPtr(v reflect.Value, ptr bool) any {
+	if ptr {
+		return v.Interface()
+	}
+	if v.CanAddr() {
+		return v.Addr().Interface()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface()
+}
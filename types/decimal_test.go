@@ -0,0 +1,90 @@
+package types
+
+import (
+	"math"
+	"math/big"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GVDecimal", func() {
+	mustDecimal := func(s string) GVDecimal {
+		d, err := NewGVDecimal(s)
+		Expect(err).ShouldNot(HaveOccurred())
+		return d
+	}
+
+	DescribeTable("parsing and formatting decimals",
+		func(s, expected string) {
+			Expect(mustDecimal(s).String()).Should(Equal(expected))
+		},
+		Entry("an integer", "42", "42"),
+		Entry("a finite decimal", "19.99", "19.99"),
+		Entry("an exponent", "1.5e3", "1500"),
+		Entry("a negative decimal", "-0.125", "-0.125"),
+		Entry("underscores", "1_000.5", "1000.5"),
+	)
+
+	It("reject invalid decimals", func() {
+		for _, s := range []string{"", "abc", "1/3", "1.2.3"} {
+			_, err := NewGVDecimal(s)
+			Expect(err).Should(HaveOccurred(), s)
+		}
+	})
+
+	It("compare and hash decimals exactly", func() {
+		x, y := mustDecimal("0.1"), mustDecimal("0.10")
+		Expect(x.Equal(y)).Should(BeTrue())
+		Expect(x.Hash()).Should(Equal(y.Hash()))
+		Expect(x.Cmp(mustDecimal("0.1000000000000000001"))).Should(Equal(-1))
+		Expect(x.Equal(GVFloat(0.1))).Should(BeFalse())
+		Expect(GVDecimal{}.Equal(mustDecimal("0"))).Should(BeTrue())
+		Expect(GVDecimal{}.String()).Should(Equal("0"))
+	})
+
+	It("format fractions that are not finite decimals", func() {
+		Expect(NewGVDecimalFromRat(big.NewRat(1, 3)).String()).Should(Equal("1/3"))
+		Expect(NewGVDecimalFromRat(big.NewRat(1, 40)).String()).Should(Equal("0.025"))
+	})
+
+	It("is immutable", func() {
+		r := big.NewRat(1, 2)
+		d := NewGVDecimalFromRat(r)
+		r.SetInt64(2)
+		d.Rat().SetInt64(3)
+		Expect(d.String()).Should(Equal("0.5"))
+	})
+
+	It("convert numbers into decimals", func() {
+		d, ok := DecimalOf(GVFloat(0.1))
+		Expect(ok).Should(BeTrue())
+		Expect(d.Equal(mustDecimal("0.1"))).Should(BeTrue())
+		d, ok = DecimalOf(GVInt(-3))
+		Expect(ok).Should(BeTrue())
+		Expect(d.String()).Should(Equal("-3"))
+		d, ok = DecimalOf(GVUint(math.MaxUint64))
+		Expect(ok).Should(BeTrue())
+		Expect(d.String()).Should(Equal("18446744073709551615"))
+
+		for _, v := range []GValue{GVFloat(math.NaN()), GVFloat(math.Inf(1)), GVString("1")} {
+			_, ok = DecimalOf(v)
+			Expect(ok).Should(BeFalse())
+		}
+	})
+
+	It("wrap big numbers in fields into decimals", func() {
+		v := struct {
+			I *big.Int
+			R big.Rat
+			F *big.Float
+		}{I: big.NewInt(7), R: *big.NewRat(3, 4), F: big.NewFloat(0.5)}
+		for f, expected := range map[string]string{"I": "7", "R": "0.75", "F": "0.5"} {
+			d, _, err := GetFieldByReflect(&v, f)
+			Expect(err).ShouldNot(HaveOccurred(), f)
+			Expect(d).Should(BeAssignableToTypeOf(GVDecimal{}), f)
+			Expect(d.(GVDecimal).String()).Should(Equal(expected), f)
+		}
+		Expect(GValueTypeOf[*big.Int]()).Should(Equal(GValueTypeDecimal))
+	})
+})
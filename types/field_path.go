@@ -0,0 +1,168 @@
+package types
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fieldStep is a field of struct followed by keys to look up,
+// like `Labels["env"]` for a map or `Tags[0]` for a slice
+type fieldStep struct {
+	name string
+	keys []any // string or int64
+}
+
+// fieldPath is a dotted path of fieldSteps like `On.Labels["env"]`
+type fieldPath []fieldStep
+
+func parseFieldPath(f string) (fieldPath, error) {
+	path := make(fieldPath, 0, 1)
+	for rest := f; ; {
+		var (
+			step fieldStep
+			err  error
+		)
+		step, rest, err = parseFieldStep(f, rest)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, step)
+		if rest == "" {
+			return path, nil
+		}
+		// parseFieldStep stops at '.' only
+		rest = rest[1:]
+	}
+}
+
+// parseFieldStep parse a fieldStep at the beginning of rest, and return what is left after it
+func parseFieldStep(f, rest string) (fieldStep, string, error) {
+	i := strings.IndexAny(rest, ".[")
+	if i < 0 {
+		i = len(rest)
+	}
+	step := fieldStep{name: rest[:i]}
+	if step.name == "" {
+		return fieldStep{}, "", errors.Errorf("missing field name in %s", f)
+	}
+
+	for rest = rest[i:]; len(rest) > 0 && rest[0] != '.'; {
+		if rest[0] != '[' {
+			return fieldStep{}, "", errors.Errorf("expecting '[' in field %s", f)
+		}
+		var (
+			key any
+			end int
+		)
+		if len(rest) > 1 && rest[1] == '"' {
+			quoted, err := strconv.QuotedPrefix(rest[1:])
+			if err != nil {
+				return fieldStep{}, "", errors.WithMessagef(err, "invalid key in field %s", f)
+			}
+			key, _ = strconv.Unquote(quoted)
+			end = 1 + len(quoted)
+		} else {
+			end = strings.IndexByte(rest, ']')
+			if end < 0 {
+				return fieldStep{}, "", errors.Errorf("missing ']' in field %s", f)
+			}
+			idx, err := strconv.ParseInt(rest[1:end], 10, 64)
+			if err != nil {
+				return fieldStep{}, "", errors.WithMessagef(err, "invalid index in field %s", f)
+			}
+			key = idx
+		}
+		if end >= len(rest) || rest[end] != ']' {
+			return fieldStep{}, "", errors.Errorf("missing ']' in field %s", f)
+		}
+		step.keys = append(step.keys, key)
+		rest = rest[end+1:]
+	}
+	return step, rest, nil
+}
+
+// lookupField look up the value of field f in struct v by path,
+// ok is false if a nil pointer is met along the path, or a key is not found
+func lookupField(v reflect.Value, f string, path fieldPath) (ret reflect.Value, ok bool, err error) {
+	for _, step := range path {
+		if v, ok = indirect(v); !ok {
+			return reflect.Value{}, false, nil
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false, errors.WithMessagef(ErrFieldNotFound,
+				"field=%s, %s is not a struct", f, v.Type())
+		}
+		sf, in := fieldByName(v.Type(), step.name)
+		if !in {
+			return reflect.Value{}, false, errors.WithMessagef(ErrFieldNotFound, "field=%s", f)
+		}
+		if v, err = v.FieldByIndexErr(sf.index); err != nil {
+			// nil pointer to an embedded struct
+			return reflect.Value{}, false, nil
+		}
+		for _, key := range step.keys {
+			if v, ok, err = lookupKey(v, key); err != nil {
+				return reflect.Value{}, false, errors.WithMessagef(err, "field=%s", f)
+			}
+			if !ok {
+				return reflect.Value{}, false, nil
+			}
+		}
+	}
+	return v, true, nil
+}
+
+// indirect dereference pointers and interfaces until a value that is neither of them,
+// ok is false if any of them is nil
+func indirect(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+// lookupKey look up the value of key in a map, slice or array,
+// ok is false if the key is not found, or the index is out of range
+func lookupKey(v reflect.Value, key any) (ret reflect.Value, ok bool, err error) {
+	if v, ok = indirect(v); !ok {
+		return reflect.Value{}, false, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		kv := reflect.ValueOf(key)
+		kt := v.Type().Key()
+		if !kv.CanConvert(kt) || (kv.Kind() == reflect.Int64 && kt.Kind() == reflect.String) {
+			return reflect.Value{}, false, errors.Errorf("cannot use %v as key of %s", key, v.Type())
+		}
+		ret = v.MapIndex(kv.Convert(kt))
+		return ret, ret.IsValid(), nil
+	case reflect.Slice, reflect.Array:
+		idx, isIdx := key.(int64)
+		if !isIdx {
+			return reflect.Value{}, false, errors.Errorf("cannot use %q as index of %s", key, v.Type())
+		}
+		if idx < 0 || idx >= int64(v.Len()) {
+			return reflect.Value{}, false, nil
+		}
+		return v.Index(int(idx)), true, nil
+	}
+	return reflect.Value{}, false, errors.Errorf("cannot look up key %v in %s", key, v.Type())
+}
+
+// LookupFieldType return the type of field f in struct type t,
+// f can be a dotted path of fields followed by keys like `On.Labels["env"]`,
+// the type of an interface is returned if it is met along the path since what is in it is unknown
+func LookupFieldType(t reflect.Type, f string) (reflect.Type, bool) {
+	if t = derefType(t); t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	plan := fieldPlanOf(t, f)
+	return plan.typ, plan.err == nil && plan.typ != nil
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("field path", func() {
+	DescribeTable("parsing field paths",
+		func(f string, expected fieldPath) {
+			path, err := parseFieldPath(f)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(path).Should(Equal(expected))
+		},
+		Entry("a field", "Color", fieldPath{{name: "Color"}}),
+		Entry("nested fields", "On.On.Rank", fieldPath{{name: "On"}, {name: "On"}, {name: "Rank"}}),
+		Entry("a map key", `Labels["env"]`, fieldPath{{name: "Labels", keys: []any{"env"}}}),
+		Entry("a quoted key with dots and brackets", `Labels["a.b[0]"].Name`,
+			fieldPath{{name: "Labels", keys: []any{"a.b[0]"}}, {name: "Name"}}),
+		Entry("indexes", "Tags[0][-1]", fieldPath{{name: "Tags", keys: []any{int64(0), int64(-1)}}}),
+		Entry("keys in the middle", `On.Tags[1].Labels["x"]`,
+			fieldPath{{name: "On"}, {name: "Tags", keys: []any{int64(1)}}, {name: "Labels", keys: []any{"x"}}}),
+	)
+
+	DescribeTable("rejecting invalid field paths",
+		func(f string) {
+			_, err := parseFieldPath(f)
+			Expect(err).Should(HaveOccurred())
+		},
+		Entry("empty", ""),
+		Entry("empty step", "On..Rank"),
+		Entry("trailing dot", "On."),
+		Entry("missing name", `["env"]`),
+		Entry("unclosed bracket", "Tags[0"),
+		Entry("unclosed quote", `Labels["env]`),
+		Entry("quoted key without bracket", `Labels["env"`),
+		Entry("invalid index", "Tags[x]"),
+		Entry("garbage after a key", "Tags[0]x"),
+	)
+})
@@ -0,0 +1,268 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// fieldPlan is how to access a field path in a struct type, it is resolved once by types and cached
+type fieldPlan struct {
+	path fieldPath
+	// index sequences of the leading steps in path, it is shorter than path if an interface is met,
+	// and the rest steps are resolved by what is in the interface at runtime
+	indexes [][]int
+	typ     reflect.Type // type of the field, or the type of the interface met along the path
+	conv    gvalueConv   // converter of the field, nil if its type is unknown until runtime
+	err     error        // why the field cannot be accessed
+}
+
+type fieldPlanKey struct {
+	t reflect.Type
+	f string
+}
+
+var fieldPlanCache sync.Map // map[fieldPlanKey]*fieldPlan
+
+// fieldPlanOf return the plan to access field f in struct type t
+func fieldPlanOf(t reflect.Type, f string) *fieldPlan {
+	key := fieldPlanKey{t: t, f: f}
+	if plan, ok := fieldPlanCache.Load(key); ok {
+		return plan.(*fieldPlan)
+	}
+	plan, _ := fieldPlanCache.LoadOrStore(key, buildFieldPlan(t, f))
+	return plan.(*fieldPlan)
+}
+
+func buildFieldPlan(t reflect.Type, f string) *fieldPlan {
+	path, err := parseFieldPath(f)
+	if err != nil {
+		return &fieldPlan{err: err}
+	}
+
+	plan := &fieldPlan{path: path, indexes: make([][]int, 0, len(path))}
+	ft := t
+	for _, step := range path {
+		ft = derefType(ft)
+		switch ft.Kind() {
+		case reflect.Struct:
+		case reflect.Interface:
+			plan.typ = ft
+			return plan
+		default:
+			return &fieldPlan{err: errors.WithMessagef(ErrFieldNotFound, "field=%s, %s is not a struct", f, ft)}
+		}
+		sf, in := fieldByName(ft, step.name)
+		if !in {
+			return &fieldPlan{err: errors.WithMessagef(ErrFieldNotFound, "field=%s", f)}
+		}
+		plan.indexes = append(plan.indexes, sf.index)
+
+		// keys are always looked up at runtime, so their types are only checked here
+		ft = sf.typ
+		for _, key := range step.keys {
+			ft = derefType(ft)
+			switch ft.Kind() {
+			case reflect.Map:
+				if _, isIdx := key.(int64); isIdx && ft.Key().Kind() == reflect.String {
+					return plan
+				}
+			case reflect.Slice, reflect.Array:
+				if _, isIdx := key.(int64); !isIdx {
+					return plan
+				}
+			case reflect.Interface:
+				plan.typ = ft
+				return plan
+			default:
+				return plan
+			}
+			ft = ft.Elem()
+		}
+	}
+	plan.typ = ft
+	plan.conv = converterOf(ft)
+	return plan
+}
+
+// lookup look up the field in struct v by the plan
+func (p *fieldPlan) lookup(v reflect.Value, f string) (ret reflect.Value, ok bool, err error) {
+	for i, step := range p.path {
+		if v, ok = indirect(v); !ok {
+			return reflect.Value{}, false, nil
+		}
+		if i >= len(p.indexes) {
+			return lookupField(v, f, p.path[i:])
+		}
+		if v, err = v.FieldByIndexErr(p.indexes[i]); err != nil {
+			// nil pointer to an embedded struct
+			return reflect.Value{}, false, nil
+		}
+		for _, key := range step.keys {
+			if v, ok, err = lookupKey(v, key); err != nil {
+				return reflect.Value{}, false, errors.WithMessagef(err, "field=%s", f)
+			}
+			if !ok {
+				return reflect.Value{}, false, nil
+			}
+		}
+	}
+	return v, true, nil
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// gvalueConv wrap a value into GValue, and return it with the underlying value
+type gvalueConv func(reflect.Value) (GValue, any, error)
+
+var converterCache sync.Map // map[reflect.Type]gvalueConv
+
+// converterOf return the converter for values of type t
+func converterOf(t reflect.Type) gvalueConv {
+	if conv, ok := converterCache.Load(t); ok {
+		return conv.(gvalueConv)
+	}
+	conv, _ := converterCache.LoadOrStore(t, buildConverter(t))
+	return conv.(gvalueConv)
+}
+
+func buildConverter(t reflect.Type) gvalueConv {
+	conv := buildNonNilConverter(t)
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return func(v reflect.Value) (GValue, any, error) {
+			if v.IsNil() {
+				return &GVNil{}, nil, nil
+			}
+			return conv(v)
+		}
+	}
+	return conv
+}
+
+// buildNonNilConverter build the converter for values of type t that are not nil
+func buildNonNilConverter(t reflect.Type) gvalueConv {
+	if _, in := rType2testValueType[t]; in {
+		return func(v reflect.Value) (GValue, any, error) {
+			ret := v.Interface()
+			return ret.(GValue), ret, nil
+		}
+	}
+	if _, ptr, in := lookupCustomGValueType(t); in {
+		return func(v reflect.Value) (GValue, any, error) {
+			ret := v.Interface()
+			if ptr {
+				return v.Elem().Interface().(GValue), ret, nil
+			}
+			return ret.(GValue), ret, nil
+		}
+	}
+
+	if conv, ok := bigNumberConverter(t); ok {
+		return conv
+	}
+
+	// time.Duration is an int64, time.Time is a struct and json.Number is a string,
+	// so check them before their kinds
+	switch t {
+	case jsonNumberType:
+		return func(v reflect.Value) (GValue, any, error) {
+			n := v.Interface().(json.Number)
+			if i, err := n.Int64(); err == nil {
+				return GVInt(i), n, nil
+			}
+			f, err := n.Float64()
+			if err != nil {
+				return nil, nil, errors.WithMessagef(err, "invalid number %s", n)
+			}
+			return GVFloat(f), n, nil
+		}
+	case durationType:
+		return func(v reflect.Value) (GValue, any, error) {
+			return GVDuration(v.Int()), v.Interface(), nil
+		}
+	case timeType:
+		return func(v reflect.Value) (GValue, any, error) {
+			raw := v.Interface()
+			return GVTime{Time: raw.(time.Time)}, raw, nil
+		}
+	case reflect.PointerTo(timeType):
+		return func(v reflect.Value) (GValue, any, error) {
+			return GVTime{Time: v.Elem().Interface().(time.Time)}, v.Interface(), nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(v reflect.Value) (GValue, any, error) {
+			return GVInt(v.Int()), v.Interface(), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(v reflect.Value) (GValue, any, error) {
+			return GVUint(v.Uint()), v.Interface(), nil
+		}
+	case reflect.String:
+		return func(v reflect.Value) (GValue, any, error) {
+			return GVString(v.String()), v.Interface(), nil
+		}
+	case reflect.Bool:
+		return func(v reflect.Value) (GValue, any, error) {
+			return GVBool(v.Bool()), v.Interface(), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(v reflect.Value) (GValue, any, error) {
+			return GVFloat(v.Float()), v.Interface(), nil
+		}
+	case reflect.Pointer, reflect.Struct:
+		return func(v reflect.Value) (GValue, any, error) {
+			ret := v.Interface()
+			return &GVStruct{V: ret}, ret, nil
+		}
+	case reflect.Interface:
+		return func(v reflect.Value) (GValue, any, error) {
+			return toGValue(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		return func(v reflect.Value) (GValue, any, error) {
+			// element converter is looked up at runtime in case of recursive types
+			conv := converterOf(t.Elem())
+			elems := make([]GValue, 0, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				e, _, err := conv(v.Index(i))
+				if err != nil {
+					return nil, nil, err
+				}
+				elems = append(elems, e)
+			}
+			return NewGVList(elems...), v.Interface(), nil
+		}
+	case reflect.Map:
+		return func(v reflect.Value) (GValue, any, error) {
+			keyConv, valueConv := converterOf(t.Key()), converterOf(t.Elem())
+			entries := make([]GVMapEntry, 0, v.Len())
+			for it := v.MapRange(); it.Next(); {
+				k, _, err := keyConv(it.Key())
+				if err != nil {
+					return nil, nil, err
+				}
+				e, _, err := valueConv(it.Value())
+				if err != nil {
+					return nil, nil, err
+				}
+				entries = append(entries, GVMapEntry{Key: k, Value: e})
+			}
+			return NewGVMap(entries...), v.Interface(), nil
+		}
+	}
+	return func(v reflect.Value) (GValue, any, error) {
+		return nil, nil, errors.Errorf("unsupported type %s to get", t)
+	}
+}
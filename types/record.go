@@ -0,0 +1,128 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// GVRecord is a schema-less fact whose fields are the keys of a map, like an object decoded from JSON,
+// it can be matched by the same TypeInfo of a struct if it has all the fields of the TypeInfo
+type GVRecord struct {
+	M map[string]any
+}
+
+func NewGVRecord(m map[string]any) *GVRecord {
+	return &GVRecord{M: m}
+}
+
+// NewGVRecordFromJSON decode a JSON object into GVRecord,
+// numbers are kept as json.Number so that integers are wrapped into GVInt and the others into GVFloat
+func NewGVRecordFromJSON(data []byte) (*GVRecord, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, errors.WithMessage(err, "fail to decode record")
+	}
+	if m == nil {
+		return nil, errors.New("fail to decode record: null")
+	}
+	return NewGVRecord(m), nil
+}
+
+func (*GVRecord) Type() GValueType      { return GValueTypeRecord }
+func (v *GVRecord) RType() reflect.Type { return reflect.TypeOf(v) }
+func (v *GVRecord) ToGoValue() any      { return v.M }
+
+// Hash of a record is the identity of its map, just like a GVStruct holding a pointer
+func (v *GVRecord) Hash() uint64 {
+	return mixHash(uint64(reflect.ValueOf(v.M).Pointer()))
+}
+
+func (v *GVRecord) Equal(w GValue) bool {
+	if w == nil || w.Type() != GValueTypeRecord {
+		return false
+	}
+	return reflect.ValueOf(v.M).Pointer() == reflect.ValueOf(w.(*GVRecord).M).Pointer()
+}
+
+// GetField extract the value of key `f` in the record, and wrap it into GValue,
+// `f` is a field path like the one in GetFieldByReflect, nested objects are looked up by their keys,
+// and structs met along the path are looked up by reflection
+func (v *GVRecord) GetField(f string) (GValue, any, error) {
+	raw, found, err := v.lookup(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found || raw == nil {
+		return &GVNil{}, nil, nil
+	}
+	ret, _, err := toGValue(reflect.ValueOf(raw))
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "field=%s", f)
+	}
+	return ret, raw, nil
+}
+
+// HasField check if the record has field path `f`, a null is a value of the field it is at,
+// but a record has no field behind a null, or a key that is not in its map or slice
+func (v *GVRecord) HasField(f string) bool {
+	_, found, err := v.lookup(f)
+	return found && err == nil
+}
+
+// lookup look up the raw value of field path f,
+// found is false if a null or a nil pointer is met along the path, or a key is not found
+func (v *GVRecord) lookup(f string) (ret any, found bool, err error) {
+	path, err := parseFieldPath(f)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ret = v.M
+	for i, step := range path {
+		if ret == nil {
+			return nil, false, nil
+		}
+		m, isObj := ret.(map[string]any)
+		if !isObj {
+			rv, ok := indirect(reflect.ValueOf(ret))
+			if !ok {
+				return nil, false, nil
+			}
+			if rv.Kind() != reflect.Struct {
+				return nil, false, errors.WithMessagef(ErrFieldNotFound,
+					"field=%s, %s is not an object", f, rv.Type())
+			}
+			fv, ok, err := lookupField(rv, f, path[i:])
+			if err != nil || !ok {
+				return nil, false, err
+			}
+			return fv.Interface(), true, nil
+		}
+
+		var in bool
+		if ret, in = m[step.name]; !in {
+			return nil, false, errors.WithMessagef(ErrFieldNotFound, "field=%s", f)
+		}
+		for _, key := range step.keys {
+			if ret == nil {
+				return nil, false, nil
+			}
+			kv, ok, err := lookupKey(reflect.ValueOf(ret), key)
+			if err != nil {
+				return nil, false, errors.WithMessagef(err, "field=%s", f)
+			}
+			if !ok {
+				return nil, false, nil
+			}
+			ret = kv.Interface()
+		}
+	}
+	return ret, true, nil
+}
@@ -0,0 +1,79 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GVRecord", func() {
+	type owner struct {
+		Name string
+		Pets []string
+	}
+	var rec *GVRecord
+
+	BeforeEach(func() {
+		var err error
+		rec, err = NewGVRecordFromJSON([]byte(`{
+			"Color": "red", "Rank": 1, "Weight": 1.5, "On": null,
+			"Labels": {"env": "prod", "team": null},
+			"Tags": ["a", "b"]
+		}`))
+		Expect(err).ShouldNot(HaveOccurred())
+		rec.M["Owner"] = &owner{Name: "bob", Pets: []string{"cat"}}
+		rec.M["Nobody"] = (*owner)(nil)
+	})
+
+	DescribeTable("getting fields",
+		func(f string, expected GValue) {
+			v, _, err := rec.GetField(f)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(v).Should(Equal(expected))
+		},
+		Entry("a string", "Color", GVString("red")),
+		Entry("an integer", "Rank", GVInt(1)),
+		Entry("a float", "Weight", GVFloat(1.5)),
+		Entry("a null", "On", &GVNil{}),
+		Entry("a key of a nested object", "Labels.env", GVString("prod")),
+		Entry("a key in brackets", `Labels["env"]`, GVString("prod")),
+		Entry("an index", "Tags[1]", GVString("b")),
+		Entry("a field of a struct", "Owner.Name", GVString("bob")),
+		Entry("an index in a struct", "Owner.Pets[0]", GVString("cat")),
+		Entry("a field behind a null", "On.Color", &GVNil{}),
+		Entry("a field behind a nil pointer", "Nobody.Name", &GVNil{}),
+		Entry("a missing key in brackets", `Labels["x"]`, &GVNil{}),
+		Entry("an index out of range", "Tags[2]", &GVNil{}),
+	)
+
+	It("fail to get missing fields", func() {
+		for _, f := range []string{"Missing", "Labels.x", "Owner.Missing", "Color.Length", "Tags..x"} {
+			_, _, err := rec.GetField(f)
+			Expect(err).Should(HaveOccurred(), f)
+		}
+	})
+
+	It("has the fields that are found", func() {
+		for _, f := range []string{"Color", "On", "Labels.env", "Labels.team", `Labels["team"]`, "Tags[0]", "Owner.Pets"} {
+			Expect(rec.HasField(f)).Should(BeTrue(), f)
+		}
+		for _, f := range []string{
+			"Missing", "Labels.x", `Labels["x"]`, "Tags[2]", "On.Color", "Labels.team.x", "Nobody.Name", "Owner.Missing",
+		} {
+			Expect(rec.HasField(f)).Should(BeFalse(), f)
+		}
+	})
+
+	It("is compared by the identity of its map", func() {
+		Expect(rec.Equal(NewGVRecord(rec.M))).Should(BeTrue())
+		Expect(rec.Hash()).Should(Equal(NewGVRecord(rec.M).Hash()))
+		Expect(rec.Equal(NewGVRecord(map[string]any{"Color": "red"}))).Should(BeFalse())
+		Expect(rec.Equal(GVString("red"))).Should(BeFalse())
+	})
+
+	It("can only be decoded from JSON objects", func() {
+		for _, data := range []string{`[1, 2]`, `null`, `"x"`, `{`} {
+			_, err := NewGVRecordFromJSON([]byte(data))
+			Expect(err).Should(HaveOccurred(), data)
+		}
+	})
+})
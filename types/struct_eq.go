@@ -0,0 +1,251 @@
+package types
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// StructEquality tell how GVStructs holding values of a struct type are compared and hashed,
+// it is used by TestEqual, join tests, GVSet and the deduplication of facts in working memory
+type StructEquality uint8
+
+const (
+	// StructEqualityIdentity compare the values by ==, so pointers are equal only if they point to the same struct,
+	// it is the default one
+	StructEqualityIdentity StructEquality = iota
+	// StructEqualityDeep compare the structs by reflect.DeepEqual no matter they are pointed by pointers or not,
+	// and hash them by their exported fields that are reached without indirections(see deepHash),
+	// so cyclic pointers in the structs are fine
+	StructEqualityDeep
+	// StructEqualityMethod compare the structs by their method `Equal(T) bool` or `Equal(*T) bool`,
+	// and hash them by their method `Hash() uint64` if there is one.
+	//
+	// WARNING: without a Hash method all the structs of the type have the same hash,
+	// so working memory, GVSets and joins on them fall back to linear scans of all the structs of the type,
+	// add a Hash method that agrees with Equal for any type with more than a few facts.
+	StructEqualityMethod
+)
+
+func (e StructEquality) String() string {
+	switch e {
+	case StructEqualityIdentity:
+		return "Identity"
+	case StructEqualityDeep:
+		return "Deep"
+	case StructEqualityMethod:
+		return "Method"
+	}
+	return "Unknown"
+}
+
+// structEq is how the values of a struct type T, or pointers to them, are compared and hashed
+type structEq struct {
+	mode  StructEquality
+	equal func(x, y reflect.Value) bool // x and y are non-nil *T
+	hash  func(x reflect.Value) uint64  // x is non-nil *T
+}
+
+var structEqs sync.Map // map[reflect.Type]*structEq, keyed by the struct type
+
+// SetStructEquality set how values of struct type T, or pointers to them, are compared when they are held by GVStructs,
+// T can be either a struct type or a pointer to it, like
+//
+//	types.SetStructEquality[Order](types.StructEqualityDeep)
+//
+// SetStructEquality panics if T is not a struct, or T has no Equal method in StructEqualityMethod,
+// and it should be called before any fact of T is added since the hashes of facts are changed.
+func SetStructEquality[T any](mode StructEquality) {
+	t := derefType(reflect.TypeOf((*T)(nil)).Elem())
+	if t.Kind() != reflect.Struct {
+		panic(errors.Errorf("cannot set the equality of %s, expecting a struct", t))
+	}
+
+	eq := &structEq{mode: mode}
+	switch mode {
+	case StructEqualityIdentity:
+		structEqs.Delete(t)
+		return
+	case StructEqualityDeep:
+		eq.equal = func(x, y reflect.Value) bool { return reflect.DeepEqual(x.Interface(), y.Interface()) }
+		eq.hash = func(x reflect.Value) uint64 { return deepHash(x.Elem()) }
+	case StructEqualityMethod:
+		eq.equal = equalMethodOf(t)
+		if eq.equal == nil {
+			panic(errors.Errorf("cannot set the equality of %s, method `Equal(%s) bool` is not found", t, t))
+		}
+		eq.hash = hashMethodOf(t)
+	default:
+		panic(errors.Errorf("invalid StructEquality %d", mode))
+	}
+	structEqs.Store(t, eq)
+}
+
+// StructEqualityOf return the StructEquality of values of type t
+func StructEqualityOf(t reflect.Type) StructEquality {
+	if eq := structEqOf(t); eq != nil {
+		return eq.mode
+	}
+	return StructEqualityIdentity
+}
+
+// structEqOf return nil if values of type t are compared by identity
+func structEqOf(t reflect.Type) *structEq {
+	if t == nil {
+		return nil
+	}
+	eq, in := structEqs.Load(derefType(t))
+	if !in {
+		return nil
+	}
+	return eq.(*structEq)
+}
+
+// equalMethodOf look up method `Equal(T) bool` or `Equal(*T) bool` of *T
+func equalMethodOf(t reflect.Type) func(x, y reflect.Value) bool {
+	m, ok := reflect.PointerTo(t).MethodByName("Equal")
+	if !ok {
+		return nil
+	}
+	mt := m.Type // the receiver is the first argument
+	if mt.NumIn() != 2 || mt.NumOut() != 1 || mt.Out(0).Kind() != reflect.Bool {
+		return nil
+	}
+	switch mt.In(1) {
+	case t:
+		return func(x, y reflect.Value) bool {
+			return m.Func.Call([]reflect.Value{x, y.Elem()})[0].Bool()
+		}
+	case reflect.PointerTo(t):
+		return func(x, y reflect.Value) bool {
+			return m.Func.Call([]reflect.Value{x, y})[0].Bool()
+		}
+	}
+	return nil
+}
+
+// hashMethodOf look up method `Hash() uint64` of *T, or hash all the values of T to the same one if it is not found
+func hashMethodOf(t reflect.Type) func(x reflect.Value) uint64 {
+	m, ok := reflect.PointerTo(t).MethodByName("Hash")
+	if !ok || m.Type.NumIn() != 1 || m.Type.NumOut() != 1 || m.Type.Out(0).Kind() != reflect.Uint64 {
+		h := mixHash(uint64(reflect.ValueOf(t).Pointer()))
+		return func(reflect.Value) uint64 { return h }
+	}
+	return func(x reflect.Value) uint64 {
+		return m.Func.Call([]reflect.Value{x})[0].Uint()
+	}
+}
+
+var deepHashSeed = maphash.MakeSeed()
+
+// deepHash hash the exported fields of struct v, values reached through pointers, interfaces, slices and maps
+// are not hashed since they may refer back to v, so that hashing never fails or loops,
+// and structs equal by reflect.DeepEqual always have the same hash
+func deepHash(v reflect.Value) uint64 {
+	var h maphash.Hash
+	h.SetSeed(deepHashSeed)
+	writeDeepHash(&h, v)
+	return h.Sum64()
+}
+
+func writeDeepHash(h *maphash.Hash, v reflect.Value) {
+	var buf [8]byte
+	writeUint := func(u uint64) {
+		binary.LittleEndian.PutUint64(buf[:], u)
+		h.Write(buf[:])
+	}
+	writeFloat := func(f float64) {
+		if f == 0 { // -0 == +0
+			f = 0
+		}
+		writeUint(math.Float64bits(f))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint(1)
+		} else {
+			writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		writeFloat(real(v.Complex()))
+		writeFloat(imag(v.Complex()))
+	case reflect.String:
+		h.WriteString(v.String())
+		writeUint(uint64(v.Len()))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeDeepHash(h, v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				writeDeepHash(h, v.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Map:
+		writeUint(uint64(v.Len()))
+	}
+}
+
+// structPtr return a pointer to the struct in v, and false if v is a nil pointer
+func structPtr(v any) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		return rv, !rv.IsNil()
+	}
+	p := reflect.New(rv.Type())
+	p.Elem().Set(rv)
+	return p, true
+}
+
+func (v GVStruct) equalByMode(w any) bool {
+	eq := structEqOf(reflect.TypeOf(v.V))
+	if eq == nil {
+		if v.V == nil || w == nil {
+			return v.V == w
+		}
+		if t := reflect.TypeOf(v.V); t != reflect.TypeOf(w) || !t.Comparable() {
+			return false
+		}
+		return v.V == w
+	}
+
+	if w == nil || derefType(reflect.TypeOf(w)) != derefType(reflect.TypeOf(v.V)) {
+		return false
+	}
+	x, xok := structPtr(v.V)
+	y, yok := structPtr(w)
+	if !xok || !yok {
+		return xok == yok
+	}
+	return eq.equal(x, y)
+}
+
+func (v GVStruct) hashByMode() uint64 {
+	t := reflect.TypeOf(v.V)
+	eq := structEqOf(t)
+	if eq == nil {
+		if t != nil && !t.Comparable() {
+			return mixHash(uint64(reflect.ValueOf(t).Pointer()))
+		}
+		return tvStructHasher.Hash(v)
+	}
+	x, ok := structPtr(v.V)
+	if !ok {
+		return mixHash(uint64(reflect.ValueOf(derefType(t)).Pointer()))
+	}
+	return eq.hash(x)
+}
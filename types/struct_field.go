@@ -0,0 +1,113 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TagName is the key of struct tags that map the fields of a fact to the names used in rules,
+// `grete:"name"` renames a field, `grete:"-"` hides a field,
+// and `grete:",inline"` promotes the fields of a struct field as if they were declared in its parent
+const TagName = "grete"
+
+// structField is a field that can be accessed by rules in a struct
+type structField struct {
+	name   string
+	index  []int // index sequence for reflect.Value.FieldByIndex
+	typ    reflect.Type
+	tagged bool // name is given by tag
+	depth  int  // depth of embedding
+}
+
+var structFieldsCache sync.Map // map[reflect.Type]map[string]*structField
+
+// fieldByName look up a field by its name in rules in struct type t
+func fieldByName(t reflect.Type, name string) (*structField, bool) {
+	sf, in := structFieldsOf(t)[name]
+	return sf, in
+}
+
+// structFieldsOf return fields by their names in rules in struct type t, the map should not be modified
+func structFieldsOf(t reflect.Type) map[string]*structField {
+	fields, ok := structFieldsCache.Load(t)
+	if !ok {
+		fields, _ = structFieldsCache.LoadOrStore(t, typeFields(t))
+	}
+	return fields.(map[string]*structField)
+}
+
+// typeFields collect fields that can be accessed by rules in struct type t,
+// like encoding/json, a field that is embedded shallower or tagged wins over others with the same name,
+// and fields with the same name that no one can win are dropped for they are ambiguous
+func typeFields(t reflect.Type) map[string]*structField {
+	candidates := make(map[string][]*structField, t.NumField())
+	visited := map[reflect.Type]bool{t: true}
+	var walk func(t reflect.Type, index []int, depth int)
+	walk = func(t reflect.Type, index []int, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get(TagName)
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			inline := false
+			for _, opt := range strings.Split(opts, ",") {
+				inline = inline || opt == "inline"
+			}
+
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			fieldIndex := append(index[:len(index):len(index)], i)
+			if ft.Kind() == reflect.Struct && (inline || (sf.Anonymous && name == "")) && !visited[ft] {
+				visited[ft] = true
+				walk(ft, fieldIndex, depth+1)
+				delete(visited, ft)
+				if inline {
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+
+			field := &structField{name: name, index: fieldIndex, typ: sf.Type, tagged: name != "", depth: depth}
+			if field.name == "" {
+				field.name = sf.Name
+			}
+			candidates[field.name] = append(candidates[field.name], field)
+		}
+	}
+	walk(t, nil, 0)
+
+	fields := make(map[string]*structField, len(candidates))
+	for name, fs := range candidates {
+		if f := dominantField(fs); f != nil {
+			fields[name] = f
+		}
+	}
+	return fields
+}
+
+// dominantField return the field that wins over the others with the same name, or nil if it is ambiguous
+func dominantField(fs []*structField) *structField {
+	var dominant *structField
+	ambiguous := false
+	for _, f := range fs {
+		switch {
+		case dominant == nil,
+			f.depth < dominant.depth,
+			f.depth == dominant.depth && f.tagged && !dominant.tagged:
+			dominant, ambiguous = f, false
+		case f.depth == dominant.depth && f.tagged == dominant.tagged:
+			ambiguous = true
+		}
+	}
+	if ambiguous {
+		return nil
+	}
+	return dominant
+}
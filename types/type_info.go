@@ -0,0 +1,148 @@
+package types
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// TypeInfoOf derive the TypeInfo of T by reflection,
+// Fields of a struct are named like what GetField accepts, including tagged names, promoted fields,
+// and dotted paths of nested structs, and VT is set so that only values of T are accepted
+func TypeInfoOf[T any]() TypeInfo {
+	return typeInfoOf(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// GValueTypeOf tell the GValueType of fields of type T like TypeInfoOf does,
+// user-defined GValues are resolved after they are registered by RegisterGValueType
+func GValueTypeOf[T any]() GValueType {
+	return gvalueTypeOf(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func typeInfoOf(t reflect.Type) TypeInfo {
+	if vt, _, in := lookupCustomGValueType(t); in {
+		return TypeInfo{T: vt}
+	}
+	t = derefType(t)
+	vt := gvalueTypeOf(t)
+	if t.Kind() != reflect.Struct || vt != GValueTypeStruct {
+		return TypeInfo{T: vt}
+	}
+
+	fields := make(map[string]GValueType)
+	collectFields(fields, "", t, map[reflect.Type]bool{t: true})
+	return TypeInfo{T: GValueTypeStruct, Fields: fields, VT: t}
+}
+
+// collectFields collect fields of struct type t into fields with prefix,
+// and fields of nested structs that are not in visited
+func collectFields(fields map[string]GValueType, prefix string, t reflect.Type, visited map[reflect.Type]bool) {
+	for name, sf := range structFieldsOf(t) {
+		vt := gvalueTypeOf(sf.typ)
+		switch sf.typ.Kind() {
+		case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+			continue
+		}
+		fields[prefix+name] = vt
+
+		ft := derefType(sf.typ)
+		if vt != GValueTypeStruct || ft.Kind() != reflect.Struct || visited[ft] {
+			continue
+		}
+		visited[ft] = true
+		collectFields(fields, prefix+name+".", ft, visited)
+		delete(visited, ft)
+	}
+}
+
+// FieldType tell the GValueType of field f of values described by v, it is looked up in Fields first,
+// then derived from VT by reflection if Fields misses it, GValueTypeUnknown is returned if both fail
+func (v TypeInfo) FieldType(f string) GValueType {
+	if vt, in := v.Fields[f]; in {
+		return vt
+	}
+	if v.VT == nil {
+		return GValueTypeUnknown
+	}
+	ft, in := LookupFieldType(v.VT, f)
+	if !in {
+		return GValueTypeUnknown
+	}
+	return gvalueTypeOf(ft)
+}
+
+// gvalueTypeOf tell the GValueType of values of type t when they are wrapped by GetField
+func gvalueTypeOf(t reflect.Type) GValueType {
+	if vt, in := rType2testValueType[t]; in {
+		return vt
+	}
+	if vt, _, in := lookupCustomGValueType(t); in {
+		return vt
+	}
+	switch t {
+	case durationType:
+		return GValueTypeDuration
+	case timeType, reflect.PointerTo(timeType):
+		return GValueTypeTime
+	case bigIntType, bigRatType, bigFloatType,
+		reflect.PointerTo(bigIntType), reflect.PointerTo(bigRatType), reflect.PointerTo(bigFloatType):
+		return GValueTypeDecimal
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return GValueTypeInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return GValueTypeUint
+	case reflect.Float32, reflect.Float64:
+		return GValueTypeFloat
+	case reflect.String:
+		return GValueTypeString
+	case reflect.Bool:
+		return GValueTypeBool
+	case reflect.Pointer, reflect.Struct:
+		return GValueTypeStruct
+	case reflect.Slice, reflect.Array:
+		return GValueTypeList
+	case reflect.Map:
+		return GValueTypeMap
+	}
+	return GValueTypeUnknown
+}
+
+var (
+	typeRegistryMu sync.RWMutex
+	typeRegistry   = make(map[string]TypeInfo)
+)
+
+// RegisterType register the TypeInfo of T derived by TypeInfoOf with name,
+// so that productions and scripts can refer to T by its name,
+// it panics if the name is registered already
+func RegisterType[T any](name string) TypeInfo {
+	ti := TypeInfoOf[T]()
+	RegisterTypeInfo(name, ti)
+	return ti
+}
+
+// RegisterTypeInfo register a TypeInfo with name, like the one returned by New<Type>TypeInfo from cmd/gretegen,
+// it panics if the name is registered already
+func RegisterTypeInfo(name string, ti TypeInfo) {
+	if name == "" {
+		panic(errors.New("cannot register a type without name"))
+	}
+	typeRegistryMu.Lock()
+	defer typeRegistryMu.Unlock()
+	if _, in := typeRegistry[name]; in {
+		panic(errors.Errorf("type %s is registered already", name))
+	}
+	typeRegistry[name] = ti
+}
+
+// TypeInfoByName lookup a TypeInfo registered by name, Fields of it should not be modified
+func TypeInfoByName(name string) (TypeInfo, bool) {
+	typeRegistryMu.RLock()
+	defer typeRegistryMu.RUnlock()
+	ti, in := typeRegistry[name]
+	return ti, in
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/dolthub/maphash"
 	"github.com/mitchellh/hashstructure/v2"
@@ -16,8 +17,8 @@ var ErrFieldNotFound = errors.New("field not found")
 type (
 	GValueType uint8
 
+	// GValue is a value in facts, it can be implemented by users and registered by RegisterGValueType
 	GValue interface {
-		testValue()
 		Hash() uint64
 		Type() GValueType
 		RType() reflect.Type
@@ -37,19 +38,37 @@ const (
 	GValueTypeFloat
 	GValueTypeString
 	GValueTypeStruct
+	GValueTypeSet
+	GValueTypeBool
+	GValueTypeTime
+	GValueTypeDuration
+	GValueTypeList
+	GValueTypeMap
+	GValueTypeRecord
+	GValueTypeDecimal
+	// GValueTypeCustom is the first GValueType of user-defined GValues, see RegisterGValueType
+	GValueTypeCustom
 )
 
 var gValueTypeDict = [...]string{
-	"Unknown", "Nil", "ID", "Int", "Uint", "Float", "String",
+	"Unknown", "Nil", "ID", "Int", "Uint", "Float", "String", "Struct", "Set", "Bool", "Time", "Duration", "List", "Map", "Record", "Decimal",
 }
 
 var gValueTypeRTypeDict = [...]reflect.Type{
 	nil, reflect.TypeOf(&GVNil{}), reflect.TypeOf(GVIdentity("")), reflect.TypeOf(GVInt(0)),
 	reflect.TypeOf(GVUint(0)), reflect.TypeOf(GVFloat(0)),
-	reflect.TypeOf(GVString("")),
+	reflect.TypeOf(GVString("")), reflect.TypeOf(GVStruct{}), reflect.TypeOf(&GVSet{}),
+	reflect.TypeOf(GVBool(false)), timeType, reflect.TypeOf(GVDuration(0)),
+	reflect.TypeOf(&GVList{}), reflect.TypeOf(&GVMap{}), reflect.TypeOf(&GVRecord{}),
+	reflect.TypeOf(GVDecimal{}),
 }
 
 func (t GValueType) String() string {
+	if t.IsCustom() {
+		if names := customGValues.Load().names; int(t-GValueTypeCustom) < len(names) {
+			return names[t-GValueTypeCustom]
+		}
+	}
 	if int(t) >= len(gValueTypeDict) {
 		return gValueTypeDict[0]
 	}
@@ -65,9 +84,22 @@ var rType2testValueType = map[reflect.Type]GValueType{
 	reflect.TypeOf(GVFloat(0)):     GValueTypeFloat,
 	reflect.TypeOf(GVString("")):   GValueTypeString,
 	reflect.TypeOf(GVStruct{}):     GValueTypeStruct,
+	reflect.TypeOf(&GVSet{}):       GValueTypeSet,
+	reflect.TypeOf(GVBool(false)):  GValueTypeBool,
+	reflect.TypeOf(GVTime{}):       GValueTypeTime,
+	reflect.TypeOf(GVDuration(0)):  GValueTypeDuration,
+	reflect.TypeOf(&GVList{}):      GValueTypeList,
+	reflect.TypeOf(&GVMap{}):       GValueTypeMap,
+	reflect.TypeOf(&GVRecord{}):    GValueTypeRecord,
+	reflect.TypeOf(GVDecimal{}):    GValueTypeDecimal,
 }
 
 func (t GValueType) RType() reflect.Type {
+	if t.IsCustom() {
+		if rtypes := customGValues.Load().rtypes; int(t-GValueTypeCustom) < len(rtypes) {
+			return rtypes[t-GValueTypeCustom]
+		}
+	}
 	if int(t) >= len(gValueTypeRTypeDict) {
 		return gValueTypeRTypeDict[0]
 	}
@@ -79,7 +111,6 @@ type GVIdentity string
 
 var tvIdentityHasher = maphash.NewHasher[GVIdentity]()
 
-func (GVIdentity) testValue()            {}
 func (GVIdentity) Type() GValueType      { return GValueTypeIdentity }
 func (v GVIdentity) Hash() uint64        { return tvIdentityHasher.Hash(v) }
 func (v GVIdentity) RType() reflect.Type { return reflect.TypeOf("") }
@@ -98,7 +129,6 @@ type GVString string
 
 var tvStringHasher = maphash.NewHasher[GVString]()
 
-func (GVString) testValue()            {}
 func (GVString) Type() GValueType      { return GValueTypeString }
 func (v GVString) Hash() uint64        { return tvStringHasher.Hash(v) }
 func (v GVString) RType() reflect.Type { return reflect.TypeOf("") }
@@ -117,7 +147,6 @@ type GVInt int64
 
 var tvIntHasher = maphash.NewHasher[GVInt]()
 
-func (GVInt) testValue()            {}
 func (GVInt) Type() GValueType      { return GValueTypeInt }
 func (v GVInt) Hash() uint64        { return tvIntHasher.Hash(v) }
 func (v GVInt) toFloat() GVFloat    { return GVFloat(v) }
@@ -137,7 +166,6 @@ type GVUint uint64
 
 var tvUintHasher = maphash.NewHasher[GVUint]()
 
-func (GVUint) testValue()            {}
 func (GVUint) Type() GValueType      { return GValueTypeUint }
 func (v GVUint) Hash() uint64        { return tvUintHasher.Hash(v) }
 func (v GVUint) toFloat() GVFloat    { return GVFloat(v) }
@@ -157,7 +185,6 @@ type GVFloat float64
 
 var tvFloatHasher = maphash.NewHasher[GVFloat]()
 
-func (GVFloat) testValue()            {}
 func (GVFloat) Type() GValueType      { return GValueTypeFloat }
 func (v GVFloat) Hash() uint64        { return tvFloatHasher.Hash(v) }
 func (v GVFloat) toFloat() GVFloat    { return GVFloat(v) }
@@ -173,6 +200,79 @@ func (v GVFloat) GetField(f string) (GValue, any, error) {
 }
 func (v GVFloat) ToGoValue() any { return float64(v) }
 
+type GVBool bool
+
+var tvBoolHasher = maphash.NewHasher[GVBool]()
+
+func (GVBool) Type() GValueType      { return GValueTypeBool }
+func (v GVBool) Hash() uint64        { return tvBoolHasher.Hash(v) }
+func (v GVBool) RType() reflect.Type { return reflect.TypeOf(false) }
+func (v GVBool) Equal(w GValue) bool {
+	return w != nil && w.Type() == GValueTypeBool && v == w.(GVBool)
+}
+func (v GVBool) GetField(f string) (GValue, any, error) {
+	if f != FieldSelf {
+		return nil, nil, ErrFieldNotFound
+	}
+	return v, bool(v), nil
+}
+func (v GVBool) ToGoValue() any { return bool(v) }
+
+// GVTime is an instant in time, times of the same instant are equal even if they are in different locations
+type GVTime struct {
+	time.Time
+}
+
+func NewGVTime(t time.Time) GVTime {
+	return GVTime{Time: t}
+}
+
+var tvTimeHasher = maphash.NewHasher[int64]()
+
+func (GVTime) Type() GValueType      { return GValueTypeTime }
+func (v GVTime) Hash() uint64        { return tvTimeHasher.Hash(v.UnixNano()) }
+func (v GVTime) RType() reflect.Type { return timeType }
+func (v GVTime) Equal(w GValue) bool {
+	return w != nil && w.Type() == GValueTypeTime && v.Time.Equal(w.(GVTime).Time)
+}
+func (v GVTime) GetField(f string) (GValue, any, error) {
+	if f != FieldSelf {
+		return nil, nil, ErrFieldNotFound
+	}
+	return v, v.Time, nil
+}
+func (v GVTime) ToGoValue() any { return v.Time }
+
+// Add return the time v+d
+func (v GVTime) Add(d GVDuration) GVTime { return GVTime{Time: v.Time.Add(time.Duration(d))} }
+
+// Sub return the duration v-w
+func (v GVTime) Sub(w GVTime) GVDuration { return GVDuration(v.Time.Sub(w.Time)) }
+
+type GVDuration time.Duration
+
+var tvDurationHasher = maphash.NewHasher[GVDuration]()
+
+func (GVDuration) Type() GValueType      { return GValueTypeDuration }
+func (v GVDuration) Hash() uint64        { return tvDurationHasher.Hash(v) }
+func (v GVDuration) RType() reflect.Type { return durationType }
+func (v GVDuration) String() string      { return time.Duration(v).String() }
+func (v GVDuration) Equal(w GValue) bool {
+	return w != nil && w.Type() == GValueTypeDuration && v == w.(GVDuration)
+}
+func (v GVDuration) GetField(f string) (GValue, any, error) {
+	if f != FieldSelf {
+		return nil, nil, ErrFieldNotFound
+	}
+	return v, time.Duration(v), nil
+}
+func (v GVDuration) ToGoValue() any { return time.Duration(v) }
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
 type GVNil struct {
 }
 
@@ -182,11 +282,12 @@ func NewGVNil() *GVNil {
 
 var tvNilHash = maphash.NewHasher[GVNil]().Hash(GVNil{})
 
-func (*GVNil) testValue()            {}
 func (*GVNil) Type() GValueType      { return GValueTypeNil }
 func (v *GVNil) Hash() uint64        { return tvNilHash }
 func (v *GVNil) RType() reflect.Type { return reflect.TypeOf(&GVNil{}) }
-func (v *GVNil) Equal(w GValue) bool { return w.Type() == GValueTypeNil }
+
+// Equal tell whether w is nil, both a GVNil and a nil GValue are nil
+func (v *GVNil) Equal(w GValue) bool { return w == nil || w.Type() == GValueTypeNil }
 func (v *GVNil) GetField(f string) (GValue, any, error) {
 	if f != FieldSelf {
 		return nil, nil, ErrFieldNotFound
@@ -207,6 +308,10 @@ func (v TypeInfo) Hash() uint64 {
 	if err != nil {
 		panic(err)
 	}
+	// hashstructure sees nothing in a reflect.Type, so tell types apart by their identities
+	if v.VT != nil {
+		ret = mixHash(ret ^ uint64(reflect.ValueOf(v.VT).Pointer()))
+	}
 	return ret
 }
 
@@ -220,65 +325,291 @@ func NewGVStruct(v any) *GVStruct {
 
 var tvStructHasher = maphash.NewHasher[GVStruct]()
 
-func (GVStruct) testValue()            {}
 func (GVStruct) Type() GValueType      { return GValueTypeStruct }
-func (v GVStruct) Hash() uint64        { return tvStructHasher.Hash(v) }
+func (v GVStruct) Hash() uint64        { return v.hashByMode() }
 func (v GVStruct) RType() reflect.Type { return reflect.TypeOf(v) }
 func (v GVStruct) Value() any          { return v.V }
 func (v GVStruct) ToGoValue() any      { return v.V }
 
-// GetField extract field value by field name `f`, wrap it into TestValue and return it
+// GetField extract field value by field name `f`, wrap it into TestValue and return it,
+// the FieldAccessor of the struct is used if it implements one, or reflection is used(see GetFieldByReflect)
 func (v GVStruct) GetField(f string) (GValue, any, error) {
-	rv := reflect.Indirect(reflect.ValueOf(v.V))
-	if !rv.IsValid() {
-		return nil, nil, errors.New("nil value in TVStruct")
+	if a, ok := v.V.(FieldAccessor); ok {
+		return a.GetField(f)
 	}
+	return GetFieldByReflect(v.V, f)
+}
+
+// toGValue wrap a value into GValue, and return it with the underlying value
+func toGValue(fv reflect.Value) (GValue, any, error) {
+	return converterOf(fv.Type())(fv)
+}
 
-	fv := rv.FieldByName(f)
-	if !fv.IsValid() {
-		return nil, nil, errors.WithMessagef(ErrFieldNotFound, "field=%s", f)
+// HasField check if the struct has field `f`,
+// the FieldAccessor of the struct is used if it implements one, or reflection is used(see HasFieldByReflect)
+func (v GVStruct) HasField(f string) bool {
+	if a, ok := v.V.(FieldAccessor); ok {
+		return a.HasField(f)
 	}
+	return HasFieldByReflect(v.V, f)
+}
 
-	if _, in := rType2testValueType[fv.Type()]; in {
-		ret := fv.Interface()
-		return ret.(GValue), ret, nil
+// Equal compare the structs by the StructEquality of their type, see SetStructEquality
+func (v GVStruct) Equal(w GValue) bool {
+	switch w := w.(type) {
+	case *GVStruct:
+		return w != nil && v.equalByMode(w.V)
+	case GVStruct:
+		return v.equalByMode(w.V)
 	}
+	return false
+}
+
+// GVSet is a set of values, values are looked up by their hashes
+type GVSet struct {
+	Elems []GValue `hash:"set"` // distinct values in the order they are added
+
+	index map[uint64][]int // hash of value -> index of value in Elems
+	hash  uint64
+}
 
-	isPtr := fv.Kind() == reflect.Pointer
-	if isPtr && fv.IsZero() {
-		return &GVNil{}, nil, nil
+func NewGVSet(values ...GValue) *GVSet {
+	v := &GVSet{
+		Elems: make([]GValue, 0, len(values)),
+		index: make(map[uint64][]int, len(values)),
 	}
+	for _, x := range values {
+		if v.Contains(x) {
+			continue
+		}
+		h := x.Hash()
+		v.index[h] = append(v.index[h], len(v.Elems))
+		v.Elems = append(v.Elems, x)
+		// the hash of a set should not depend on the order of its values
+		v.hash += mixHash(h)
+	}
+	return v
+}
 
-	switch fv.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return GVInt(fv.Int()), fv.Interface(), nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return GVUint(fv.Uint()), fv.Interface(), nil
-	case reflect.String:
-		return GVString(fv.String()), fv.Interface(), nil
-	case reflect.Float32, reflect.Float64:
-		return GVFloat(fv.Float()), fv.Interface(), nil
-	case reflect.Ptr, reflect.Struct:
-		ret := fv.Interface()
-		return &GVStruct{V: ret}, ret, nil
-	default:
-		return nil, nil, errors.Errorf("unsupported type of %s to get", f)
+func (*GVSet) Type() GValueType      { return GValueTypeSet }
+func (v *GVSet) Hash() uint64        { return v.hash }
+func (v *GVSet) RType() reflect.Type { return reflect.TypeOf(v) }
+func (v *GVSet) Len() int            { return len(v.Elems) }
+func (v *GVSet) GetField(f string) (GValue, any, error) {
+	if f != FieldSelf {
+		return nil, nil, ErrFieldNotFound
 	}
+	return v, v.ToGoValue(), nil
 }
 
-func (v GVStruct) HasField(f string) bool {
-	vt := reflect.TypeOf(v.V)
-	if vt.Kind() == reflect.Ptr {
-		vt = vt.Elem()
+// Contains test whether x is in the set, x is compared with the values in the set by Equal
+func (v *GVSet) Contains(x GValue) bool {
+	if x == nil {
+		return false
+	}
+	for _, i := range v.index[x.Hash()] {
+		if v.Elems[i].Equal(x) {
+			return true
+		}
 	}
+	return false
+}
 
-	_, in := vt.FieldByName(f)
-	return in
+func (v *GVSet) Equal(w GValue) bool {
+	if w == nil || w.Type() != GValueTypeSet {
+		return false
+	}
+	o := w.(*GVSet)
+	if v.Len() != o.Len() || v.hash != o.hash {
+		return false
+	}
+	for _, x := range o.Elems {
+		if !v.Contains(x) {
+			return false
+		}
+	}
+	return true
 }
 
-func (v GVStruct) Equal(w GValue) bool {
-	return w != nil && w.Type() == GValueTypeStruct &&
-		v.V == w.(*GVStruct).V
+func (v *GVSet) ToGoValue() any {
+	ret := make([]any, 0, len(v.Elems))
+	for _, x := range v.Elems {
+		ret = append(ret, x.ToGoValue())
+	}
+	return ret
+}
+
+// GVList is a list of values converted from a slice or an array
+type GVList struct {
+	Elems []GValue
+}
+
+func NewGVList(values ...GValue) *GVList {
+	return &GVList{Elems: values}
+}
+
+func (*GVList) Type() GValueType      { return GValueTypeList }
+func (v *GVList) RType() reflect.Type { return reflect.TypeOf(v) }
+func (v *GVList) Len() int            { return len(v.Elems) }
+func (v *GVList) Hash() uint64 {
+	var h uint64
+	for _, e := range v.Elems {
+		h = mixHash(h*31 + e.Hash())
+	}
+	return h
+}
+func (v *GVList) GetField(f string) (GValue, any, error) {
+	if f != FieldSelf {
+		return nil, nil, ErrFieldNotFound
+	}
+	return v, v.ToGoValue(), nil
+}
+
+func (v *GVList) Equal(w GValue) bool {
+	if w == nil || w.Type() != GValueTypeList {
+		return false
+	}
+	o := w.(*GVList)
+	if v.Len() != o.Len() {
+		return false
+	}
+	for i := range v.Elems {
+		if !v.Elems[i].Equal(o.Elems[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *GVList) ToGoValue() any {
+	ret := make([]any, 0, len(v.Elems))
+	for _, x := range v.Elems {
+		ret = append(ret, x.ToGoValue())
+	}
+	return ret
+}
+
+type GVMapEntry struct {
+	Key   GValue
+	Value GValue
+}
+
+// GVMap is a map converted from a go map, values are looked up by the hashes of their keys
+type GVMap struct {
+	Entries []GVMapEntry `hash:"set"`
+
+	index map[uint64][]int // hash of key -> index of entry in Entries
+	hash  uint64
+}
+
+// NewGVMap create a GVMap from entries, the latter one wins if two entries have the same key
+func NewGVMap(entries ...GVMapEntry) *GVMap {
+	v := &GVMap{
+		Entries: make([]GVMapEntry, 0, len(entries)),
+		index:   make(map[uint64][]int, len(entries)),
+	}
+	for _, e := range entries {
+		if i, in := v.find(e.Key); in {
+			v.Entries[i].Value = e.Value
+			continue
+		}
+		h := e.Key.Hash()
+		v.index[h] = append(v.index[h], len(v.Entries))
+		v.Entries = append(v.Entries, e)
+	}
+	for _, e := range v.Entries {
+		// the hash of a map should not depend on the order of its entries
+		v.hash += mixHash(e.Key.Hash() ^ mixHash(e.Value.Hash()))
+	}
+	return v
+}
+
+func (*GVMap) Type() GValueType      { return GValueTypeMap }
+func (v *GVMap) Hash() uint64        { return v.hash }
+func (v *GVMap) RType() reflect.Type { return reflect.TypeOf(v) }
+func (v *GVMap) Len() int            { return len(v.Entries) }
+func (v *GVMap) GetField(f string) (GValue, any, error) {
+	if f != FieldSelf {
+		return nil, nil, ErrFieldNotFound
+	}
+	m, err := v.GoMap()
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, m, nil
+}
+
+func (v *GVMap) find(key GValue) (int, bool) {
+	if key == nil {
+		return 0, false
+	}
+	for _, i := range v.index[key.Hash()] {
+		if v.Entries[i].Key.Equal(key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Get return the value of key, and whether the key is in the map
+func (v *GVMap) Get(key GValue) (GValue, bool) {
+	i, in := v.find(key)
+	if !in {
+		return nil, false
+	}
+	return v.Entries[i].Value, true
+}
+
+func (v *GVMap) Equal(w GValue) bool {
+	if w == nil || w.Type() != GValueTypeMap {
+		return false
+	}
+	o := w.(*GVMap)
+	if v.Len() != o.Len() || v.hash != o.hash {
+		return false
+	}
+	for _, e := range o.Entries {
+		if x, in := v.Get(e.Key); !in || !x.Equal(e.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ToGoValue convert the map into map[any]any, a key whose go value cannot be a key of a go map,
+// like the []any of a GVList, is kept as the GValue itself, see GoMap for keys that cannot be kept either
+func (v *GVMap) ToGoValue() any {
+	ret, _ := v.GoMap()
+	return ret
+}
+
+// GoMap convert the map into map[any]any like ToGoValue, the entries whose keys cannot be keys of a go map,
+// like user-defined GValues holding slices, are left out with an error
+func (v *GVMap) GoMap() (map[any]any, error) {
+	var err error
+	ret := make(map[any]any, len(v.Entries))
+	for _, e := range v.Entries {
+		k := e.Key.ToGoValue()
+		if k != nil && !reflect.ValueOf(k).Comparable() {
+			if !reflect.ValueOf(e.Key).Comparable() {
+				err = errors.Errorf("key %v of type %s cannot be a key of a go map", k, e.Key.Type())
+				continue
+			}
+			k = e.Key
+		}
+		ret[k] = e.Value.ToGoValue()
+	}
+	return ret, err
+}
+
+// mixHash is the finalizer of murmur3, which spreads the bits of h
+func mixHash(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
 }
 
 func UnwrapTestValue(v any) any {
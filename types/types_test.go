@@ -0,0 +1,44 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GVMap", func() {
+	It("convert into a go map with keys that cannot be go map keys", func() {
+		v := struct{ M map[[2]int]string }{M: map[[2]int]string{{1, 2}: "x"}}
+		gv, _, err := GetFieldByReflect(v, "M")
+		Expect(err).ShouldNot(HaveOccurred())
+		m := gv.(*GVMap)
+		key := NewGVList(GVInt(1), GVInt(2))
+		e, in := m.Get(key)
+		Expect(in).Should(BeTrue())
+		Expect(e).Should(Equal(GVString("x")))
+
+		var goMap map[any]any
+		Expect(func() { goMap = m.ToGoValue().(map[any]any) }).ShouldNot(Panic())
+		Expect(goMap).Should(HaveLen(1))
+		for k, e := range goMap {
+			Expect(k).Should(BeAssignableToTypeOf(&GVList{}))
+			Expect(k.(GValue).Equal(key)).Should(BeTrue())
+			Expect(e).Should(Equal(GVString("x")))
+		}
+		_, raw, err := m.GetField(FieldSelf)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(raw).Should(HaveLen(1))
+	})
+
+	It("fail to convert into a go map with keys that cannot be kept", func() {
+		m := NewGVMap(
+			GVMapEntry{Key: GVString("a"), Value: GVInt(1)},
+			GVMapEntry{Key: GVStruct{V: struct{ L []int }{L: []int{1}}}, Value: GVInt(2)},
+		)
+		goMap, err := m.GoMap()
+		Expect(err).Should(HaveOccurred())
+		Expect(goMap).Should(Equal(map[any]any{GVString("a"): int64(1)}))
+		Expect(func() { m.ToGoValue() }).ShouldNot(Panic())
+		_, _, err = m.GetField(FieldSelf)
+		Expect(err).Should(HaveOccurred())
+	})
+})